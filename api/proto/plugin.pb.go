@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -65,6 +66,18 @@ type PluginInfo struct {
 	Author            string                 `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
 	Commands          []string               `protobuf:"bytes,5,rep,name=commands,proto3" json:"commands,omitempty"`
 	HandleAllMessages bool                   `protobuf:"varint,6,opt,name=handle_all_messages,json=handleAllMessages,proto3" json:"handle_all_messages,omitempty"`
+	MessageFilter     *MessageFilter         `protobuf:"bytes,7,opt,name=message_filter,json=messageFilter,proto3" json:"message_filter,omitempty"`         // Declarative subscription for non-command messages
+	Priority          int32                  `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`                                       // Higher runs first in ordered dispatch
+	ObserveOnly       bool                   `protobuf:"varint,9,opt,name=observe_only,json=observeOnly,proto3" json:"observe_only,omitempty"`              // Observe messages without short-circuiting
+	EventTopics       []string               `protobuf:"bytes,10,rep,name=event_topics,json=eventTopics,proto3" json:"event_topics,omitempty"`              // Event bus topics, "prefix.*" and "*" allowed
+	Methods           []string               `protobuf:"bytes,11,rep,name=methods,proto3" json:"methods,omitempty"`                                         // Methods other plugins may invoke
+	DependsOn         []string               `protobuf:"bytes,12,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`                    // Plugins that must be started first
+	ProtocolVersion   uint32                 `protobuf:"varint,13,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // Highest plugin protocol the SDK speaks
+	Capabilities      *Capabilities          `protobuf:"bytes,14,opt,name=capabilities,proto3" json:"capabilities,omitempty"`                               // Requested access, unset means unrestricted
+	SdkVersion        string                 `protobuf:"bytes,15,opt,name=sdk_version,json=sdkVersion,proto3" json:"sdk_version,omitempty"`                 // SDK the plugin was built with, empty before the handshake existed
+	Features          []string               `protobuf:"bytes,16,rep,name=features,proto3" json:"features,omitempty"`                                       // Protocol features the SDK supports
+	CoreVersion       string                 `protobuf:"bytes,17,opt,name=core_version,json=coreVersion,proto3" json:"core_version,omitempty"`              // Compatible core versions, e.g. ">=1.0.0, <2.0.0"
+	ConfigSchema      []*ConfigField         `protobuf:"bytes,18,rep,name=config_schema,json=configSchema,proto3" json:"config_schema,omitempty"`           // Settings admins may change
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -141,34 +154,119 @@ func (x *PluginInfo) GetHandleAllMessages() bool {
 	return false
 }
 
-type MessageEvent struct {
+func (x *PluginInfo) GetMessageFilter() *MessageFilter {
+	if x != nil {
+		return x.MessageFilter
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetObserveOnly() bool {
+	if x != nil {
+		return x.ObserveOnly
+	}
+	return false
+}
+
+func (x *PluginInfo) GetEventTopics() []string {
+	if x != nil {
+		return x.EventTopics
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetMethods() []string {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetDependsOn() []string {
+	if x != nil {
+		return x.DependsOn
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *PluginInfo) GetCapabilities() *Capabilities {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetSdkVersion() string {
+	if x != nil {
+		return x.SdkVersion
+	}
+	return ""
+}
+
+func (x *PluginInfo) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+func (x *PluginInfo) GetCoreVersion() string {
+	if x != nil {
+		return x.CoreVersion
+	}
+	return ""
+}
+
+func (x *PluginInfo) GetConfigSchema() []*ConfigField {
+	if x != nil {
+		return x.ConfigSchema
+	}
+	return nil
+}
+
+// ConfigField declares one setting of a plugin. Values travel as strings
+// in the canonical form of their type.
+type ConfigField struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	GroupId       int64                  `protobuf:"varint,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`            // 0 if private message
-	MessageType   string                 `protobuf:"bytes,4,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // "private" or "group"
-	RawMessage    string                 `protobuf:"bytes,5,opt,name=raw_message,json=rawMessage,proto3" json:"raw_message,omitempty"`
-	Segments      []*MessageSegment      `protobuf:"bytes,6,rep,name=segments,proto3" json:"segments,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Sender        *UserInfo              `protobuf:"bytes,8,opt,name=sender,proto3" json:"sender,omitempty"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // "string" (default), "int", "float", "bool" or "duration"
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Default       string                 `protobuf:"bytes,4,opt,name=default,proto3" json:"default,omitempty"`    // Used while no value is set
+	Required      bool                   `protobuf:"varint,5,opt,name=required,proto3" json:"required,omitempty"` // The plugin is not started until it is set
+	Secret        bool                   `protobuf:"varint,6,opt,name=secret,proto3" json:"secret,omitempty"`     // Masked when shown to admins
+	Options       []string               `protobuf:"bytes,7,rep,name=options,proto3" json:"options,omitempty"`    // Allowed values, empty means any
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MessageEvent) Reset() {
-	*x = MessageEvent{}
+func (x *ConfigField) Reset() {
+	*x = ConfigField{}
 	mi := &file_api_proto_plugin_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MessageEvent) String() string {
+func (x *ConfigField) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MessageEvent) ProtoMessage() {}
+func (*ConfigField) ProtoMessage() {}
 
-func (x *MessageEvent) ProtoReflect() protoreflect.Message {
+func (x *ConfigField) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -180,89 +278,85 @@ func (x *MessageEvent) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MessageEvent.ProtoReflect.Descriptor instead.
-func (*MessageEvent) Descriptor() ([]byte, []int) {
+// Deprecated: Use ConfigField.ProtoReflect.Descriptor instead.
+func (*ConfigField) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *MessageEvent) GetMessageId() string {
+func (x *ConfigField) GetKey() string {
 	if x != nil {
-		return x.MessageId
+		return x.Key
 	}
 	return ""
 }
 
-func (x *MessageEvent) GetUserId() int64 {
-	if x != nil {
-		return x.UserId
-	}
-	return 0
-}
-
-func (x *MessageEvent) GetGroupId() int64 {
+func (x *ConfigField) GetType() string {
 	if x != nil {
-		return x.GroupId
+		return x.Type
 	}
-	return 0
+	return ""
 }
 
-func (x *MessageEvent) GetMessageType() string {
+func (x *ConfigField) GetDescription() string {
 	if x != nil {
-		return x.MessageType
+		return x.Description
 	}
 	return ""
 }
 
-func (x *MessageEvent) GetRawMessage() string {
+func (x *ConfigField) GetDefault() string {
 	if x != nil {
-		return x.RawMessage
+		return x.Default
 	}
 	return ""
 }
 
-func (x *MessageEvent) GetSegments() []*MessageSegment {
+func (x *ConfigField) GetRequired() bool {
 	if x != nil {
-		return x.Segments
+		return x.Required
 	}
-	return nil
+	return false
 }
 
-func (x *MessageEvent) GetTimestamp() int64 {
+func (x *ConfigField) GetSecret() bool {
 	if x != nil {
-		return x.Timestamp
+		return x.Secret
 	}
-	return 0
+	return false
 }
 
-func (x *MessageEvent) GetSender() *UserInfo {
+func (x *ConfigField) GetOptions() []string {
 	if x != nil {
-		return x.Sender
+		return x.Options
 	}
 	return nil
 }
 
-type MessageSegment struct {
+// PluginConfig is a plugin's effective configuration: every field of its
+// schema with a value or default
+type PluginConfig struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // text, image, at, face, etc.
-	Data          map[string]string      `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Revision      uint64                 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"` // Increases with every change
+	Changed       []string               `protobuf:"bytes,3,rep,name=changed,proto3" json:"changed,omitempty"`    // Keys changed since the previous revision
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MessageSegment) Reset() {
-	*x = MessageSegment{}
+func (x *PluginConfig) Reset() {
+	*x = PluginConfig{}
 	mi := &file_api_proto_plugin_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MessageSegment) String() string {
+func (x *PluginConfig) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MessageSegment) ProtoMessage() {}
+func (*PluginConfig) ProtoMessage() {}
 
-func (x *MessageSegment) ProtoReflect() protoreflect.Message {
+func (x *PluginConfig) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -274,48 +368,58 @@ func (x *MessageSegment) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MessageSegment.ProtoReflect.Descriptor instead.
-func (*MessageSegment) Descriptor() ([]byte, []int) {
+// Deprecated: Use PluginConfig.ProtoReflect.Descriptor instead.
+func (*PluginConfig) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *MessageSegment) GetType() string {
+func (x *PluginConfig) GetValues() map[string]string {
 	if x != nil {
-		return x.Type
+		return x.Values
 	}
-	return ""
+	return nil
 }
 
-func (x *MessageSegment) GetData() map[string]string {
+func (x *PluginConfig) GetRevision() uint64 {
 	if x != nil {
-		return x.Data
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *PluginConfig) GetChanged() []string {
+	if x != nil {
+		return x.Changed
 	}
 	return nil
 }
 
-type CommandEvent struct {
+// Capabilities a plugin needs from the core. The core only allows calls
+// within the set an admin approved.
+type Capabilities struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       *MessageEvent          `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
-	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
-	Args          []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
+	Rpcs          []string               `protobuf:"bytes,1,rep,name=rpcs,proto3" json:"rpcs,omitempty"`                                  // BotService methods, e.g. "SendMessage"
+	Actions       []string               `protobuf:"bytes,2,rep,name=actions,proto3" json:"actions,omitempty"`                            // NapCat actions for CallAPI, "get_*" matches a prefix
+	Groups        []int64                `protobuf:"varint,3,rep,packed,name=groups,proto3" json:"groups,omitempty"`                      // Groups the plugin may act in, empty means any
+	UploadPaths   []string               `protobuf:"bytes,4,rep,name=upload_paths,json=uploadPaths,proto3" json:"upload_paths,omitempty"` // Directories files may be uploaded from
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandEvent) Reset() {
-	*x = CommandEvent{}
+func (x *Capabilities) Reset() {
+	*x = Capabilities{}
 	mi := &file_api_proto_plugin_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandEvent) String() string {
+func (x *Capabilities) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandEvent) ProtoMessage() {}
+func (*Capabilities) ProtoMessage() {}
 
-func (x *CommandEvent) ProtoReflect() protoreflect.Message {
+func (x *Capabilities) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -327,54 +431,68 @@ func (x *CommandEvent) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandEvent.ProtoReflect.Descriptor instead.
-func (*CommandEvent) Descriptor() ([]byte, []int) {
+// Deprecated: Use Capabilities.ProtoReflect.Descriptor instead.
+func (*Capabilities) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *CommandEvent) GetMessage() *MessageEvent {
+func (x *Capabilities) GetRpcs() []string {
 	if x != nil {
-		return x.Message
+		return x.Rpcs
 	}
 	return nil
 }
 
-func (x *CommandEvent) GetCommand() string {
+func (x *Capabilities) GetActions() []string {
 	if x != nil {
-		return x.Command
+		return x.Actions
 	}
-	return ""
+	return nil
 }
 
-func (x *CommandEvent) GetArgs() []string {
+func (x *Capabilities) GetGroups() []int64 {
 	if x != nil {
-		return x.Args
+		return x.Groups
 	}
 	return nil
 }
 
-type HandleResult struct {
+func (x *Capabilities) GetUploadPaths() []string {
+	if x != nil {
+		return x.UploadPaths
+	}
+	return nil
+}
+
+// MessageFilter narrows which non-command messages are delivered to a plugin.
+// All non-empty scope fields must match; if keywords or patterns are set,
+// at least one of them must match the raw message text.
+type MessageFilter struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Handled       bool                   `protobuf:"varint,1,opt,name=handled,proto3" json:"handled,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Keywords      []string               `protobuf:"bytes,1,rep,name=keywords,proto3" json:"keywords,omitempty"`                             // Substring triggers
+	Patterns      []string               `protobuf:"bytes,2,rep,name=patterns,proto3" json:"patterns,omitempty"`                             // Regular expression triggers
+	GroupIds      []int64                `protobuf:"varint,3,rep,packed,name=group_ids,json=groupIds,proto3" json:"group_ids,omitempty"`     // Only these groups
+	UserIds       []int64                `protobuf:"varint,4,rep,packed,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`        // Only these senders
+	SegmentTypes  []string               `protobuf:"bytes,5,rep,name=segment_types,json=segmentTypes,proto3" json:"segment_types,omitempty"` // Required segment types, e.g. "image"
+	MessageTypes  []string               `protobuf:"bytes,6,rep,name=message_types,json=messageTypes,proto3" json:"message_types,omitempty"` // "private" and/or "group"
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HandleResult) Reset() {
-	*x = HandleResult{}
+func (x *MessageFilter) Reset() {
+	*x = MessageFilter{}
 	mi := &file_api_proto_plugin_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HandleResult) String() string {
+func (x *MessageFilter) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HandleResult) ProtoMessage() {}
+func (*MessageFilter) ProtoMessage() {}
 
-func (x *HandleResult) ProtoReflect() protoreflect.Message {
+func (x *MessageFilter) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -386,49 +504,81 @@ func (x *HandleResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HandleResult.ProtoReflect.Descriptor instead.
-func (*HandleResult) Descriptor() ([]byte, []int) {
+// Deprecated: Use MessageFilter.ProtoReflect.Descriptor instead.
+func (*MessageFilter) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *HandleResult) GetHandled() bool {
+func (x *MessageFilter) GetKeywords() []string {
 	if x != nil {
-		return x.Handled
+		return x.Keywords
 	}
-	return false
+	return nil
 }
 
-func (x *HandleResult) GetError() string {
+func (x *MessageFilter) GetPatterns() []string {
 	if x != nil {
-		return x.Error
+		return x.Patterns
 	}
-	return ""
+	return nil
 }
 
-type SendMessageRequest struct {
+func (x *MessageFilter) GetGroupIds() []int64 {
+	if x != nil {
+		return x.GroupIds
+	}
+	return nil
+}
+
+func (x *MessageFilter) GetUserIds() []int64 {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+func (x *MessageFilter) GetSegmentTypes() []string {
+	if x != nil {
+		return x.SegmentTypes
+	}
+	return nil
+}
+
+func (x *MessageFilter) GetMessageTypes() []string {
+	if x != nil {
+		return x.MessageTypes
+	}
+	return nil
+}
+
+type MessageEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MessageType   string                 `protobuf:"bytes,1,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // "private" or "group"
+	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
 	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	GroupId       int64                  `protobuf:"varint,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	Segments      []*MessageSegment      `protobuf:"bytes,4,rep,name=segments,proto3" json:"segments,omitempty"`
+	GroupId       int64                  `protobuf:"varint,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`            // 0 if private message
+	MessageType   string                 `protobuf:"bytes,4,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // "private" or "group"
+	RawMessage    string                 `protobuf:"bytes,5,opt,name=raw_message,json=rawMessage,proto3" json:"raw_message,omitempty"`
+	Segments      []*MessageSegment      `protobuf:"bytes,6,rep,name=segments,proto3" json:"segments,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Sender        *UserInfo              `protobuf:"bytes,8,opt,name=sender,proto3" json:"sender,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SendMessageRequest) Reset() {
-	*x = SendMessageRequest{}
+func (x *MessageEvent) Reset() {
+	*x = MessageEvent{}
 	mi := &file_api_proto_plugin_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SendMessageRequest) String() string {
+func (x *MessageEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SendMessageRequest) ProtoMessage() {}
+func (*MessageEvent) ProtoMessage() {}
 
-func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
+func (x *MessageEvent) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -440,61 +590,89 @@ func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
-func (*SendMessageRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use MessageEvent.ProtoReflect.Descriptor instead.
+func (*MessageEvent) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *SendMessageRequest) GetMessageType() string {
+func (x *MessageEvent) GetMessageId() string {
 	if x != nil {
-		return x.MessageType
+		return x.MessageId
 	}
 	return ""
 }
 
-func (x *SendMessageRequest) GetUserId() int64 {
+func (x *MessageEvent) GetUserId() int64 {
 	if x != nil {
 		return x.UserId
 	}
 	return 0
 }
 
-func (x *SendMessageRequest) GetGroupId() int64 {
+func (x *MessageEvent) GetGroupId() int64 {
 	if x != nil {
 		return x.GroupId
 	}
 	return 0
 }
 
-func (x *SendMessageRequest) GetSegments() []*MessageSegment {
+func (x *MessageEvent) GetMessageType() string {
 	if x != nil {
-		return x.Segments
+		return x.MessageType
 	}
-	return nil
+	return ""
 }
 
-type SendMessageResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *MessageEvent) GetRawMessage() string {
+	if x != nil {
+		return x.RawMessage
+	}
+	return ""
 }
 
-func (x *SendMessageResponse) Reset() {
-	*x = SendMessageResponse{}
-	mi := &file_api_proto_plugin_proto_msgTypes[7]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *MessageEvent) GetSegments() []*MessageSegment {
+	if x != nil {
+		return x.Segments
+	}
+	return nil
 }
 
-func (x *SendMessageResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *MessageEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
 }
 
-func (*SendMessageResponse) ProtoMessage() {}
+func (x *MessageEvent) GetSender() *UserInfo {
+	if x != nil {
+		return x.Sender
+	}
+	return nil
+}
 
-func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
+type MessageSegment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // text, image, at, face, etc.
+	Data          map[string]string      `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageSegment) Reset() {
+	*x = MessageSegment{}
+	mi := &file_api_proto_plugin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageSegment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageSegment) ProtoMessage() {}
+
+func (x *MessageSegment) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -506,46 +684,48 @@ func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SendMessageResponse.ProtoReflect.Descriptor instead.
-func (*SendMessageResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use MessageSegment.ProtoReflect.Descriptor instead.
+func (*MessageSegment) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *SendMessageResponse) GetMessageId() int64 {
+func (x *MessageSegment) GetType() string {
 	if x != nil {
-		return x.MessageId
+		return x.Type
 	}
-	return 0
+	return ""
 }
 
-func (x *SendMessageResponse) GetError() string {
+func (x *MessageSegment) GetData() map[string]string {
 	if x != nil {
-		return x.Error
+		return x.Data
 	}
-	return ""
+	return nil
 }
 
-type GetUserInfoRequest struct {
+type CommandEvent struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Message       *MessageEvent          `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Args          []string               `protobuf:"bytes,3,rep,name=args,proto3" json:"args,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetUserInfoRequest) Reset() {
-	*x = GetUserInfoRequest{}
+func (x *CommandEvent) Reset() {
+	*x = CommandEvent{}
 	mi := &file_api_proto_plugin_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetUserInfoRequest) String() string {
+func (x *CommandEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetUserInfoRequest) ProtoMessage() {}
+func (*CommandEvent) ProtoMessage() {}
 
-func (x *GetUserInfoRequest) ProtoReflect() protoreflect.Message {
+func (x *CommandEvent) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -557,42 +737,54 @@ func (x *GetUserInfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetUserInfoRequest.ProtoReflect.Descriptor instead.
-func (*GetUserInfoRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use CommandEvent.ProtoReflect.Descriptor instead.
+func (*CommandEvent) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *GetUserInfoRequest) GetUserId() int64 {
+func (x *CommandEvent) GetMessage() *MessageEvent {
 	if x != nil {
-		return x.UserId
+		return x.Message
 	}
-	return 0
+	return nil
 }
 
-type UserInfo struct {
+func (x *CommandEvent) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *CommandEvent) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type HandleResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Nickname      string                 `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
-	Card          string                 `protobuf:"bytes,3,opt,name=card,proto3" json:"card,omitempty"` // group card name
-	Role          string                 `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"` // owner, admin, member
+	Handled       bool                   `protobuf:"varint,1,opt,name=handled,proto3" json:"handled,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UserInfo) Reset() {
-	*x = UserInfo{}
+func (x *HandleResult) Reset() {
+	*x = HandleResult{}
 	mi := &file_api_proto_plugin_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UserInfo) String() string {
+func (x *HandleResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UserInfo) ProtoMessage() {}
+func (*HandleResult) ProtoMessage() {}
 
-func (x *UserInfo) ProtoReflect() protoreflect.Message {
+func (x *HandleResult) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -604,60 +796,49 @@ func (x *UserInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UserInfo.ProtoReflect.Descriptor instead.
-func (*UserInfo) Descriptor() ([]byte, []int) {
+// Deprecated: Use HandleResult.ProtoReflect.Descriptor instead.
+func (*HandleResult) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *UserInfo) GetUserId() int64 {
-	if x != nil {
-		return x.UserId
-	}
-	return 0
-}
-
-func (x *UserInfo) GetNickname() string {
-	if x != nil {
-		return x.Nickname
-	}
-	return ""
-}
-
-func (x *UserInfo) GetCard() string {
+func (x *HandleResult) GetHandled() bool {
 	if x != nil {
-		return x.Card
+		return x.Handled
 	}
-	return ""
+	return false
 }
 
-func (x *UserInfo) GetRole() string {
+func (x *HandleResult) GetError() string {
 	if x != nil {
-		return x.Role
+		return x.Error
 	}
 	return ""
 }
 
-type GetGroupInfoRequest struct {
+type SendMessageRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	MessageType   string                 `protobuf:"bytes,1,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"` // "private" or "group"
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       int64                  `protobuf:"varint,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Segments      []*MessageSegment      `protobuf:"bytes,4,rep,name=segments,proto3" json:"segments,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetGroupInfoRequest) Reset() {
-	*x = GetGroupInfoRequest{}
+func (x *SendMessageRequest) Reset() {
+	*x = SendMessageRequest{}
 	mi := &file_api_proto_plugin_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetGroupInfoRequest) String() string {
+func (x *SendMessageRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetGroupInfoRequest) ProtoMessage() {}
+func (*SendMessageRequest) ProtoMessage() {}
 
-func (x *GetGroupInfoRequest) ProtoReflect() protoreflect.Message {
+func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -669,41 +850,61 @@ func (x *GetGroupInfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetGroupInfoRequest.ProtoReflect.Descriptor instead.
-func (*GetGroupInfoRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendMessageRequest) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *GetGroupInfoRequest) GetGroupId() int64 {
+func (x *SendMessageRequest) GetMessageType() string {
+	if x != nil {
+		return x.MessageType
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *SendMessageRequest) GetGroupId() int64 {
 	if x != nil {
 		return x.GroupId
 	}
 	return 0
 }
 
-type GroupInfo struct {
+func (x *SendMessageRequest) GetSegments() []*MessageSegment {
+	if x != nil {
+		return x.Segments
+	}
+	return nil
+}
+
+type SendMessageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
-	MemberCount   int32                  `protobuf:"varint,3,opt,name=member_count,json=memberCount,proto3" json:"member_count,omitempty"`
+	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GroupInfo) Reset() {
-	*x = GroupInfo{}
+func (x *SendMessageResponse) Reset() {
+	*x = SendMessageResponse{}
 	mi := &file_api_proto_plugin_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GroupInfo) String() string {
+func (x *SendMessageResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GroupInfo) ProtoMessage() {}
+func (*SendMessageResponse) ProtoMessage() {}
 
-func (x *GroupInfo) ProtoReflect() protoreflect.Message {
+func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -715,54 +916,46 @@ func (x *GroupInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GroupInfo.ProtoReflect.Descriptor instead.
-func (*GroupInfo) Descriptor() ([]byte, []int) {
+// Deprecated: Use SendMessageResponse.ProtoReflect.Descriptor instead.
+func (*SendMessageResponse) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *GroupInfo) GetGroupId() int64 {
+func (x *SendMessageResponse) GetMessageId() int64 {
 	if x != nil {
-		return x.GroupId
+		return x.MessageId
 	}
 	return 0
 }
 
-func (x *GroupInfo) GetGroupName() string {
+func (x *SendMessageResponse) GetError() string {
 	if x != nil {
-		return x.GroupName
+		return x.Error
 	}
 	return ""
 }
 
-func (x *GroupInfo) GetMemberCount() int32 {
-	if x != nil {
-		return x.MemberCount
-	}
-	return 0
-}
-
-type LogRequest struct {
+type GetUserInfoRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"` // debug, info, warn, error
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LogRequest) Reset() {
-	*x = LogRequest{}
+func (x *GetUserInfoRequest) Reset() {
+	*x = GetUserInfoRequest{}
 	mi := &file_api_proto_plugin_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LogRequest) String() string {
+func (x *GetUserInfoRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LogRequest) ProtoMessage() {}
+func (*GetUserInfoRequest) ProtoMessage() {}
 
-func (x *LogRequest) ProtoReflect() protoreflect.Message {
+func (x *GetUserInfoRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_api_proto_plugin_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -774,48 +967,1340 @@ func (x *LogRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LogRequest.ProtoReflect.Descriptor instead.
-func (*LogRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetUserInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetUserInfoRequest) Descriptor() ([]byte, []int) {
 	return file_api_proto_plugin_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *LogRequest) GetLevel() string {
-	if x != nil {
-		return x.Level
-	}
-	return ""
-}
-
-func (x *LogRequest) GetMessage() string {
+func (x *GetUserInfoRequest) GetUserId() int64 {
 	if x != nil {
-		return x.Message
+		return x.UserId
 	}
-	return ""
+	return 0
 }
 
-type HealthResponse struct {
+type UserInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Healthy       bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
-	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Nickname      string                 `protobuf:"bytes,2,opt,name=nickname,proto3" json:"nickname,omitempty"`
+	Card          string                 `protobuf:"bytes,3,opt,name=card,proto3" json:"card,omitempty"` // group card name
+	Role          string                 `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"` // owner, admin, member
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HealthResponse) Reset() {
-	*x = HealthResponse{}
-	mi := &file_api_proto_plugin_proto_msgTypes[13]
+func (x *UserInfo) Reset() {
+	*x = UserInfo{}
+	mi := &file_api_proto_plugin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserInfo) ProtoMessage() {}
+
+func (x *UserInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserInfo.ProtoReflect.Descriptor instead.
+func (*UserInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UserInfo) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UserInfo) GetNickname() string {
+	if x != nil {
+		return x.Nickname
+	}
+	return ""
+}
+
+func (x *UserInfo) GetCard() string {
+	if x != nil {
+		return x.Card
+	}
+	return ""
+}
+
+func (x *UserInfo) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type GetGroupInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupInfoRequest) Reset() {
+	*x = GetGroupInfoRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupInfoRequest) ProtoMessage() {}
+
+func (x *GetGroupInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupInfoRequest.ProtoReflect.Descriptor instead.
+func (*GetGroupInfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetGroupInfoRequest) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+type GroupInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	GroupName     string                 `protobuf:"bytes,2,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	MemberCount   int32                  `protobuf:"varint,3,opt,name=member_count,json=memberCount,proto3" json:"member_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupInfo) Reset() {
+	*x = GroupInfo{}
+	mi := &file_api_proto_plugin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupInfo) ProtoMessage() {}
+
+func (x *GroupInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupInfo.ProtoReflect.Descriptor instead.
+func (*GroupInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GroupInfo) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *GroupInfo) GetGroupName() string {
+	if x != nil {
+		return x.GroupName
+	}
+	return ""
+}
+
+func (x *GroupInfo) GetMemberCount() int32 {
+	if x != nil {
+		return x.MemberCount
+	}
+	return 0
+}
+
+type LogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"` // debug, info, warn, error
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogRequest) Reset() {
+	*x = LogRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogRequest) ProtoMessage() {}
+
+func (x *LogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogRequest.ProtoReflect.Descriptor instead.
+func (*LogRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LogRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Healthy       bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_api_proto_plugin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *HealthResponse) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type UploadGroupFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	FilePath      string                 `protobuf:"bytes,2,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"` // Absolute path to file
+	FileName      string                 `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"` // Display name
+	Folder        string                 `protobuf:"bytes,4,opt,name=folder,proto3" json:"folder,omitempty"`                     // Folder path (optional, default "/")
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadGroupFileRequest) Reset() {
+	*x = UploadGroupFileRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadGroupFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadGroupFileRequest) ProtoMessage() {}
+
+func (x *UploadGroupFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadGroupFileRequest.ProtoReflect.Descriptor instead.
+func (*UploadGroupFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UploadGroupFileRequest) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *UploadGroupFileRequest) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *UploadGroupFileRequest) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *UploadGroupFileRequest) GetFolder() string {
+	if x != nil {
+		return x.Folder
+	}
+	return ""
+}
+
+type UploadPrivateFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FilePath      string                 `protobuf:"bytes,2,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"` // Absolute path to file
+	FileName      string                 `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"` // Display name
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadPrivateFileRequest) Reset() {
+	*x = UploadPrivateFileRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadPrivateFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadPrivateFileRequest) ProtoMessage() {}
+
+func (x *UploadPrivateFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadPrivateFileRequest.ProtoReflect.Descriptor instead.
+func (*UploadPrivateFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UploadPrivateFileRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UploadPrivateFileRequest) GetFilePath() string {
+	if x != nil {
+		return x.FilePath
+	}
+	return ""
+}
+
+func (x *UploadPrivateFileRequest) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+type UploadFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	FileId        string                 `protobuf:"bytes,3,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"` // File ID returned by QQ
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadFileResponse) Reset() {
+	*x = UploadFileResponse{}
+	mi := &file_api_proto_plugin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadFileResponse) ProtoMessage() {}
+
+func (x *UploadFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadFileResponse.ProtoReflect.Descriptor instead.
+func (*UploadFileResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UploadFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UploadFileResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *UploadFileResponse) GetFileId() string {
+	if x != nil {
+		return x.FileId
+	}
+	return ""
+}
+
+type CallAPIRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Action        string                 `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`                                                                           // API action name
+	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // API parameters
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallAPIRequest) Reset() {
+	*x = CallAPIRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallAPIRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallAPIRequest) ProtoMessage() {}
+
+func (x *CallAPIRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallAPIRequest.ProtoReflect.Descriptor instead.
+func (*CallAPIRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CallAPIRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *CallAPIRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type CallAPIResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"` // Raw JSON response
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallAPIResponse) Reset() {
+	*x = CallAPIResponse{}
+	mi := &file_api_proto_plugin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallAPIResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallAPIResponse) ProtoMessage() {}
+
+func (x *CallAPIResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallAPIResponse.ProtoReflect.Descriptor instead.
+func (*CallAPIResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CallAPIResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CallAPIResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CallAPIResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type BusEvent struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Topic  string                 `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Source string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"` // Publishing plugin name
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*BusEvent_Json
+	//	*BusEvent_Any
+	Payload       isBusEvent_Payload `protobuf_oneof:"payload"`
+	Durable       bool               `protobuf:"varint,6,opt,name=durable,proto3" json:"durable,omitempty"`     // Redelivered until acknowledged
+	Timestamp     int64              `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix milliseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BusEvent) Reset() {
+	*x = BusEvent{}
+	mi := &file_api_proto_plugin_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BusEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BusEvent) ProtoMessage() {}
+
+func (x *BusEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BusEvent.ProtoReflect.Descriptor instead.
+func (*BusEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BusEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BusEvent) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *BusEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *BusEvent) GetPayload() isBusEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *BusEvent) GetJson() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*BusEvent_Json); ok {
+			return x.Json
+		}
+	}
+	return nil
+}
+
+func (x *BusEvent) GetAny() *anypb.Any {
+	if x != nil {
+		if x, ok := x.Payload.(*BusEvent_Any); ok {
+			return x.Any
+		}
+	}
+	return nil
+}
+
+func (x *BusEvent) GetDurable() bool {
+	if x != nil {
+		return x.Durable
+	}
+	return false
+}
+
+func (x *BusEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type isBusEvent_Payload interface {
+	isBusEvent_Payload()
+}
+
+type BusEvent_Json struct {
+	Json []byte `protobuf:"bytes,4,opt,name=json,proto3,oneof"` // JSON encoded payload
+}
+
+type BusEvent_Any struct {
+	Any *anypb.Any `protobuf:"bytes,5,opt,name=any,proto3,oneof"`
+}
+
+func (*BusEvent_Json) isBusEvent_Payload() {}
+
+func (*BusEvent_Any) isBusEvent_Payload() {}
+
+type PublishRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Topic  string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Source string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*PublishRequest_Json
+	//	*PublishRequest_Any
+	Payload       isPublishRequest_Payload `protobuf_oneof:"payload"`
+	Durable       bool                     `protobuf:"varint,5,opt,name=durable,proto3" json:"durable,omitempty"` // At-least-once instead of at-most-once delivery
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishRequest) Reset() {
+	*x = PublishRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishRequest) ProtoMessage() {}
+
+func (x *PublishRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
+func (*PublishRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PublishRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *PublishRequest) GetPayload() isPublishRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetJson() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*PublishRequest_Json); ok {
+			return x.Json
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetAny() *anypb.Any {
+	if x != nil {
+		if x, ok := x.Payload.(*PublishRequest_Any); ok {
+			return x.Any
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetDurable() bool {
+	if x != nil {
+		return x.Durable
+	}
+	return false
+}
+
+type isPublishRequest_Payload interface {
+	isPublishRequest_Payload()
+}
+
+type PublishRequest_Json struct {
+	Json []byte `protobuf:"bytes,3,opt,name=json,proto3,oneof"`
+}
+
+type PublishRequest_Any struct {
+	Any *anypb.Any `protobuf:"bytes,4,opt,name=any,proto3,oneof"`
+}
+
+func (*PublishRequest_Json) isPublishRequest_Payload() {}
+
+func (*PublishRequest_Any) isPublishRequest_Payload() {}
+
+type PublishResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Subscribers   int32                  `protobuf:"varint,2,opt,name=subscribers,proto3" json:"subscribers,omitempty"` // Number of subscribers the event was routed to
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishResponse) Reset() {
+	*x = PublishResponse{}
+	mi := &file_api_proto_plugin_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishResponse) ProtoMessage() {}
+
+func (x *PublishResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishResponse.ProtoReflect.Descriptor instead.
+func (*PublishResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PublishResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PublishResponse) GetSubscribers() int32 {
+	if x != nil {
+		return x.Subscribers
+	}
+	return 0
+}
+
+func (x *PublishResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type InvokeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"` // Target plugin name
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`                       // JSON encoded arguments
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`                         // Calling plugin name
+	TimeoutMs     int64                  `protobuf:"varint,5,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"` // Call deadline, 0 for the core default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvokeRequest) Reset() {
+	*x = InvokeRequest{}
+	mi := &file_api_proto_plugin_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeRequest) ProtoMessage() {}
+
+func (x *InvokeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeRequest.ProtoReflect.Descriptor instead.
+func (*InvokeRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *InvokeRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *InvokeRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *InvokeRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *InvokeRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *InvokeRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type InvokeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"` // JSON encoded result
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"` // Error class: not_found, unavailable, unimplemented, deadline_exceeded, internal
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InvokeResponse) Reset() {
+	*x = InvokeResponse{}
+	mi := &file_api_proto_plugin_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InvokeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InvokeResponse) ProtoMessage() {}
+
+func (x *InvokeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InvokeResponse.ProtoReflect.Descriptor instead.
+func (*InvokeResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *InvokeResponse) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *InvokeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *InvokeResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+// Frames sent by a plugin on a v2 stream
+type PluginFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*PluginFrame_Hello
+	//	*PluginFrame_Ack
+	//	*PluginFrame_Credit
+	//	*PluginFrame_Action
+	Frame         isPluginFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginFrame) Reset() {
+	*x = PluginFrame{}
+	mi := &file_api_proto_plugin_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginFrame) ProtoMessage() {}
+
+func (x *PluginFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginFrame.ProtoReflect.Descriptor instead.
+func (*PluginFrame) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *PluginFrame) GetFrame() isPluginFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *PluginFrame) GetHello() *Hello {
+	if x != nil {
+		if x, ok := x.Frame.(*PluginFrame_Hello); ok {
+			return x.Hello
+		}
+	}
+	return nil
+}
+
+func (x *PluginFrame) GetAck() *Ack {
+	if x != nil {
+		if x, ok := x.Frame.(*PluginFrame_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *PluginFrame) GetCredit() *Credit {
+	if x != nil {
+		if x, ok := x.Frame.(*PluginFrame_Credit); ok {
+			return x.Credit
+		}
+	}
+	return nil
+}
+
+func (x *PluginFrame) GetAction() *Action {
+	if x != nil {
+		if x, ok := x.Frame.(*PluginFrame_Action); ok {
+			return x.Action
+		}
+	}
+	return nil
+}
+
+type isPluginFrame_Frame interface {
+	isPluginFrame_Frame()
+}
+
+type PluginFrame_Hello struct {
+	Hello *Hello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type PluginFrame_Ack struct {
+	Ack *Ack `protobuf:"bytes,2,opt,name=ack,proto3,oneof"`
+}
+
+type PluginFrame_Credit struct {
+	Credit *Credit `protobuf:"bytes,3,opt,name=credit,proto3,oneof"`
+}
+
+type PluginFrame_Action struct {
+	Action *Action `protobuf:"bytes,4,opt,name=action,proto3,oneof"`
+}
+
+func (*PluginFrame_Hello) isPluginFrame_Frame() {}
+
+func (*PluginFrame_Ack) isPluginFrame_Frame() {}
+
+func (*PluginFrame_Credit) isPluginFrame_Frame() {}
+
+func (*PluginFrame_Action) isPluginFrame_Frame() {}
+
+// Frames sent by the core on a v2 stream
+type CoreFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*CoreFrame_Welcome
+	//	*CoreFrame_Event
+	//	*CoreFrame_ActionResult
+	Frame         isCoreFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CoreFrame) Reset() {
+	*x = CoreFrame{}
+	mi := &file_api_proto_plugin_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CoreFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CoreFrame) ProtoMessage() {}
+
+func (x *CoreFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CoreFrame.ProtoReflect.Descriptor instead.
+func (*CoreFrame) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CoreFrame) GetFrame() isCoreFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *CoreFrame) GetWelcome() *Welcome {
+	if x != nil {
+		if x, ok := x.Frame.(*CoreFrame_Welcome); ok {
+			return x.Welcome
+		}
+	}
+	return nil
+}
+
+func (x *CoreFrame) GetEvent() *PluginEvent {
+	if x != nil {
+		if x, ok := x.Frame.(*CoreFrame_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *CoreFrame) GetActionResult() *ActionResult {
+	if x != nil {
+		if x, ok := x.Frame.(*CoreFrame_ActionResult); ok {
+			return x.ActionResult
+		}
+	}
+	return nil
+}
+
+type isCoreFrame_Frame interface {
+	isCoreFrame_Frame()
+}
+
+type CoreFrame_Welcome struct {
+	Welcome *Welcome `protobuf:"bytes,1,opt,name=welcome,proto3,oneof"`
+}
+
+type CoreFrame_Event struct {
+	Event *PluginEvent `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+type CoreFrame_ActionResult struct {
+	ActionResult *ActionResult `protobuf:"bytes,3,opt,name=action_result,json=actionResult,proto3,oneof"`
+}
+
+func (*CoreFrame_Welcome) isCoreFrame_Frame() {}
+
+func (*CoreFrame_Event) isCoreFrame_Frame() {}
+
+func (*CoreFrame_ActionResult) isCoreFrame_Frame() {}
+
+type Hello struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PluginName      string                 `protobuf:"bytes,1,opt,name=plugin_name,json=pluginName,proto3" json:"plugin_name,omitempty"`
+	Session         string                 `protobuf:"bytes,2,opt,name=session,proto3" json:"session,omitempty"` // Launch session handed to the plugin by the core
+	ProtocolVersion uint32                 `protobuf:"varint,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	InitialCredits  uint32                 `protobuf:"varint,4,opt,name=initial_credits,json=initialCredits,proto3" json:"initial_credits,omitempty"` // Events the core may have in flight
+	Info            *PluginInfo            `protobuf:"bytes,5,opt,name=info,proto3" json:"info,omitempty"`
+	Token           string                 `protobuf:"bytes,6,opt,name=token,proto3" json:"token,omitempty"` // Registration token of a plugin not launched by the core
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	mi := &file_api_proto_plugin_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *Hello) GetPluginName() string {
+	if x != nil {
+		return x.PluginName
+	}
+	return ""
+}
+
+func (x *Hello) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *Hello) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Hello) GetInitialCredits() uint32 {
+	if x != nil {
+		return x.InitialCredits
+	}
+	return 0
+}
+
+func (x *Hello) GetInfo() *PluginInfo {
+	if x != nil {
+		return x.Info
+	}
+	return nil
+}
+
+func (x *Hello) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type Welcome struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ProtocolVersion uint32                 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // Protocol the core agreed to speak
+	CoreVersion     string                 `protobuf:"bytes,2,opt,name=core_version,json=coreVersion,proto3" json:"core_version,omitempty"`
+	Features        []string               `protobuf:"bytes,3,rep,name=features,proto3" json:"features,omitempty"` // Protocol features the core supports
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Welcome) Reset() {
+	*x = Welcome{}
+	mi := &file_api_proto_plugin_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HealthResponse) String() string {
+func (x *Welcome) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HealthResponse) ProtoMessage() {}
+func (*Welcome) ProtoMessage() {}
 
-func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[13]
+func (x *Welcome) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -826,50 +2311,65 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
-func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use Welcome.ProtoReflect.Descriptor instead.
+func (*Welcome) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *HealthResponse) GetHealthy() bool {
+func (x *Welcome) GetProtocolVersion() uint32 {
 	if x != nil {
-		return x.Healthy
+		return x.ProtocolVersion
 	}
-	return false
+	return 0
 }
 
-func (x *HealthResponse) GetStatus() string {
+func (x *Welcome) GetCoreVersion() string {
 	if x != nil {
-		return x.Status
+		return x.CoreVersion
 	}
 	return ""
 }
 
-type UploadGroupFileRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	GroupId       int64                  `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
-	FilePath      string                 `protobuf:"bytes,2,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"` // Absolute path to file
-	FileName      string                 `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"` // Display name
-	Folder        string                 `protobuf:"bytes,4,opt,name=folder,proto3" json:"folder,omitempty"`                     // Folder path (optional, default "/")
+func (x *Welcome) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+// An event delivered to the plugin; answered by an Ack with the same seq
+type PluginEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*PluginEvent_Message
+	//	*PluginEvent_Command
+	//	*PluginEvent_BusEvent
+	//	*PluginEvent_Invoke
+	//	*PluginEvent_Health
+	//	*PluginEvent_Shutdown
+	//	*PluginEvent_Config
+	Payload       isPluginEvent_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadGroupFileRequest) Reset() {
-	*x = UploadGroupFileRequest{}
-	mi := &file_api_proto_plugin_proto_msgTypes[14]
+func (x *PluginEvent) Reset() {
+	*x = PluginEvent{}
+	mi := &file_api_proto_plugin_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadGroupFileRequest) String() string {
+func (x *PluginEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadGroupFileRequest) ProtoMessage() {}
+func (*PluginEvent) ProtoMessage() {}
 
-func (x *UploadGroupFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[14]
+func (x *PluginEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -880,63 +2380,164 @@ func (x *UploadGroupFileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadGroupFileRequest.ProtoReflect.Descriptor instead.
-func (*UploadGroupFileRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use PluginEvent.ProtoReflect.Descriptor instead.
+func (*PluginEvent) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *UploadGroupFileRequest) GetGroupId() int64 {
+func (x *PluginEvent) GetSeq() uint64 {
 	if x != nil {
-		return x.GroupId
+		return x.Seq
 	}
 	return 0
 }
 
-func (x *UploadGroupFileRequest) GetFilePath() string {
+func (x *PluginEvent) GetPayload() isPluginEvent_Payload {
 	if x != nil {
-		return x.FilePath
+		return x.Payload
 	}
-	return ""
+	return nil
 }
 
-func (x *UploadGroupFileRequest) GetFileName() string {
+func (x *PluginEvent) GetMessage() *MessageEvent {
 	if x != nil {
-		return x.FileName
+		if x, ok := x.Payload.(*PluginEvent_Message); ok {
+			return x.Message
+		}
 	}
-	return ""
+	return nil
 }
 
-func (x *UploadGroupFileRequest) GetFolder() string {
+func (x *PluginEvent) GetCommand() *CommandEvent {
 	if x != nil {
-		return x.Folder
+		if x, ok := x.Payload.(*PluginEvent_Command); ok {
+			return x.Command
+		}
 	}
-	return ""
+	return nil
 }
 
-type UploadPrivateFileRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	FilePath      string                 `protobuf:"bytes,2,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"` // Absolute path to file
-	FileName      string                 `protobuf:"bytes,3,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"` // Display name
+func (x *PluginEvent) GetBusEvent() *BusEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginEvent_BusEvent); ok {
+			return x.BusEvent
+		}
+	}
+	return nil
+}
+
+func (x *PluginEvent) GetInvoke() *InvokeRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginEvent_Invoke); ok {
+			return x.Invoke
+		}
+	}
+	return nil
+}
+
+func (x *PluginEvent) GetHealth() *Empty {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginEvent_Health); ok {
+			return x.Health
+		}
+	}
+	return nil
+}
+
+func (x *PluginEvent) GetShutdown() *Empty {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginEvent_Shutdown); ok {
+			return x.Shutdown
+		}
+	}
+	return nil
+}
+
+func (x *PluginEvent) GetConfig() *PluginConfig {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginEvent_Config); ok {
+			return x.Config
+		}
+	}
+	return nil
+}
+
+type isPluginEvent_Payload interface {
+	isPluginEvent_Payload()
+}
+
+type PluginEvent_Message struct {
+	Message *MessageEvent `protobuf:"bytes,2,opt,name=message,proto3,oneof"`
+}
+
+type PluginEvent_Command struct {
+	Command *CommandEvent `protobuf:"bytes,3,opt,name=command,proto3,oneof"`
+}
+
+type PluginEvent_BusEvent struct {
+	BusEvent *BusEvent `protobuf:"bytes,4,opt,name=bus_event,json=busEvent,proto3,oneof"`
+}
+
+type PluginEvent_Invoke struct {
+	Invoke *InvokeRequest `protobuf:"bytes,5,opt,name=invoke,proto3,oneof"`
+}
+
+type PluginEvent_Health struct {
+	Health *Empty `protobuf:"bytes,6,opt,name=health,proto3,oneof"` // Control events do not consume credits
+}
+
+type PluginEvent_Shutdown struct {
+	Shutdown *Empty `protobuf:"bytes,7,opt,name=shutdown,proto3,oneof"`
+}
+
+type PluginEvent_Config struct {
+	Config *PluginConfig `protobuf:"bytes,8,opt,name=config,proto3,oneof"` // Configuration changed, acked with a HandleResult
+}
+
+func (*PluginEvent_Message) isPluginEvent_Payload() {}
+
+func (*PluginEvent_Command) isPluginEvent_Payload() {}
+
+func (*PluginEvent_BusEvent) isPluginEvent_Payload() {}
+
+func (*PluginEvent_Invoke) isPluginEvent_Payload() {}
+
+func (*PluginEvent_Health) isPluginEvent_Payload() {}
+
+func (*PluginEvent_Shutdown) isPluginEvent_Payload() {}
+
+func (*PluginEvent_Config) isPluginEvent_Payload() {}
+
+type Ack struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*Ack_HandleResult
+	//	*Ack_Health
+	//	*Ack_Invoke
+	//	*Ack_Empty
+	Result        isAck_Result `protobuf_oneof:"result"`
+	Credits       uint32       `protobuf:"varint,6,opt,name=credits,proto3" json:"credits,omitempty"` // Credits returned together with this ack
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadPrivateFileRequest) Reset() {
-	*x = UploadPrivateFileRequest{}
-	mi := &file_api_proto_plugin_proto_msgTypes[15]
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_api_proto_plugin_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadPrivateFileRequest) String() string {
+func (x *Ack) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadPrivateFileRequest) ProtoMessage() {}
+func (*Ack) ProtoMessage() {}
 
-func (x *UploadPrivateFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[15]
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -947,56 +2548,119 @@ func (x *UploadPrivateFileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadPrivateFileRequest.ProtoReflect.Descriptor instead.
-func (*UploadPrivateFileRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{33}
 }
 
-func (x *UploadPrivateFileRequest) GetUserId() int64 {
+func (x *Ack) GetSeq() uint64 {
 	if x != nil {
-		return x.UserId
+		return x.Seq
 	}
 	return 0
 }
 
-func (x *UploadPrivateFileRequest) GetFilePath() string {
+func (x *Ack) GetResult() isAck_Result {
 	if x != nil {
-		return x.FilePath
+		return x.Result
 	}
-	return ""
+	return nil
 }
 
-func (x *UploadPrivateFileRequest) GetFileName() string {
+func (x *Ack) GetHandleResult() *HandleResult {
 	if x != nil {
-		return x.FileName
+		if x, ok := x.Result.(*Ack_HandleResult); ok {
+			return x.HandleResult
+		}
 	}
-	return ""
+	return nil
 }
 
-type UploadFileResponse struct {
+func (x *Ack) GetHealth() *HealthResponse {
+	if x != nil {
+		if x, ok := x.Result.(*Ack_Health); ok {
+			return x.Health
+		}
+	}
+	return nil
+}
+
+func (x *Ack) GetInvoke() *InvokeResponse {
+	if x != nil {
+		if x, ok := x.Result.(*Ack_Invoke); ok {
+			return x.Invoke
+		}
+	}
+	return nil
+}
+
+func (x *Ack) GetEmpty() *Empty {
+	if x != nil {
+		if x, ok := x.Result.(*Ack_Empty); ok {
+			return x.Empty
+		}
+	}
+	return nil
+}
+
+func (x *Ack) GetCredits() uint32 {
+	if x != nil {
+		return x.Credits
+	}
+	return 0
+}
+
+type isAck_Result interface {
+	isAck_Result()
+}
+
+type Ack_HandleResult struct {
+	HandleResult *HandleResult `protobuf:"bytes,2,opt,name=handle_result,json=handleResult,proto3,oneof"`
+}
+
+type Ack_Health struct {
+	Health *HealthResponse `protobuf:"bytes,3,opt,name=health,proto3,oneof"`
+}
+
+type Ack_Invoke struct {
+	Invoke *InvokeResponse `protobuf:"bytes,4,opt,name=invoke,proto3,oneof"`
+}
+
+type Ack_Empty struct {
+	Empty *Empty `protobuf:"bytes,5,opt,name=empty,proto3,oneof"`
+}
+
+func (*Ack_HandleResult) isAck_Result() {}
+
+func (*Ack_Health) isAck_Result() {}
+
+func (*Ack_Invoke) isAck_Result() {}
+
+func (*Ack_Empty) isAck_Result() {}
+
+// Grants the core additional event credits
+type Credit struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	FileId        string                 `protobuf:"bytes,3,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"` // File ID returned by QQ
+	Credits       uint32                 `protobuf:"varint,1,opt,name=credits,proto3" json:"credits,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UploadFileResponse) Reset() {
-	*x = UploadFileResponse{}
-	mi := &file_api_proto_plugin_proto_msgTypes[16]
+func (x *Credit) Reset() {
+	*x = Credit{}
+	mi := &file_api_proto_plugin_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UploadFileResponse) String() string {
+func (x *Credit) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UploadFileResponse) ProtoMessage() {}
+func (*Credit) ProtoMessage() {}
 
-func (x *UploadFileResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[16]
+func (x *Credit) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1007,55 +2671,43 @@ func (x *UploadFileResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UploadFileResponse.ProtoReflect.Descriptor instead.
-func (*UploadFileResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{16}
-}
-
-func (x *UploadFileResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *UploadFileResponse) GetError() string {
-	if x != nil {
-		return x.Error
-	}
-	return ""
+// Deprecated: Use Credit.ProtoReflect.Descriptor instead.
+func (*Credit) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{34}
 }
 
-func (x *UploadFileResponse) GetFileId() string {
+func (x *Credit) GetCredits() uint32 {
 	if x != nil {
-		return x.FileId
+		return x.Credits
 	}
-	return ""
+	return 0
 }
 
-type CallAPIRequest struct {
+// A BotService call made over the stream
+type Action struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Action        string                 `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`                                                                           // API action name
-	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // API parameters
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"` // BotService method name, e.g. "SendMessage"
+	Request       *anypb.Any             `protobuf:"bytes,3,opt,name=request,proto3" json:"request,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CallAPIRequest) Reset() {
-	*x = CallAPIRequest{}
-	mi := &file_api_proto_plugin_proto_msgTypes[17]
+func (x *Action) Reset() {
+	*x = Action{}
+	mi := &file_api_proto_plugin_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CallAPIRequest) String() string {
+func (x *Action) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CallAPIRequest) ProtoMessage() {}
+func (*Action) ProtoMessage() {}
 
-func (x *CallAPIRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[17]
+func (x *Action) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1066,49 +2718,56 @@ func (x *CallAPIRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CallAPIRequest.ProtoReflect.Descriptor instead.
-func (*CallAPIRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use Action.ProtoReflect.Descriptor instead.
+func (*Action) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{35}
 }
 
-func (x *CallAPIRequest) GetAction() string {
+func (x *Action) GetId() uint64 {
 	if x != nil {
-		return x.Action
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Action) GetMethod() string {
+	if x != nil {
+		return x.Method
 	}
 	return ""
 }
 
-func (x *CallAPIRequest) GetParams() map[string]string {
+func (x *Action) GetRequest() *anypb.Any {
 	if x != nil {
-		return x.Params
+		return x.Request
 	}
 	return nil
 }
 
-type CallAPIResponse struct {
+type ActionResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"` // Raw JSON response
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Response      *anypb.Any             `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CallAPIResponse) Reset() {
-	*x = CallAPIResponse{}
-	mi := &file_api_proto_plugin_proto_msgTypes[18]
+func (x *ActionResult) Reset() {
+	*x = ActionResult{}
+	mi := &file_api_proto_plugin_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CallAPIResponse) String() string {
+func (x *ActionResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CallAPIResponse) ProtoMessage() {}
+func (*ActionResult) ProtoMessage() {}
 
-func (x *CallAPIResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_plugin_proto_msgTypes[18]
+func (x *ActionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_plugin_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1119,38 +2778,38 @@ func (x *CallAPIResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CallAPIResponse.ProtoReflect.Descriptor instead.
-func (*CallAPIResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_plugin_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use ActionResult.ProtoReflect.Descriptor instead.
+func (*ActionResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_plugin_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *CallAPIResponse) GetSuccess() bool {
+func (x *ActionResult) GetId() uint64 {
 	if x != nil {
-		return x.Success
+		return x.Id
 	}
-	return false
+	return 0
 }
 
-func (x *CallAPIResponse) GetError() string {
+func (x *ActionResult) GetResponse() *anypb.Any {
 	if x != nil {
-		return x.Error
+		return x.Response
 	}
-	return ""
+	return nil
 }
 
-func (x *CallAPIResponse) GetData() []byte {
+func (x *ActionResult) GetError() string {
 	if x != nil {
-		return x.Data
+		return x.Error
 	}
-	return nil
+	return ""
 }
 
 var File_api_proto_plugin_proto protoreflect.FileDescriptor
 
 const file_api_proto_plugin_proto_rawDesc = "" +
 	"\n" +
-	"\x16api/proto/plugin.proto\x12\x06plugin\"\a\n" +
-	"\x05Empty\"\xc0\x01\n" +
+	"\x16api/proto/plugin.proto\x12\x06plugin\x1a\x19google/protobuf/any.proto\"\a\n" +
+	"\x05Empty\"\x98\x05\n" +
 	"\n" +
 	"PluginInfo\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
@@ -1158,7 +2817,49 @@ const file_api_proto_plugin_proto_rawDesc = "" +
 	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x16\n" +
 	"\x06author\x18\x04 \x01(\tR\x06author\x12\x1a\n" +
 	"\bcommands\x18\x05 \x03(\tR\bcommands\x12.\n" +
-	"\x13handle_all_messages\x18\x06 \x01(\bR\x11handleAllMessages\"\xa1\x02\n" +
+	"\x13handle_all_messages\x18\x06 \x01(\bR\x11handleAllMessages\x12<\n" +
+	"\x0emessage_filter\x18\a \x01(\v2\x15.plugin.MessageFilterR\rmessageFilter\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\x05R\bpriority\x12!\n" +
+	"\fobserve_only\x18\t \x01(\bR\vobserveOnly\x12!\n" +
+	"\fevent_topics\x18\n" +
+	" \x03(\tR\veventTopics\x12\x18\n" +
+	"\amethods\x18\v \x03(\tR\amethods\x12\x1d\n" +
+	"\n" +
+	"depends_on\x18\f \x03(\tR\tdependsOn\x12)\n" +
+	"\x10protocol_version\x18\r \x01(\rR\x0fprotocolVersion\x128\n" +
+	"\fcapabilities\x18\x0e \x01(\v2\x14.plugin.CapabilitiesR\fcapabilities\x12\x1f\n" +
+	"\vsdk_version\x18\x0f \x01(\tR\n" +
+	"sdkVersion\x12\x1a\n" +
+	"\bfeatures\x18\x10 \x03(\tR\bfeatures\x12!\n" +
+	"\fcore_version\x18\x11 \x01(\tR\vcoreVersion\x128\n" +
+	"\rconfig_schema\x18\x12 \x03(\v2\x13.plugin.ConfigFieldR\fconfigSchema\"\xbd\x01\n" +
+	"\vConfigField\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x18\n" +
+	"\adefault\x18\x04 \x01(\tR\adefault\x12\x1a\n" +
+	"\brequired\x18\x05 \x01(\bR\brequired\x12\x16\n" +
+	"\x06secret\x18\x06 \x01(\bR\x06secret\x12\x18\n" +
+	"\aoptions\x18\a \x03(\tR\aoptions\"\xb9\x01\n" +
+	"\fPluginConfig\x128\n" +
+	"\x06values\x18\x01 \x03(\v2 .plugin.PluginConfig.ValuesEntryR\x06values\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\x12\x18\n" +
+	"\achanged\x18\x03 \x03(\tR\achanged\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"w\n" +
+	"\fCapabilities\x12\x12\n" +
+	"\x04rpcs\x18\x01 \x03(\tR\x04rpcs\x12\x18\n" +
+	"\aactions\x18\x02 \x03(\tR\aactions\x12\x16\n" +
+	"\x06groups\x18\x03 \x03(\x03R\x06groups\x12!\n" +
+	"\fupload_paths\x18\x04 \x03(\tR\vuploadPaths\"\xc9\x01\n" +
+	"\rMessageFilter\x12\x1a\n" +
+	"\bkeywords\x18\x01 \x03(\tR\bkeywords\x12\x1a\n" +
+	"\bpatterns\x18\x02 \x03(\tR\bpatterns\x12\x1b\n" +
+	"\tgroup_ids\x18\x03 \x03(\x03R\bgroupIds\x12\x19\n" +
+	"\buser_ids\x18\x04 \x03(\x03R\auserIds\x12#\n" +
+	"\rsegment_types\x18\x05 \x03(\tR\fsegmentTypes\x12#\n" +
+	"\rmessage_types\x18\x06 \x03(\tR\fmessageTypes\"\xa1\x02\n" +
 	"\fMessageEvent\x12\x1d\n" +
 	"\n" +
 	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x17\n" +
@@ -1235,13 +2936,98 @@ const file_api_proto_plugin_proto_rawDesc = "" +
 	"\x0fCallAPIResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12\x12\n" +
-	"\x04data\x18\x03 \x01(\fR\x04data2\x8a\x02\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"\xcb\x01\n" +
+	"\bBusEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05topic\x18\x02 \x01(\tR\x05topic\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x12\x14\n" +
+	"\x04json\x18\x04 \x01(\fH\x00R\x04json\x12(\n" +
+	"\x03any\x18\x05 \x01(\v2\x14.google.protobuf.AnyH\x00R\x03any\x12\x18\n" +
+	"\adurable\x18\x06 \x01(\bR\adurable\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\x03R\ttimestampB\t\n" +
+	"\apayload\"\xa3\x01\n" +
+	"\x0ePublishRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x14\n" +
+	"\x04json\x18\x03 \x01(\fH\x00R\x04json\x12(\n" +
+	"\x03any\x18\x04 \x01(\v2\x14.google.protobuf.AnyH\x00R\x03any\x12\x18\n" +
+	"\adurable\x18\x05 \x01(\bR\adurableB\t\n" +
+	"\apayload\"Y\n" +
+	"\x0fPublishResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
+	"\vsubscribers\x18\x02 \x01(\x05R\vsubscribers\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x90\x01\n" +
+	"\rInvokeRequest\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x18\n" +
+	"\apayload\x18\x03 \x01(\fR\apayload\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x05 \x01(\x03R\ttimeoutMs\"T\n" +
+	"\x0eInvokeResponse\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\"\xb2\x01\n" +
+	"\vPluginFrame\x12%\n" +
+	"\x05hello\x18\x01 \x01(\v2\r.plugin.HelloH\x00R\x05hello\x12\x1f\n" +
+	"\x03ack\x18\x02 \x01(\v2\v.plugin.AckH\x00R\x03ack\x12(\n" +
+	"\x06credit\x18\x03 \x01(\v2\x0e.plugin.CreditH\x00R\x06credit\x12(\n" +
+	"\x06action\x18\x04 \x01(\v2\x0e.plugin.ActionH\x00R\x06actionB\a\n" +
+	"\x05frame\"\xab\x01\n" +
+	"\tCoreFrame\x12+\n" +
+	"\awelcome\x18\x01 \x01(\v2\x0f.plugin.WelcomeH\x00R\awelcome\x12+\n" +
+	"\x05event\x18\x02 \x01(\v2\x13.plugin.PluginEventH\x00R\x05event\x12;\n" +
+	"\raction_result\x18\x03 \x01(\v2\x14.plugin.ActionResultH\x00R\factionResultB\a\n" +
+	"\x05frame\"\xd4\x01\n" +
+	"\x05Hello\x12\x1f\n" +
+	"\vplugin_name\x18\x01 \x01(\tR\n" +
+	"pluginName\x12\x18\n" +
+	"\asession\x18\x02 \x01(\tR\asession\x12)\n" +
+	"\x10protocol_version\x18\x03 \x01(\rR\x0fprotocolVersion\x12'\n" +
+	"\x0finitial_credits\x18\x04 \x01(\rR\x0einitialCredits\x12&\n" +
+	"\x04info\x18\x05 \x01(\v2\x12.plugin.PluginInfoR\x04info\x12\x14\n" +
+	"\x05token\x18\x06 \x01(\tR\x05token\"s\n" +
+	"\aWelcome\x12)\n" +
+	"\x10protocol_version\x18\x01 \x01(\rR\x0fprotocolVersion\x12!\n" +
+	"\fcore_version\x18\x02 \x01(\tR\vcoreVersion\x12\x1a\n" +
+	"\bfeatures\x18\x03 \x03(\tR\bfeatures\"\xf6\x02\n" +
+	"\vPluginEvent\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x120\n" +
+	"\amessage\x18\x02 \x01(\v2\x14.plugin.MessageEventH\x00R\amessage\x120\n" +
+	"\acommand\x18\x03 \x01(\v2\x14.plugin.CommandEventH\x00R\acommand\x12/\n" +
+	"\tbus_event\x18\x04 \x01(\v2\x10.plugin.BusEventH\x00R\bbusEvent\x12/\n" +
+	"\x06invoke\x18\x05 \x01(\v2\x15.plugin.InvokeRequestH\x00R\x06invoke\x12'\n" +
+	"\x06health\x18\x06 \x01(\v2\r.plugin.EmptyH\x00R\x06health\x12+\n" +
+	"\bshutdown\x18\a \x01(\v2\r.plugin.EmptyH\x00R\bshutdown\x12.\n" +
+	"\x06config\x18\b \x01(\v2\x14.plugin.PluginConfigH\x00R\x06configB\t\n" +
+	"\apayload\"\x83\x02\n" +
+	"\x03Ack\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12;\n" +
+	"\rhandle_result\x18\x02 \x01(\v2\x14.plugin.HandleResultH\x00R\fhandleResult\x120\n" +
+	"\x06health\x18\x03 \x01(\v2\x16.plugin.HealthResponseH\x00R\x06health\x120\n" +
+	"\x06invoke\x18\x04 \x01(\v2\x16.plugin.InvokeResponseH\x00R\x06invoke\x12%\n" +
+	"\x05empty\x18\x05 \x01(\v2\r.plugin.EmptyH\x00R\x05empty\x12\x18\n" +
+	"\acredits\x18\x06 \x01(\rR\acreditsB\b\n" +
+	"\x06result\"\"\n" +
+	"\x06Credit\x12\x18\n" +
+	"\acredits\x18\x01 \x01(\rR\acredits\"`\n" +
+	"\x06Action\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12.\n" +
+	"\arequest\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\arequest\"f\n" +
+	"\fActionResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x120\n" +
+	"\bresponse\x18\x02 \x01(\v2\x14.google.protobuf.AnyR\bresponse\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error2\xb6\x03\n" +
 	"\rPluginService\x12,\n" +
 	"\aGetInfo\x12\r.plugin.Empty\x1a\x12.plugin.PluginInfo\x127\n" +
 	"\tOnMessage\x12\x14.plugin.MessageEvent\x1a\x14.plugin.HandleResult\x127\n" +
 	"\tOnCommand\x12\x14.plugin.CommandEvent\x1a\x14.plugin.HandleResult\x12/\n" +
 	"\x06Health\x12\r.plugin.Empty\x1a\x16.plugin.HealthResponse\x12(\n" +
-	"\bShutdown\x12\r.plugin.Empty\x1a\r.plugin.Empty2\xd9\x03\n" +
+	"\bShutdown\x12\r.plugin.Empty\x1a\r.plugin.Empty\x121\n" +
+	"\aOnEvent\x12\x10.plugin.BusEvent\x1a\x14.plugin.HandleResult\x129\n" +
+	"\bOnInvoke\x12\x15.plugin.InvokeRequest\x1a\x16.plugin.InvokeResponse\x12<\n" +
+	"\x0eOnConfigChange\x12\x14.plugin.PluginConfig\x1a\x14.plugin.HandleResult2\x86\x05\n" +
 	"\n" +
 	"BotService\x12F\n" +
 	"\vSendMessage\x12\x1a.plugin.SendMessageRequest\x1a\x1b.plugin.SendMessageResponse\x12;\n" +
@@ -1250,7 +3036,13 @@ const file_api_proto_plugin_proto_rawDesc = "" +
 	"\x03Log\x12\x12.plugin.LogRequest\x1a\r.plugin.Empty\x12M\n" +
 	"\x0fUploadGroupFile\x12\x1e.plugin.UploadGroupFileRequest\x1a\x1a.plugin.UploadFileResponse\x12Q\n" +
 	"\x11UploadPrivateFile\x12 .plugin.UploadPrivateFileRequest\x1a\x1a.plugin.UploadFileResponse\x12:\n" +
-	"\aCallAPI\x12\x16.plugin.CallAPIRequest\x1a\x17.plugin.CallAPIResponseB5Z3github.com/DaikonSushi/bot-platform/api/proto;protob\x06proto3"
+	"\aCallAPI\x12\x16.plugin.CallAPIRequest\x1a\x17.plugin.CallAPIResponse\x12:\n" +
+	"\aPublish\x12\x16.plugin.PublishRequest\x1a\x17.plugin.PublishResponse\x12=\n" +
+	"\fInvokePlugin\x12\x15.plugin.InvokeRequest\x1a\x16.plugin.InvokeResponse\x120\n" +
+	"\tGetConfig\x12\r.plugin.Empty\x1a\x14.plugin.PluginConfig2C\n" +
+	"\n" +
+	"PluginHost\x125\n" +
+	"\aConnect\x12\x13.plugin.PluginFrame\x1a\x11.plugin.CoreFrame(\x010\x01B5Z3github.com/DaikonSushi/bot-platform/api/proto;protob\x06proto3"
 
 var (
 	file_api_proto_plugin_proto_rawDescOnce sync.Once
@@ -1264,66 +3056,127 @@ func file_api_proto_plugin_proto_rawDescGZIP() []byte {
 	return file_api_proto_plugin_proto_rawDescData
 }
 
-var file_api_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_api_proto_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 40)
 var file_api_proto_plugin_proto_goTypes = []any{
 	(*Empty)(nil),                    // 0: plugin.Empty
 	(*PluginInfo)(nil),               // 1: plugin.PluginInfo
-	(*MessageEvent)(nil),             // 2: plugin.MessageEvent
-	(*MessageSegment)(nil),           // 3: plugin.MessageSegment
-	(*CommandEvent)(nil),             // 4: plugin.CommandEvent
-	(*HandleResult)(nil),             // 5: plugin.HandleResult
-	(*SendMessageRequest)(nil),       // 6: plugin.SendMessageRequest
-	(*SendMessageResponse)(nil),      // 7: plugin.SendMessageResponse
-	(*GetUserInfoRequest)(nil),       // 8: plugin.GetUserInfoRequest
-	(*UserInfo)(nil),                 // 9: plugin.UserInfo
-	(*GetGroupInfoRequest)(nil),      // 10: plugin.GetGroupInfoRequest
-	(*GroupInfo)(nil),                // 11: plugin.GroupInfo
-	(*LogRequest)(nil),               // 12: plugin.LogRequest
-	(*HealthResponse)(nil),           // 13: plugin.HealthResponse
-	(*UploadGroupFileRequest)(nil),   // 14: plugin.UploadGroupFileRequest
-	(*UploadPrivateFileRequest)(nil), // 15: plugin.UploadPrivateFileRequest
-	(*UploadFileResponse)(nil),       // 16: plugin.UploadFileResponse
-	(*CallAPIRequest)(nil),           // 17: plugin.CallAPIRequest
-	(*CallAPIResponse)(nil),          // 18: plugin.CallAPIResponse
-	nil,                              // 19: plugin.MessageSegment.DataEntry
-	nil,                              // 20: plugin.CallAPIRequest.ParamsEntry
+	(*ConfigField)(nil),              // 2: plugin.ConfigField
+	(*PluginConfig)(nil),             // 3: plugin.PluginConfig
+	(*Capabilities)(nil),             // 4: plugin.Capabilities
+	(*MessageFilter)(nil),            // 5: plugin.MessageFilter
+	(*MessageEvent)(nil),             // 6: plugin.MessageEvent
+	(*MessageSegment)(nil),           // 7: plugin.MessageSegment
+	(*CommandEvent)(nil),             // 8: plugin.CommandEvent
+	(*HandleResult)(nil),             // 9: plugin.HandleResult
+	(*SendMessageRequest)(nil),       // 10: plugin.SendMessageRequest
+	(*SendMessageResponse)(nil),      // 11: plugin.SendMessageResponse
+	(*GetUserInfoRequest)(nil),       // 12: plugin.GetUserInfoRequest
+	(*UserInfo)(nil),                 // 13: plugin.UserInfo
+	(*GetGroupInfoRequest)(nil),      // 14: plugin.GetGroupInfoRequest
+	(*GroupInfo)(nil),                // 15: plugin.GroupInfo
+	(*LogRequest)(nil),               // 16: plugin.LogRequest
+	(*HealthResponse)(nil),           // 17: plugin.HealthResponse
+	(*UploadGroupFileRequest)(nil),   // 18: plugin.UploadGroupFileRequest
+	(*UploadPrivateFileRequest)(nil), // 19: plugin.UploadPrivateFileRequest
+	(*UploadFileResponse)(nil),       // 20: plugin.UploadFileResponse
+	(*CallAPIRequest)(nil),           // 21: plugin.CallAPIRequest
+	(*CallAPIResponse)(nil),          // 22: plugin.CallAPIResponse
+	(*BusEvent)(nil),                 // 23: plugin.BusEvent
+	(*PublishRequest)(nil),           // 24: plugin.PublishRequest
+	(*PublishResponse)(nil),          // 25: plugin.PublishResponse
+	(*InvokeRequest)(nil),            // 26: plugin.InvokeRequest
+	(*InvokeResponse)(nil),           // 27: plugin.InvokeResponse
+	(*PluginFrame)(nil),              // 28: plugin.PluginFrame
+	(*CoreFrame)(nil),                // 29: plugin.CoreFrame
+	(*Hello)(nil),                    // 30: plugin.Hello
+	(*Welcome)(nil),                  // 31: plugin.Welcome
+	(*PluginEvent)(nil),              // 32: plugin.PluginEvent
+	(*Ack)(nil),                      // 33: plugin.Ack
+	(*Credit)(nil),                   // 34: plugin.Credit
+	(*Action)(nil),                   // 35: plugin.Action
+	(*ActionResult)(nil),             // 36: plugin.ActionResult
+	nil,                              // 37: plugin.PluginConfig.ValuesEntry
+	nil,                              // 38: plugin.MessageSegment.DataEntry
+	nil,                              // 39: plugin.CallAPIRequest.ParamsEntry
+	(*anypb.Any)(nil),                // 40: google.protobuf.Any
 }
 var file_api_proto_plugin_proto_depIdxs = []int32{
-	3,  // 0: plugin.MessageEvent.segments:type_name -> plugin.MessageSegment
-	9,  // 1: plugin.MessageEvent.sender:type_name -> plugin.UserInfo
-	19, // 2: plugin.MessageSegment.data:type_name -> plugin.MessageSegment.DataEntry
-	2,  // 3: plugin.CommandEvent.message:type_name -> plugin.MessageEvent
-	3,  // 4: plugin.SendMessageRequest.segments:type_name -> plugin.MessageSegment
-	20, // 5: plugin.CallAPIRequest.params:type_name -> plugin.CallAPIRequest.ParamsEntry
-	0,  // 6: plugin.PluginService.GetInfo:input_type -> plugin.Empty
-	2,  // 7: plugin.PluginService.OnMessage:input_type -> plugin.MessageEvent
-	4,  // 8: plugin.PluginService.OnCommand:input_type -> plugin.CommandEvent
-	0,  // 9: plugin.PluginService.Health:input_type -> plugin.Empty
-	0,  // 10: plugin.PluginService.Shutdown:input_type -> plugin.Empty
-	6,  // 11: plugin.BotService.SendMessage:input_type -> plugin.SendMessageRequest
-	8,  // 12: plugin.BotService.GetUserInfo:input_type -> plugin.GetUserInfoRequest
-	10, // 13: plugin.BotService.GetGroupInfo:input_type -> plugin.GetGroupInfoRequest
-	12, // 14: plugin.BotService.Log:input_type -> plugin.LogRequest
-	14, // 15: plugin.BotService.UploadGroupFile:input_type -> plugin.UploadGroupFileRequest
-	15, // 16: plugin.BotService.UploadPrivateFile:input_type -> plugin.UploadPrivateFileRequest
-	17, // 17: plugin.BotService.CallAPI:input_type -> plugin.CallAPIRequest
-	1,  // 18: plugin.PluginService.GetInfo:output_type -> plugin.PluginInfo
-	5,  // 19: plugin.PluginService.OnMessage:output_type -> plugin.HandleResult
-	5,  // 20: plugin.PluginService.OnCommand:output_type -> plugin.HandleResult
-	13, // 21: plugin.PluginService.Health:output_type -> plugin.HealthResponse
-	0,  // 22: plugin.PluginService.Shutdown:output_type -> plugin.Empty
-	7,  // 23: plugin.BotService.SendMessage:output_type -> plugin.SendMessageResponse
-	9,  // 24: plugin.BotService.GetUserInfo:output_type -> plugin.UserInfo
-	11, // 25: plugin.BotService.GetGroupInfo:output_type -> plugin.GroupInfo
-	0,  // 26: plugin.BotService.Log:output_type -> plugin.Empty
-	16, // 27: plugin.BotService.UploadGroupFile:output_type -> plugin.UploadFileResponse
-	16, // 28: plugin.BotService.UploadPrivateFile:output_type -> plugin.UploadFileResponse
-	18, // 29: plugin.BotService.CallAPI:output_type -> plugin.CallAPIResponse
-	18, // [18:30] is the sub-list for method output_type
-	6,  // [6:18] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	5,  // 0: plugin.PluginInfo.message_filter:type_name -> plugin.MessageFilter
+	4,  // 1: plugin.PluginInfo.capabilities:type_name -> plugin.Capabilities
+	2,  // 2: plugin.PluginInfo.config_schema:type_name -> plugin.ConfigField
+	37, // 3: plugin.PluginConfig.values:type_name -> plugin.PluginConfig.ValuesEntry
+	7,  // 4: plugin.MessageEvent.segments:type_name -> plugin.MessageSegment
+	13, // 5: plugin.MessageEvent.sender:type_name -> plugin.UserInfo
+	38, // 6: plugin.MessageSegment.data:type_name -> plugin.MessageSegment.DataEntry
+	6,  // 7: plugin.CommandEvent.message:type_name -> plugin.MessageEvent
+	7,  // 8: plugin.SendMessageRequest.segments:type_name -> plugin.MessageSegment
+	39, // 9: plugin.CallAPIRequest.params:type_name -> plugin.CallAPIRequest.ParamsEntry
+	40, // 10: plugin.BusEvent.any:type_name -> google.protobuf.Any
+	40, // 11: plugin.PublishRequest.any:type_name -> google.protobuf.Any
+	30, // 12: plugin.PluginFrame.hello:type_name -> plugin.Hello
+	33, // 13: plugin.PluginFrame.ack:type_name -> plugin.Ack
+	34, // 14: plugin.PluginFrame.credit:type_name -> plugin.Credit
+	35, // 15: plugin.PluginFrame.action:type_name -> plugin.Action
+	31, // 16: plugin.CoreFrame.welcome:type_name -> plugin.Welcome
+	32, // 17: plugin.CoreFrame.event:type_name -> plugin.PluginEvent
+	36, // 18: plugin.CoreFrame.action_result:type_name -> plugin.ActionResult
+	1,  // 19: plugin.Hello.info:type_name -> plugin.PluginInfo
+	6,  // 20: plugin.PluginEvent.message:type_name -> plugin.MessageEvent
+	8,  // 21: plugin.PluginEvent.command:type_name -> plugin.CommandEvent
+	23, // 22: plugin.PluginEvent.bus_event:type_name -> plugin.BusEvent
+	26, // 23: plugin.PluginEvent.invoke:type_name -> plugin.InvokeRequest
+	0,  // 24: plugin.PluginEvent.health:type_name -> plugin.Empty
+	0,  // 25: plugin.PluginEvent.shutdown:type_name -> plugin.Empty
+	3,  // 26: plugin.PluginEvent.config:type_name -> plugin.PluginConfig
+	9,  // 27: plugin.Ack.handle_result:type_name -> plugin.HandleResult
+	17, // 28: plugin.Ack.health:type_name -> plugin.HealthResponse
+	27, // 29: plugin.Ack.invoke:type_name -> plugin.InvokeResponse
+	0,  // 30: plugin.Ack.empty:type_name -> plugin.Empty
+	40, // 31: plugin.Action.request:type_name -> google.protobuf.Any
+	40, // 32: plugin.ActionResult.response:type_name -> google.protobuf.Any
+	0,  // 33: plugin.PluginService.GetInfo:input_type -> plugin.Empty
+	6,  // 34: plugin.PluginService.OnMessage:input_type -> plugin.MessageEvent
+	8,  // 35: plugin.PluginService.OnCommand:input_type -> plugin.CommandEvent
+	0,  // 36: plugin.PluginService.Health:input_type -> plugin.Empty
+	0,  // 37: plugin.PluginService.Shutdown:input_type -> plugin.Empty
+	23, // 38: plugin.PluginService.OnEvent:input_type -> plugin.BusEvent
+	26, // 39: plugin.PluginService.OnInvoke:input_type -> plugin.InvokeRequest
+	3,  // 40: plugin.PluginService.OnConfigChange:input_type -> plugin.PluginConfig
+	10, // 41: plugin.BotService.SendMessage:input_type -> plugin.SendMessageRequest
+	12, // 42: plugin.BotService.GetUserInfo:input_type -> plugin.GetUserInfoRequest
+	14, // 43: plugin.BotService.GetGroupInfo:input_type -> plugin.GetGroupInfoRequest
+	16, // 44: plugin.BotService.Log:input_type -> plugin.LogRequest
+	18, // 45: plugin.BotService.UploadGroupFile:input_type -> plugin.UploadGroupFileRequest
+	19, // 46: plugin.BotService.UploadPrivateFile:input_type -> plugin.UploadPrivateFileRequest
+	21, // 47: plugin.BotService.CallAPI:input_type -> plugin.CallAPIRequest
+	24, // 48: plugin.BotService.Publish:input_type -> plugin.PublishRequest
+	26, // 49: plugin.BotService.InvokePlugin:input_type -> plugin.InvokeRequest
+	0,  // 50: plugin.BotService.GetConfig:input_type -> plugin.Empty
+	28, // 51: plugin.PluginHost.Connect:input_type -> plugin.PluginFrame
+	1,  // 52: plugin.PluginService.GetInfo:output_type -> plugin.PluginInfo
+	9,  // 53: plugin.PluginService.OnMessage:output_type -> plugin.HandleResult
+	9,  // 54: plugin.PluginService.OnCommand:output_type -> plugin.HandleResult
+	17, // 55: plugin.PluginService.Health:output_type -> plugin.HealthResponse
+	0,  // 56: plugin.PluginService.Shutdown:output_type -> plugin.Empty
+	9,  // 57: plugin.PluginService.OnEvent:output_type -> plugin.HandleResult
+	27, // 58: plugin.PluginService.OnInvoke:output_type -> plugin.InvokeResponse
+	9,  // 59: plugin.PluginService.OnConfigChange:output_type -> plugin.HandleResult
+	11, // 60: plugin.BotService.SendMessage:output_type -> plugin.SendMessageResponse
+	13, // 61: plugin.BotService.GetUserInfo:output_type -> plugin.UserInfo
+	15, // 62: plugin.BotService.GetGroupInfo:output_type -> plugin.GroupInfo
+	0,  // 63: plugin.BotService.Log:output_type -> plugin.Empty
+	20, // 64: plugin.BotService.UploadGroupFile:output_type -> plugin.UploadFileResponse
+	20, // 65: plugin.BotService.UploadPrivateFile:output_type -> plugin.UploadFileResponse
+	22, // 66: plugin.BotService.CallAPI:output_type -> plugin.CallAPIResponse
+	25, // 67: plugin.BotService.Publish:output_type -> plugin.PublishResponse
+	27, // 68: plugin.BotService.InvokePlugin:output_type -> plugin.InvokeResponse
+	3,  // 69: plugin.BotService.GetConfig:output_type -> plugin.PluginConfig
+	29, // 70: plugin.PluginHost.Connect:output_type -> plugin.CoreFrame
+	52, // [52:71] is the sub-list for method output_type
+	33, // [33:52] is the sub-list for method input_type
+	33, // [33:33] is the sub-list for extension type_name
+	33, // [33:33] is the sub-list for extension extendee
+	0,  // [0:33] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_plugin_proto_init() }
@@ -1331,15 +3184,49 @@ func file_api_proto_plugin_proto_init() {
 	if File_api_proto_plugin_proto != nil {
 		return
 	}
+	file_api_proto_plugin_proto_msgTypes[23].OneofWrappers = []any{
+		(*BusEvent_Json)(nil),
+		(*BusEvent_Any)(nil),
+	}
+	file_api_proto_plugin_proto_msgTypes[24].OneofWrappers = []any{
+		(*PublishRequest_Json)(nil),
+		(*PublishRequest_Any)(nil),
+	}
+	file_api_proto_plugin_proto_msgTypes[28].OneofWrappers = []any{
+		(*PluginFrame_Hello)(nil),
+		(*PluginFrame_Ack)(nil),
+		(*PluginFrame_Credit)(nil),
+		(*PluginFrame_Action)(nil),
+	}
+	file_api_proto_plugin_proto_msgTypes[29].OneofWrappers = []any{
+		(*CoreFrame_Welcome)(nil),
+		(*CoreFrame_Event)(nil),
+		(*CoreFrame_ActionResult)(nil),
+	}
+	file_api_proto_plugin_proto_msgTypes[32].OneofWrappers = []any{
+		(*PluginEvent_Message)(nil),
+		(*PluginEvent_Command)(nil),
+		(*PluginEvent_BusEvent)(nil),
+		(*PluginEvent_Invoke)(nil),
+		(*PluginEvent_Health)(nil),
+		(*PluginEvent_Shutdown)(nil),
+		(*PluginEvent_Config)(nil),
+	}
+	file_api_proto_plugin_proto_msgTypes[33].OneofWrappers = []any{
+		(*Ack_HandleResult)(nil),
+		(*Ack_Health)(nil),
+		(*Ack_Invoke)(nil),
+		(*Ack_Empty)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_plugin_proto_rawDesc), len(file_api_proto_plugin_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   21,
+			NumMessages:   40,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_api_proto_plugin_proto_goTypes,
 		DependencyIndexes: file_api_proto_plugin_proto_depIdxs,
@@ -19,11 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PluginService_GetInfo_FullMethodName   = "/plugin.PluginService/GetInfo"
-	PluginService_OnMessage_FullMethodName = "/plugin.PluginService/OnMessage"
-	PluginService_OnCommand_FullMethodName = "/plugin.PluginService/OnCommand"
-	PluginService_Health_FullMethodName    = "/plugin.PluginService/Health"
-	PluginService_Shutdown_FullMethodName  = "/plugin.PluginService/Shutdown"
+	PluginService_GetInfo_FullMethodName        = "/plugin.PluginService/GetInfo"
+	PluginService_OnMessage_FullMethodName      = "/plugin.PluginService/OnMessage"
+	PluginService_OnCommand_FullMethodName      = "/plugin.PluginService/OnCommand"
+	PluginService_Health_FullMethodName         = "/plugin.PluginService/Health"
+	PluginService_Shutdown_FullMethodName       = "/plugin.PluginService/Shutdown"
+	PluginService_OnEvent_FullMethodName        = "/plugin.PluginService/OnEvent"
+	PluginService_OnInvoke_FullMethodName       = "/plugin.PluginService/OnInvoke"
+	PluginService_OnConfigChange_FullMethodName = "/plugin.PluginService/OnConfigChange"
 )
 
 // PluginServiceClient is the client API for PluginService service.
@@ -42,6 +45,12 @@ type PluginServiceClient interface {
 	Health(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*HealthResponse, error)
 	// Shutdown plugin gracefully
 	Shutdown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	// Handle an event published on the plugin event bus
+	OnEvent(ctx context.Context, in *BusEvent, opts ...grpc.CallOption) (*HandleResult, error)
+	// Handle a call to one of the methods declared in PluginInfo
+	OnInvoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error)
+	// Receive the plugin's configuration after an admin changed it
+	OnConfigChange(ctx context.Context, in *PluginConfig, opts ...grpc.CallOption) (*HandleResult, error)
 }
 
 type pluginServiceClient struct {
@@ -102,6 +111,36 @@ func (c *pluginServiceClient) Shutdown(ctx context.Context, in *Empty, opts ...g
 	return out, nil
 }
 
+func (c *pluginServiceClient) OnEvent(ctx context.Context, in *BusEvent, opts ...grpc.CallOption) (*HandleResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandleResult)
+	err := c.cc.Invoke(ctx, PluginService_OnEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) OnInvoke(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InvokeResponse)
+	err := c.cc.Invoke(ctx, PluginService_OnInvoke_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginServiceClient) OnConfigChange(ctx context.Context, in *PluginConfig, opts ...grpc.CallOption) (*HandleResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandleResult)
+	err := c.cc.Invoke(ctx, PluginService_OnConfigChange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PluginServiceServer is the server API for PluginService service.
 // All implementations must embed UnimplementedPluginServiceServer
 // for forward compatibility.
@@ -118,6 +157,12 @@ type PluginServiceServer interface {
 	Health(context.Context, *Empty) (*HealthResponse, error)
 	// Shutdown plugin gracefully
 	Shutdown(context.Context, *Empty) (*Empty, error)
+	// Handle an event published on the plugin event bus
+	OnEvent(context.Context, *BusEvent) (*HandleResult, error)
+	// Handle a call to one of the methods declared in PluginInfo
+	OnInvoke(context.Context, *InvokeRequest) (*InvokeResponse, error)
+	// Receive the plugin's configuration after an admin changed it
+	OnConfigChange(context.Context, *PluginConfig) (*HandleResult, error)
 	mustEmbedUnimplementedPluginServiceServer()
 }
 
@@ -143,6 +188,15 @@ func (UnimplementedPluginServiceServer) Health(context.Context, *Empty) (*Health
 func (UnimplementedPluginServiceServer) Shutdown(context.Context, *Empty) (*Empty, error) {
 	return nil, status.Error(codes.Unimplemented, "method Shutdown not implemented")
 }
+func (UnimplementedPluginServiceServer) OnEvent(context.Context, *BusEvent) (*HandleResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method OnEvent not implemented")
+}
+func (UnimplementedPluginServiceServer) OnInvoke(context.Context, *InvokeRequest) (*InvokeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method OnInvoke not implemented")
+}
+func (UnimplementedPluginServiceServer) OnConfigChange(context.Context, *PluginConfig) (*HandleResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method OnConfigChange not implemented")
+}
 func (UnimplementedPluginServiceServer) mustEmbedUnimplementedPluginServiceServer() {}
 func (UnimplementedPluginServiceServer) testEmbeddedByValue()                       {}
 
@@ -254,6 +308,60 @@ func _PluginService_Shutdown_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PluginService_OnEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BusEvent)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).OnEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_OnEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).OnEvent(ctx, req.(*BusEvent))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_OnInvoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).OnInvoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_OnInvoke_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).OnInvoke(ctx, req.(*InvokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PluginService_OnConfigChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PluginConfig)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginServiceServer).OnConfigChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PluginService_OnConfigChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginServiceServer).OnConfigChange(ctx, req.(*PluginConfig))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // PluginService_ServiceDesc is the grpc.ServiceDesc for PluginService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -281,6 +389,18 @@ var PluginService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Shutdown",
 			Handler:    _PluginService_Shutdown_Handler,
 		},
+		{
+			MethodName: "OnEvent",
+			Handler:    _PluginService_OnEvent_Handler,
+		},
+		{
+			MethodName: "OnInvoke",
+			Handler:    _PluginService_OnInvoke_Handler,
+		},
+		{
+			MethodName: "OnConfigChange",
+			Handler:    _PluginService_OnConfigChange_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/plugin.proto",
@@ -294,6 +414,9 @@ const (
 	BotService_UploadGroupFile_FullMethodName   = "/plugin.BotService/UploadGroupFile"
 	BotService_UploadPrivateFile_FullMethodName = "/plugin.BotService/UploadPrivateFile"
 	BotService_CallAPI_FullMethodName           = "/plugin.BotService/CallAPI"
+	BotService_Publish_FullMethodName           = "/plugin.BotService/Publish"
+	BotService_InvokePlugin_FullMethodName      = "/plugin.BotService/InvokePlugin"
+	BotService_GetConfig_FullMethodName         = "/plugin.BotService/GetConfig"
 )
 
 // BotServiceClient is the client API for BotService service.
@@ -316,6 +439,12 @@ type BotServiceClient interface {
 	UploadPrivateFile(ctx context.Context, in *UploadPrivateFileRequest, opts ...grpc.CallOption) (*UploadFileResponse, error)
 	// Call NapCat API directly (for advanced use cases)
 	CallAPI(ctx context.Context, in *CallAPIRequest, opts ...grpc.CallOption) (*CallAPIResponse, error)
+	// Publish an event to other plugins
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Call a method exposed by another plugin
+	InvokePlugin(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error)
+	// Get the calling plugin's configuration
+	GetConfig(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PluginConfig, error)
 }
 
 type botServiceClient struct {
@@ -396,6 +525,36 @@ func (c *botServiceClient) CallAPI(ctx context.Context, in *CallAPIRequest, opts
 	return out, nil
 }
 
+func (c *botServiceClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, BotService_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *botServiceClient) InvokePlugin(ctx context.Context, in *InvokeRequest, opts ...grpc.CallOption) (*InvokeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InvokeResponse)
+	err := c.cc.Invoke(ctx, BotService_InvokePlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *botServiceClient) GetConfig(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PluginConfig, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PluginConfig)
+	err := c.cc.Invoke(ctx, BotService_GetConfig_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BotServiceServer is the server API for BotService service.
 // All implementations must embed UnimplementedBotServiceServer
 // for forward compatibility.
@@ -416,6 +575,12 @@ type BotServiceServer interface {
 	UploadPrivateFile(context.Context, *UploadPrivateFileRequest) (*UploadFileResponse, error)
 	// Call NapCat API directly (for advanced use cases)
 	CallAPI(context.Context, *CallAPIRequest) (*CallAPIResponse, error)
+	// Publish an event to other plugins
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Call a method exposed by another plugin
+	InvokePlugin(context.Context, *InvokeRequest) (*InvokeResponse, error)
+	// Get the calling plugin's configuration
+	GetConfig(context.Context, *Empty) (*PluginConfig, error)
 	mustEmbedUnimplementedBotServiceServer()
 }
 
@@ -447,6 +612,15 @@ func (UnimplementedBotServiceServer) UploadPrivateFile(context.Context, *UploadP
 func (UnimplementedBotServiceServer) CallAPI(context.Context, *CallAPIRequest) (*CallAPIResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method CallAPI not implemented")
 }
+func (UnimplementedBotServiceServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedBotServiceServer) InvokePlugin(context.Context, *InvokeRequest) (*InvokeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method InvokePlugin not implemented")
+}
+func (UnimplementedBotServiceServer) GetConfig(context.Context, *Empty) (*PluginConfig, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetConfig not implemented")
+}
 func (UnimplementedBotServiceServer) mustEmbedUnimplementedBotServiceServer() {}
 func (UnimplementedBotServiceServer) testEmbeddedByValue()                    {}
 
@@ -594,6 +768,60 @@ func _BotService_CallAPI_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BotService_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BotServiceServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BotService_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BotServiceServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BotService_InvokePlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BotServiceServer).InvokePlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BotService_InvokePlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BotServiceServer).InvokePlugin(ctx, req.(*InvokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BotService_GetConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BotServiceServer).GetConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BotService_GetConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BotServiceServer).GetConfig(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BotService_ServiceDesc is the grpc.ServiceDesc for BotService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -629,7 +857,127 @@ var BotService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CallAPI",
 			Handler:    _BotService_CallAPI_Handler,
 		},
+		{
+			MethodName: "Publish",
+			Handler:    _BotService_Publish_Handler,
+		},
+		{
+			MethodName: "InvokePlugin",
+			Handler:    _BotService_InvokePlugin_Handler,
+		},
+		{
+			MethodName: "GetConfig",
+			Handler:    _BotService_GetConfig_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/plugin.proto",
 }
+
+const (
+	PluginHost_Connect_FullMethodName = "/plugin.PluginHost/Connect"
+)
+
+// PluginHostClient is the client API for PluginHost service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Plugin host service - core platform implements this (protocol v2).
+// A v2 plugin opens a single stream to the core instead of listening on a
+// port. Events, acks, flow-control credits and BotService actions are all
+// multiplexed over it.
+type PluginHostClient interface {
+	// Plugin sends Hello first, core answers with Welcome
+	Connect(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PluginFrame, CoreFrame], error)
+}
+
+type pluginHostClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPluginHostClient(cc grpc.ClientConnInterface) PluginHostClient {
+	return &pluginHostClient{cc}
+}
+
+func (c *pluginHostClient) Connect(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[PluginFrame, CoreFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PluginHost_ServiceDesc.Streams[0], PluginHost_Connect_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PluginFrame, CoreFrame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginHost_ConnectClient = grpc.BidiStreamingClient[PluginFrame, CoreFrame]
+
+// PluginHostServer is the server API for PluginHost service.
+// All implementations must embed UnimplementedPluginHostServer
+// for forward compatibility.
+//
+// Plugin host service - core platform implements this (protocol v2).
+// A v2 plugin opens a single stream to the core instead of listening on a
+// port. Events, acks, flow-control credits and BotService actions are all
+// multiplexed over it.
+type PluginHostServer interface {
+	// Plugin sends Hello first, core answers with Welcome
+	Connect(grpc.BidiStreamingServer[PluginFrame, CoreFrame]) error
+	mustEmbedUnimplementedPluginHostServer()
+}
+
+// UnimplementedPluginHostServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPluginHostServer struct{}
+
+func (UnimplementedPluginHostServer) Connect(grpc.BidiStreamingServer[PluginFrame, CoreFrame]) error {
+	return status.Error(codes.Unimplemented, "method Connect not implemented")
+}
+func (UnimplementedPluginHostServer) mustEmbedUnimplementedPluginHostServer() {}
+func (UnimplementedPluginHostServer) testEmbeddedByValue()                    {}
+
+// UnsafePluginHostServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PluginHostServer will
+// result in compilation errors.
+type UnsafePluginHostServer interface {
+	mustEmbedUnimplementedPluginHostServer()
+}
+
+func RegisterPluginHostServer(s grpc.ServiceRegistrar, srv PluginHostServer) {
+	// If the following call panics, it indicates UnimplementedPluginHostServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PluginHost_ServiceDesc, srv)
+}
+
+func _PluginHost_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PluginHostServer).Connect(&grpc.GenericServerStream[PluginFrame, CoreFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PluginHost_ConnectServer = grpc.BidiStreamingServer[PluginFrame, CoreFrame]
+
+// PluginHost_ServiceDesc is the grpc.ServiceDesc for PluginHost service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PluginHost_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "plugin.PluginHost",
+	HandlerType: (*PluginHostServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       _PluginHost_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/proto/plugin.proto",
+}
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 )
 
 const defaultAddr = "http://127.0.0.1:8080"
@@ -29,24 +33,27 @@ func main() {
 		listPlugins(addr)
 	case "install", "i":
 		if len(os.Args) < 3 {
-			fmt.Println("Usage: botctl install [--start] <github_repo_url>")
+			fmt.Println("Usage: botctl install [--start] [--unverified] <source>")
 			os.Exit(1)
 		}
-		// Parse --start flag
+		// Parse --start and --unverified flags
 		autoStart := false
+		allowUnverified := false
 		repoURL := ""
 		for _, arg := range os.Args[2:] {
 			if arg == "--start" || arg == "-s" {
 				autoStart = true
+			} else if arg == "--unverified" {
+				allowUnverified = true
 			} else if !strings.HasPrefix(arg, "-") {
 				repoURL = arg
 			}
 		}
 		if repoURL == "" {
-			fmt.Println("Usage: botctl install [--start] <github_repo_url>")
+			fmt.Println("Usage: botctl install [--start] [--unverified] <source>")
 			os.Exit(1)
 		}
-		installPlugin(addr, repoURL, autoStart)
+		installPlugin(addr, repoURL, autoStart, allowUnverified)
 	case "start":
 		if len(os.Args) < 3 {
 			fmt.Println("Usage: botctl start <plugin_name>")
@@ -65,6 +72,136 @@ func main() {
 			os.Exit(1)
 		}
 		uninstallPlugin(addr, os.Args[2])
+	case "pin":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: botctl pin <command> <plugin_name>")
+			os.Exit(1)
+		}
+		pinCommand(addr, os.Args[2], os.Args[3])
+	case "unpin":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: botctl unpin <command>")
+			os.Exit(1)
+		}
+		unpinCommand(addr, os.Args[2])
+	case "approve":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: botctl approve <plugin_name>")
+			os.Exit(1)
+		}
+		approvePlugin(addr, os.Args[2])
+	case "upgrade":
+		allowUnverified := false
+		args := make([]string, 0)
+		for _, arg := range os.Args[2:] {
+			if arg == "--unverified" {
+				allowUnverified = true
+			} else {
+				args = append(args, arg)
+			}
+		}
+		if len(args) == 0 {
+			fmt.Println("Usage: botctl upgrade [--unverified] <plugin_name> [version]")
+			os.Exit(1)
+		}
+		version := ""
+		if len(args) > 1 {
+			version = args[1]
+		}
+		upgradePlugin(addr, args[0], version, allowUnverified)
+	case "rollback":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: botctl rollback <plugin_name>")
+			os.Exit(1)
+		}
+		rollbackPlugin(addr, os.Args[2])
+	case "hold", "unhold":
+		if len(os.Args) < 3 {
+			fmt.Printf("Usage: botctl %s <plugin_name>\n", os.Args[1])
+			os.Exit(1)
+		}
+		holdPlugin(addr, os.Args[2], os.Args[1] == "hold")
+	case "autoupdate":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: botctl autoupdate <plugin_name> <off|patch|minor|any>")
+			os.Exit(1)
+		}
+		setAutoUpdate(addr, os.Args[2], os.Args[3])
+	case "updates":
+		checkUpdates(addr)
+	case "replicas":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: botctl replicas <plugin_name> <count> [none|group|user]")
+			os.Exit(1)
+		}
+		count, err := strconv.Atoi(os.Args[3])
+		if err != nil {
+			fmt.Printf("Invalid replica count: %s\n", os.Args[3])
+			os.Exit(1)
+		}
+		sticky := ""
+		if len(os.Args) > 4 {
+			sticky = os.Args[4]
+		}
+		setReplicas(addr, os.Args[2], count, sticky)
+	case "config":
+		args := os.Args[2:]
+		switch {
+		case len(args) == 1:
+			showConfig(addr, args[0])
+		case len(args) >= 4 && args[1] == "set":
+			value := strings.Join(args[3:], " ")
+			updateConfig(addr, args[0], map[string]*string{args[2]: &value})
+		case len(args) == 3 && args[1] == "unset":
+			updateConfig(addr, args[0], map[string]*string{args[2]: nil})
+		default:
+			fmt.Println("Usage: botctl config <plugin_name> [set <key> <value> | unset <key>]")
+			os.Exit(1)
+		}
+	case "versions":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: botctl versions <plugin_name>")
+			os.Exit(1)
+		}
+		listVersions(addr, os.Args[2])
+	case "search":
+		refresh := false
+		terms := make([]string, 0)
+		for _, arg := range os.Args[2:] {
+			if arg == "--refresh" {
+				refresh = true
+			} else {
+				terms = append(terms, arg)
+			}
+		}
+		searchCatalog(addr, strings.Join(terms, " "), refresh)
+	case "logs":
+		// Parse -f and -n flags
+		follow := false
+		lines := 100
+		name := ""
+		args := os.Args[2:]
+		for i := 0; i < len(args); i++ {
+			switch arg := args[i]; {
+			case arg == "-f" || arg == "--follow":
+				follow = true
+			case (arg == "-n" || arg == "--lines") && i+1 < len(args):
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil {
+					fmt.Println("Usage: botctl logs [-f] [-n lines] <plugin_name>")
+					os.Exit(1)
+				}
+				lines = n
+				i++
+			case !strings.HasPrefix(arg, "-"):
+				name = arg
+			}
+		}
+		if name == "" {
+			fmt.Println("Usage: botctl logs [-f] [-n lines] <plugin_name>")
+			os.Exit(1)
+		}
+		showLogs(addr, name, lines, follow)
 	case "health":
 		checkHealth(addr)
 	case "help", "-h", "--help":
@@ -84,11 +221,37 @@ Usage:
 
 Commands:
   list, ls                      List all installed plugins
-  install, i [--start] <url>    Install plugin from GitHub repo URL
+  search [--refresh] [keyword]  Search the plugin catalogs
+  install, i [--start] <src>    Install plugin by catalog name, from a repo
+                                URL, owner/repo, URL of a binary or
+                                .tar.gz/.zip, or local path; add @tag for a
+                                specific release
                                 --start, -s: Auto-start after install
+                                --unverified: Accept a download without a
+                                checksum or signature
+  upgrade <name> [version]      Upgrade to the latest or a given release, going
+                                back if the new version fails its health check
+  rollback <name>               Return to the version before the last upgrade
+  hold <name>                   Keep a plugin at its current version
+  unhold <name>                 Allow a held plugin to be upgraded again
+  versions <name>               Show installed and available versions
+  updates                       Check all plugins for new releases now
+  autoupdate <name> <policy>    Upgrade a plugin on its own within off, patch,
+                                minor or any releases
+  replicas <name> <n> [sticky]  Run n replicas of a plugin and spread calls
+                                over them; sticky group or user keeps each
+                                group or user on one replica
+  config <name>                 Show a plugin's settings
+  config <name> set <key> <val> Change a setting; running plugins apply it live
+  config <name> unset <key>     Reset a setting to its default
   start <name>                  Start a plugin
   stop <name>                   Stop a running plugin
   uninstall, rm <name>          Uninstall a plugin
+  pin <command> <name>          Route a conflicting command to a plugin
+  unpin <command>               Remove a command pin
+  approve <name>                Grant a plugin the capabilities it requests
+  logs [-f] [-n lines] <name>   Show a plugin's output
+                                -f, --follow: Keep streaming new lines
   health                        Check platform health
   help                          Show this help
 
@@ -97,10 +260,20 @@ Environment:
 
 Examples:
   botctl list
+  botctl search weather
+  botctl install showmejm
   botctl install https://github.com/user/plugin-weather
   botctl install --start DaikonSushi/plugin-echo
+  botctl install ./build/weather_linux_amd64.tar.gz
+  botctl install user/plugin-weather@v1.2.0
+  botctl upgrade weather
+  botctl rollback weather
+  botctl autoupdate weather patch
+  botctl replicas weather 3 group
+  botctl config weather set units metric
   botctl start weather
   botctl stop weather
+  botctl logs -f weather
   botctl uninstall weather`)
 }
 
@@ -122,6 +295,20 @@ func listPlugins(addr string) {
 			Author      string   `json:"author"`
 			Commands    []string `json:"commands"`
 			Status      string   `json:"status"`
+			Desired     string   `json:"desired"`
+			Drift       bool     `json:"drift"`
+			Held        bool     `json:"held"`
+			Verified    string   `json:"verified"`
+			Approval    string   `json:"approval"`
+			Violations  []string `json:"violations"`
+			Replicas    int      `json:"replicas"`
+			Instances   []struct {
+				Status string `json:"status"`
+			} `json:"replica_status"`
+			Update *struct {
+				Latest    string `json:"latest"`
+				Available bool   `json:"available"`
+			} `json:"update"`
 		} `json:"data"`
 	}
 
@@ -141,8 +328,8 @@ func listPlugins(addr string) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tVERSION\tSTATUS\tCOMMANDS\tDESCRIPTION")
-	fmt.Fprintln(w, "----\t-------\t------\t--------\t-----------")
+	fmt.Fprintln(w, "NAME\tVERSION\tSTATUS\tDESIRED\tCOMMANDS\tDESCRIPTION")
+	fmt.Fprintln(w, "----\t-------\t------\t-------\t--------\t-----------")
 	for _, p := range result.Data {
 		cmds := ""
 		for i, c := range p.Commands {
@@ -151,15 +338,57 @@ func listPlugins(addr string) {
 			}
 			cmds += "/" + c
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Version, p.Status, cmds, p.Description)
+		status := p.Status
+		if p.Approval == "pending" {
+			status += " (unapproved)"
+		}
+		if p.Verified == "none" {
+			status += " (unverified)"
+		}
+		if len(p.Violations) > 0 {
+			status += " (limits hit)"
+		}
+		if p.Replicas > 1 && p.Status == "running" {
+			running := 0
+			for _, r := range p.Instances {
+				if r.Status == "running" {
+					running++
+				}
+			}
+			status += fmt.Sprintf(" (%d/%d replicas)", running, p.Replicas)
+		}
+		desired := p.Desired
+		if desired == "" {
+			desired = "-"
+		}
+		if p.Drift {
+			desired += " (drift)"
+		}
+		version := p.Version
+		if p.Held {
+			version += " (held)"
+		}
+		if p.Update != nil && p.Update.Available {
+			version += " (" + p.Update.Latest + " available)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, version, status, desired, cmds, p.Description)
 	}
 	w.Flush()
 }
 
-func installPlugin(addr, repoURL string, autoStart bool) {
-	fmt.Printf("Installing plugin from %s...\n", repoURL)
+func installPlugin(addr, source string, autoStart, allowUnverified bool) {
+	// Paths are resolved on the core's host; make relative ones absolute
+	// for the usual case of botctl running next to the core
+	if !strings.Contains(source, "://") {
+		if abs, err := filepath.Abs(source); err == nil {
+			if _, err := os.Stat(abs); err == nil {
+				source = abs
+			}
+		}
+	}
+	fmt.Printf("Installing plugin from %s...\n", source)
 
-	body, _ := json.Marshal(map[string]interface{}{"repo_url": repoURL, "auto_start": autoStart})
+	body, _ := json.Marshal(map[string]interface{}{"source": source, "auto_start": autoStart, "allow_unverified": allowUnverified})
 	resp, err := http.Post(addr+"/api/plugins/install", "application/json", bytes.NewReader(body))
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -212,6 +441,457 @@ func uninstallPlugin(addr, name string) {
 	printResult(resp.Body)
 }
 
+func pinCommand(addr, command, name string) {
+	body, _ := json.Marshal(map[string]string{"command": command, "plugin": name})
+	resp, err := http.Post(addr+"/api/commands/pin", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func unpinCommand(addr, command string) {
+	body, _ := json.Marshal(map[string]string{"command": command})
+	resp, err := http.Post(addr+"/api/commands/unpin", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func approvePlugin(addr, name string) {
+	body, _ := json.Marshal(map[string]string{"name": name})
+	resp, err := http.Post(addr+"/api/plugins/approve", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func upgradePlugin(addr, name, version string, allowUnverified bool) {
+	if version != "" {
+		fmt.Printf("Upgrading plugin %s to %s...\n", name, version)
+	} else {
+		fmt.Printf("Upgrading plugin %s...\n", name)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"name": name, "version": version, "allow_unverified": allowUnverified})
+	resp, err := http.Post(addr+"/api/plugins/upgrade", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printVersionResult(resp.Body)
+}
+
+func rollbackPlugin(addr, name string) {
+	fmt.Printf("Rolling back plugin %s...\n", name)
+
+	body, _ := json.Marshal(map[string]string{"name": name})
+	resp, err := http.Post(addr+"/api/plugins/rollback", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printVersionResult(resp.Body)
+}
+
+func holdPlugin(addr, name string, held bool) {
+	endpoint := "/api/plugins/hold"
+	if !held {
+		endpoint = "/api/plugins/unhold"
+	}
+
+	body, _ := json.Marshal(map[string]string{"name": name})
+	resp, err := http.Post(addr+endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func setAutoUpdate(addr, name, policy string) {
+	body, _ := json.Marshal(map[string]string{"name": name, "policy": policy})
+	resp, err := http.Post(addr+"/api/plugins/autoupdate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func setReplicas(addr, name string, replicas int, sticky string) {
+	body, _ := json.Marshal(map[string]interface{}{"name": name, "replicas": replicas, "sticky": sticky})
+	resp, err := http.Post(addr+"/api/plugins/replicas", "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printResult(resp.Body)
+}
+
+func checkUpdates(addr string) {
+	resp, err := http.Post(addr+"/api/plugins/check-updates", "application/json", nil)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    []struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			Held       bool   `json:"held"`
+			AutoUpdate string `json:"auto_update"`
+			Update     *struct {
+				Latest    string `json:"latest"`
+				Available bool   `json:"available"`
+				Error     string `json:"error"`
+				Awaiting  string `json:"awaiting"`
+			} `json:"update"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Code != 0 {
+		fmt.Printf("❌ Error: %s\n", result.Message)
+		os.Exit(1)
+	}
+
+	if len(result.Data) == 0 {
+		fmt.Println("No plugins with releases to check.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tINSTALLED\tLATEST\tAUTO-UPDATE\tSTATUS")
+	for _, p := range result.Data {
+		latest, status := "-", "up to date"
+		if p.Update != nil {
+			if p.Update.Latest != "" {
+				latest = p.Update.Latest
+			}
+			switch {
+			case p.Update.Error != "":
+				status = "check failed: " + p.Update.Error
+			case p.Update.Awaiting != "":
+				status = "v" + p.Update.Awaiting + " awaiting approval"
+			case p.Update.Available && p.Held:
+				status = "update available (held)"
+			case p.Update.Available:
+				status = "update available"
+			}
+		}
+		policy := p.AutoUpdate
+		if policy == "" {
+			policy = "off"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Version, latest, policy, status)
+	}
+	w.Flush()
+}
+
+func showConfig(addr, name string) {
+	resp, err := http.Get(addr + "/api/plugins/" + url.PathEscape(name) + "/config")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printConfigResult(resp.Body, false)
+}
+
+func updateConfig(addr, name string, values map[string]*string) {
+	body, _ := json.Marshal(map[string]interface{}{"values": values})
+	req, _ := http.NewRequest(http.MethodPut, addr+"/api/plugins/"+url.PathEscape(name)+"/config", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	printConfigResult(resp.Body, true)
+}
+
+func printConfigResult(body io.Reader, changed bool) {
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Settings []struct {
+				Key         string   `json:"key"`
+				Type        string   `json:"type"`
+				Description string   `json:"description"`
+				Required    bool     `json:"required"`
+				Options     []string `json:"options"`
+				Value       string   `json:"value"`
+				Set         bool     `json:"set"`
+			} `json:"settings"`
+			Missing    []string `json:"missing"`
+			Applied    bool     `json:"applied"`
+			ApplyError string   `json:"apply_error"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Code != 0 {
+		fmt.Printf("❌ Error: %s\n", result.Message)
+		os.Exit(1)
+	}
+
+	if changed {
+		switch {
+		case result.Data.Applied:
+			fmt.Println("✅ Config updated and applied")
+		case result.Data.ApplyError != "":
+			fmt.Printf("⚠️  Config saved, but the plugin did not apply it: %s\n", result.Data.ApplyError)
+		default:
+			fmt.Println("✅ Config saved")
+		}
+	}
+
+	if len(result.Data.Settings) == 0 {
+		fmt.Println("This plugin has no settings.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tVALUE\tDESCRIPTION")
+	for _, s := range result.Data.Settings {
+		typ := s.Type
+		if typ == "" {
+			typ = "string"
+		}
+		if len(s.Options) > 0 {
+			typ += " (" + strings.Join(s.Options, "|") + ")"
+		}
+		value := s.Value
+		switch {
+		case value == "" && s.Required:
+			value = "(required)"
+		case value == "":
+			value = "-"
+		case !s.Set:
+			value += " (default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Key, typ, value, s.Description)
+	}
+	w.Flush()
+
+	if len(result.Data.Missing) > 0 {
+		fmt.Printf("⚠️  Required before the plugin can start: %s\n", strings.Join(result.Data.Missing, ", "))
+	}
+}
+
+func listVersions(addr, name string) {
+	resp, err := http.Get(addr + "/api/plugins/" + url.PathEscape(name) + "/versions")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Current        string   `json:"current"`
+			Previous       string   `json:"previous"`
+			Held           bool     `json:"held"`
+			Installed      []string `json:"installed"`
+			Available      []string `json:"available"`
+			AvailableError string   `json:"available_error"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Code != 0 {
+		fmt.Printf("❌ Error: %s\n", result.Message)
+		os.Exit(1)
+	}
+
+	fmt.Println("Installed:")
+	for _, v := range result.Data.Installed {
+		switch {
+		case v == result.Data.Current && result.Data.Held:
+			fmt.Printf(" * %s (current, held)\n", v)
+		case v == result.Data.Current:
+			fmt.Printf(" * %s (current)\n", v)
+		case v == result.Data.Previous:
+			fmt.Printf("   %s (previous)\n", v)
+		default:
+			fmt.Printf("   %s\n", v)
+		}
+	}
+	if len(result.Data.Available) > 0 {
+		fmt.Println("Available:")
+		for _, v := range result.Data.Available {
+			fmt.Printf("   %s\n", v)
+		}
+	} else if result.Data.AvailableError != "" {
+		fmt.Printf("Could not list releases: %s\n", result.Data.AvailableError)
+	}
+}
+
+func searchCatalog(addr, query string, refresh bool) {
+	params := url.Values{}
+	params.Set("q", query)
+	if refresh {
+		params.Set("refresh", "true")
+	}
+	resp, err := http.Get(addr + "/api/catalog?" + params.Encode())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    []struct {
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Installed   string `json:"installed"`
+			Versions    []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Code != 0 {
+		fmt.Printf("❌ Error: %s\n", result.Message)
+		os.Exit(1)
+	}
+
+	if len(result.Data) == 0 {
+		fmt.Println("No plugins found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLATEST\tINSTALLED\tDESCRIPTION")
+	for _, p := range result.Data {
+		latest, installed := "-", "-"
+		if len(p.Versions) > 0 {
+			latest = p.Versions[0].Version
+		}
+		if p.Installed != "" {
+			installed = p.Installed
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, latest, installed, p.Description)
+	}
+	w.Flush()
+}
+
+// logEntry is one captured line of plugin output
+type logEntry struct {
+	Time   time.Time `json:"time"`
+	Stream string    `json:"stream"`
+	Level  string    `json:"level"`
+	Line   string    `json:"line"`
+}
+
+func (e logEntry) String() string {
+	tag := e.Stream
+	if e.Level != "" {
+		tag = e.Level
+	}
+	return fmt.Sprintf("%s [%s] %s", e.Time.Local().Format("2006-01-02 15:04:05"), tag, e.Line)
+}
+
+func showLogs(addr, name string, lines int, follow bool) {
+	query := url.Values{}
+	query.Set("lines", strconv.Itoa(lines))
+	if follow {
+		query.Set("follow", "true")
+	}
+
+	resp, err := http.Get(addr + "/api/plugins/" + url.PathEscape(name) + "/logs?" + query.Encode())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !follow {
+		var result struct {
+			Code    int        `json:"code"`
+			Message string     `json:"message"`
+			Data    []logEntry `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			fmt.Printf("Error parsing response: %v\n", err)
+			os.Exit(1)
+		}
+		if result.Code != 0 {
+			fmt.Printf("❌ Error: %s\n", result.Message)
+			os.Exit(1)
+		}
+		for _, entry := range result.Data {
+			fmt.Println(entry)
+		}
+		return
+	}
+
+	// Follow mode streams one JSON entry per line until interrupted
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var entry logEntry
+		if err := dec.Decode(&entry); err != nil {
+			if err != io.EOF {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		fmt.Println(entry)
+	}
+}
+
 func checkHealth(addr string) {
 	resp, err := http.Get(addr + "/api/health")
 	if err != nil {
@@ -262,9 +942,13 @@ func printInstallResult(body io.Reader, autoStart bool) {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
 		Data    struct {
-			Name    string `json:"name"`
-			Version string `json:"version"`
-			Started bool   `json:"started"`
+			Name     string `json:"name"`
+			Version  string `json:"version"`
+			Started  bool   `json:"started"`
+			Verified string `json:"verified"`
+
+			Capabilities json.RawMessage `json:"capabilities"`
+			Approval     string          `json:"approval"`
 		} `json:"data"`
 	}
 
@@ -281,6 +965,15 @@ func printInstallResult(body io.Reader, autoStart bool) {
 	fmt.Printf("✅ %s\n", result.Message)
 	fmt.Printf("   Name: %s\n", result.Data.Name)
 	fmt.Printf("   Version: %s\n", result.Data.Version)
+	if result.Data.Verified != "" {
+		fmt.Printf("   Verified: %s\n", result.Data.Verified)
+	}
+	if len(result.Data.Capabilities) > 0 {
+		fmt.Printf("   Capabilities: %s\n", result.Data.Capabilities)
+	}
+	if result.Data.Approval == "pending" {
+		fmt.Printf("   ⚠️  Capabilities need approval: botctl approve %s\n", result.Data.Name)
+	}
 	if autoStart {
 		if result.Data.Started {
 			fmt.Printf("   Status: 🟢 started\n")
@@ -289,3 +982,38 @@ func printInstallResult(body io.Reader, autoStart bool) {
 		}
 	}
 }
+
+func printVersionResult(body io.Reader) {
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Name     string `json:"name"`
+			Version  string `json:"version"`
+			Previous string `json:"previous_version"`
+			Approval string `json:"approval"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Code != 0 {
+		fmt.Printf("❌ Error: %s\n", result.Message)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ %s\n", result.Message)
+	if result.Data.Name == "" {
+		return
+	}
+	fmt.Printf("   Version: %s\n", result.Data.Version)
+	if result.Data.Previous != "" {
+		fmt.Printf("   Previous: %s (botctl rollback %s)\n", result.Data.Previous, result.Data.Name)
+	}
+	if result.Data.Approval == "pending" {
+		fmt.Printf("   ⚠️  New capabilities need approval: botctl approve %s, then restart it\n", result.Data.Name)
+	}
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/DaikonSushi/bot-platform/internal/bot"
 	"github.com/DaikonSushi/bot-platform/internal/botservice"
 	"github.com/DaikonSushi/bot-platform/internal/config"
+	"github.com/DaikonSushi/bot-platform/internal/eventbus"
 	"github.com/DaikonSushi/bot-platform/internal/pluginmgr"
 	"github.com/DaikonSushi/bot-platform/internal/server"
 	"github.com/DaikonSushi/bot-platform/plugins/echo"
@@ -24,6 +27,9 @@ import (
 )
 
 func main() {
+	// The core binary doubles as the exec wrapper that applies plugin limits
+	pluginmgr.RunExecWrapper()
+
 	// Parse flags
 	configPath := flag.String("config", "config.yaml", "Path to config file")
 	flag.Parse()
@@ -39,23 +45,14 @@ func main() {
 	// Create bot instance
 	b := bot.New(cfg)
 
-	// Start BotService gRPC server for external plugins to call back
+	// Event bus shared by built-in and external plugins
+	bus := eventbus.New(filepath.Join(cfg.PluginManager.DataDir, "events"))
+	b.SetEventBus(bus)
+
+	// BotService for external plugins to call back
 	grpcPort := cfg.PluginManager.GRPCPort
 	botSvc := botservice.NewService(b)
-	grpcServer := grpc.NewServer()
-	pb.RegisterBotServiceServer(grpcServer, botSvc)
-
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
-	if err != nil {
-		log.Fatalf("Failed to listen on gRPC port %d: %v", grpcPort, err)
-	}
-
-	go func() {
-		log.Printf("[Main] BotService gRPC server starting on port %d", grpcPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Printf("[Main] gRPC server error: %v", err)
-		}
-	}()
+	botSvc.SetEventBus(bus)
 
 	// Initialize external plugin manager if enabled
 	var extPluginMgr *pluginmgr.PluginManager
@@ -69,20 +66,128 @@ func main() {
 			cfg.PluginManager.ConfigDir,
 			grpcPort,
 		)
+		extPluginMgr.SetDispatchPolicy(cfg.PluginManager.DispatchMode, cfg.PluginManager.Priorities)
+		extPluginMgr.SetCommandPolicy(cfg.PluginManager.CommandConflict, cfg.PluginManager.CommandPins)
+		extPluginMgr.SetEventBus(bus)
+		extPluginMgr.SetRemoteAuth(cfg.PluginManager.RemoteToken, cfg.PluginManager.RemoteKeys)
+		extPluginMgr.SetBotService(botSvc)
+		botSvc.SetPluginInvoker(extPluginMgr)
+		botSvc.SetPluginLogger(extPluginMgr)
+		botSvc.SetPluginConfigs(extPluginMgr)
+		extPluginMgr.SetRestartPolicy(pluginmgr.RestartPolicy{
+			Policy:      cfg.PluginManager.RestartPolicy,
+			Overrides:   cfg.PluginManager.RestartPolicies,
+			Backoff:     time.Duration(cfg.PluginManager.RestartBackoff) * time.Second,
+			MaxBackoff:  time.Duration(cfg.PluginManager.RestartMaxDelay) * time.Second,
+			MaxRestarts: cfg.PluginManager.MaxRestarts,
+			Window:      time.Duration(cfg.PluginManager.RestartWindow) * time.Second,
+		})
+		if err := extPluginMgr.SetTransport(cfg.PluginManager.Transport, cfg.PluginManager.RuntimeDir); err != nil {
+			log.Fatalf("Failed to set up plugin transport: %v", err)
+		}
+		if err := extPluginMgr.SetLogging(
+			cfg.PluginManager.LogDir,
+			cfg.PluginManager.LogBufferLines,
+			int64(cfg.PluginManager.LogMaxSizeMB)<<20,
+			cfg.PluginManager.LogMaxFiles,
+		); err != nil {
+			log.Fatalf("Failed to set up plugin logs: %v", err)
+		}
+		overrides := make(map[string]pluginmgr.Limits)
+		for name, l := range cfg.PluginManager.PluginLimits {
+			overrides[name] = pluginLimits(l)
+		}
+		if err := extPluginMgr.SetIsolation(pluginmgr.Isolation{
+			DataDir:        cfg.PluginManager.PluginDataDir,
+			Limits:         pluginLimits(cfg.PluginManager.Limits),
+			Overrides:      overrides,
+			EnvPassthrough: cfg.PluginManager.EnvPassthrough,
+			CgroupParent:   cfg.PluginManager.CgroupParent,
+		}); err != nil {
+			log.Fatalf("Failed to set up plugin isolation: %v", err)
+		}
+		releaseAPIs := make(map[string]pluginmgr.ReleaseAPI)
+		for host, api := range cfg.PluginManager.ReleaseAPIs {
+			releaseAPIs[host] = pluginmgr.ReleaseAPI{BaseURL: api.API, Token: api.Token}
+		}
+		extPluginMgr.SetReleaseAPIs(releaseAPIs)
+		extPluginMgr.SetKeepVersions(cfg.PluginManager.KeepVersions)
+		if err := extPluginMgr.SetVerification(pluginmgr.Verification{
+			TrustedKeys:      cfg.PluginManager.TrustedKeys,
+			RequireSignature: cfg.PluginManager.RequireSignature,
+		}); err != nil {
+			log.Fatalf("Failed to set up plugin verification: %v", err)
+		}
+		extPluginMgr.SetCatalogs(cfg.PluginManager.Catalogs)
+	}
+
+	// Only plugins launched or registered by the manager may call BotService
+	serverOpts := make([]grpc.ServerOption, 0)
+	if extPluginMgr != nil {
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(extPluginMgr.AuthInterceptor()))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
+	pb.RegisterBotServiceServer(grpcServer, botSvc)
+
+	// v2 and remote plugins open a stream to the core instead of
+	// listening on a port
+	if extPluginMgr != nil {
+		pb.RegisterPluginHostServer(grpcServer, extPluginMgr.HostServer())
+	}
+
+	// Services must be registered before serving. In unix mode spawned
+	// plugins use a private socket and the TCP port is only opened for
+	// remote plugins.
+	unixMode := extPluginMgr != nil && extPluginMgr.Transport() == pluginmgr.TransportUnix
+	if unixMode {
+		socket := extPluginMgr.CoreSocket()
+		unixLis, err := pluginmgr.ListenUnix(socket)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", socket, err)
+		}
+		go func() {
+			log.Printf("[Main] BotService gRPC server starting on %s", socket)
+			if err := grpcServer.Serve(unixLis); err != nil {
+				log.Printf("[Main] gRPC server error: %v", err)
+			}
+		}()
+	}
+
+	// Without a plugin manager no plugin could authenticate, so BotService
+	// is not served at all
+	remote := cfg.PluginManager.RemoteToken != "" || len(cfg.PluginManager.RemoteKeys) > 0
+	if extPluginMgr != nil && (!unixMode || remote) {
+		addr := net.JoinHostPort(cfg.PluginManager.GRPCHost, strconv.Itoa(grpcPort))
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", addr, err)
+		}
+		go func() {
+			log.Printf("[Main] BotService gRPC server starting on %s", addr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("[Main] gRPC server error: %v", err)
+			}
+		}()
+	}
 
+	if extPluginMgr != nil {
 		// Load installed plugins
 		if err := extPluginMgr.LoadInstalledPlugins(); err != nil {
 			log.Printf("[Main] Warning: failed to load installed plugins: %v", err)
 		}
 
-		// Auto-start plugins
-		if len(cfg.PluginManager.AutoStart) > 0 {
-			extPluginMgr.AutoStartPlugins(context.Background(), cfg.PluginManager.AutoStart)
-		}
+		// Start what admins left running, and auto_start plugins they never stopped
+		extPluginMgr.ReconcilePlugins(context.Background(), cfg.PluginManager.AutoStart)
 
 		// Set external plugin manager to bot
 		b.SetExternalPluginManager(extPluginMgr)
 
+		extPluginMgr.SetUpdateCheck(pluginmgr.UpdateCheck{
+			Interval: time.Duration(cfg.PluginManager.UpdateCheckInterval) * time.Minute,
+			Admins:   cfg.Bot.Admins,
+			Notifier: b,
+		})
+
 		log.Println("[Main] External plugin manager initialized")
 	}
 
@@ -140,14 +245,28 @@ func main() {
 	// Graceful shutdown
 	log.Println("[Main] Shutting down...")
 
-	// Stop gRPC server
-	grpcServer.GracefulStop()
-
-	// Stop external plugins
+	// Stop external plugins first so their streams close before the
+	// gRPC server waits for open RPCs
 	if extPluginMgr != nil {
 		extPluginMgr.Shutdown()
 	}
 
+	// Stop gRPC server
+	grpcServer.GracefulStop()
+
+	bus.Close()
 	b.Stop()
 	log.Println("[Main] Goodbye!")
 }
+
+// pluginLimits converts configured limits for the plugin manager
+func pluginLimits(l config.PluginLimits) pluginmgr.Limits {
+	return pluginmgr.Limits{
+		MemoryMB:   l.MemoryMB,
+		CPUPercent: l.CPUPercent,
+		OpenFiles:  l.OpenFiles,
+		Processes:  l.Processes,
+		UID:        l.RunAsUID,
+		GID:        l.RunAsGID,
+	}
+}
@@ -22,6 +22,10 @@ func (p *EchoPlugin) Info() pluginsdk.PluginInfo {
 		Author:            "hovanzhang",
 		Commands:          []string{"echo", "say", "repeat"},
 		HandleAllMessages: false,
+		Capabilities: &pluginsdk.Capabilities{
+			RPCs: []string{"SendMessage"},
+		},
+		CoreVersion: ">=1.0.0, <2.0.0",
 	}
 }
 
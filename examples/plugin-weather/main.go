@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/DaikonSushi/bot-platform/pkg/pluginsdk"
@@ -24,6 +25,12 @@ func (p *WeatherPlugin) Info() pluginsdk.PluginInfo {
 		Author:            "YourName",
 		Commands:          []string{"weather", "天气"},
 		HandleAllMessages: false,
+		// Admins change these with /plugin config weather set <key> <value>
+		ConfigSchema: []pluginsdk.ConfigField{
+			{Key: "endpoint", Default: "https://wttr.in", Description: "Weather API base URL (wttr.in compatible)"},
+			{Key: "timeout", Type: pluginsdk.ConfigDuration, Default: "10s", Description: "How long to wait for the weather API"},
+			{Key: "lang", Default: "zh", Options: []string{"zh", "en"}, Description: "Language of the weather description"},
+		},
 	}
 }
 
@@ -48,8 +55,15 @@ func (p *WeatherPlugin) OnCommand(ctx context.Context, bot *pluginsdk.BotClient,
 		return true
 	}
 
+	// The SDK keeps the config current, changes apply to the next request
+	cfg, err := bot.Config()
+	if err != nil {
+		bot.Reply(msg, pluginsdk.Text(fmt.Sprintf("读取配置失败: %v", err)))
+		return true
+	}
+
 	city := strings.Join(args, " ")
-	weather, err := getWeather(city)
+	weather, err := getWeather(cfg, city)
 	if err != nil {
 		bot.Reply(msg, pluginsdk.Text(fmt.Sprintf("获取天气失败: %v", err)))
 		return true
@@ -59,12 +73,13 @@ func (p *WeatherPlugin) OnCommand(ctx context.Context, bot *pluginsdk.BotClient,
 	return true
 }
 
-// getWeather fetches weather info (using a free weather API as example)
-func getWeather(city string) (string, error) {
-	// Using wttr.in free weather API
-	url := fmt.Sprintf("https://wttr.in/%s?format=j1", city)
+// getWeather fetches weather info from the configured wttr.in compatible API
+func getWeather(cfg *pluginsdk.Config, city string) (string, error) {
+	endpoint := strings.TrimRight(cfg.String("endpoint"), "/")
+	api := fmt.Sprintf("%s/%s?format=j1&lang=%s", endpoint, url.PathEscape(city), cfg.String("lang"))
 
-	resp, err := http.Get(url)
+	client := &http.Client{Timeout: cfg.Duration("timeout")}
+	resp, err := client.Get(api)
 	if err != nil {
 		return "", err
 	}
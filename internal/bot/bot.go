@@ -17,6 +17,7 @@ import (
 
 	pb "github.com/DaikonSushi/bot-platform/api/proto"
 	"github.com/DaikonSushi/bot-platform/internal/config"
+	"github.com/DaikonSushi/bot-platform/internal/eventbus"
 	"github.com/DaikonSushi/bot-platform/internal/message"
 	"github.com/DaikonSushi/bot-platform/internal/plugin"
 	"github.com/DaikonSushi/bot-platform/internal/pluginmgr"
@@ -30,6 +31,7 @@ type Bot struct {
 	wsMu             sync.Mutex // Protects wsConn
 	pluginManager    *plugin.Manager
 	extPluginManager *pluginmgr.PluginManager
+	eventBus         *eventbus.Bus
 	running          bool
 	mu               sync.RWMutex
 	stopChan         chan struct{}
@@ -50,11 +52,42 @@ func New(cfg *config.Config) *Bot {
 // RegisterPlugin registers a plugin with the bot
 func (b *Bot) RegisterPlugin(p plugin.Plugin) {
 	b.pluginManager.Register(p)
+
+	// Subscribe event handlers to the bus
+	if h, ok := p.(plugin.EventHandler); ok && b.eventBus != nil {
+		b.eventBus.Attach(p.Name(), h.Topics(), eventbus.HandlerFunc(h.OnEvent))
+	}
+}
+
+// SetEventBus sets the plugin event bus. Must be called before RegisterPlugin.
+func (b *Bot) SetEventBus(bus *eventbus.Bus) {
+	b.eventBus = bus
+}
+
+// Publish publishes a JSON-encoded event on the plugin event bus
+func (b *Bot) Publish(source, topic string, payload interface{}, durable bool) error {
+	if b.eventBus == nil {
+		return fmt.Errorf("event bus is not enabled")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.eventBus.Publish(&pb.BusEvent{
+		Topic:   topic,
+		Source:  source,
+		Payload: &pb.BusEvent_Json{Json: data},
+		Durable: durable,
+	})
+	return err
 }
 
 // SetExternalPluginManager sets the external plugin manager
 func (b *Bot) SetExternalPluginManager(mgr *pluginmgr.PluginManager) {
 	b.extPluginManager = mgr
+	b.pluginManager.SetCommandResolver(mgr)
 }
 
 // Start starts the bot and connects to NapCat
@@ -264,16 +297,31 @@ func (b *Bot) dispatchToExternalPlugins(ctx *plugin.Context) {
 
 // convertToPbMessageEvent converts internal event to protobuf event
 func (b *Bot) convertToPbMessageEvent(event *message.Event) *pb.MessageEvent {
+	segments := make([]*pb.MessageSegment, 0, len(event.Message))
+	for _, seg := range event.Message {
+		data := make(map[string]string, len(seg.Data))
+		for k, v := range seg.Data {
+			data[k] = fmt.Sprint(v)
+		}
+		segments = append(segments, &pb.MessageSegment{
+			Type: seg.Type,
+			Data: data,
+		})
+	}
+
 	return &pb.MessageEvent{
 		MessageId:   fmt.Sprintf("%d", event.MessageID),
 		UserId:      event.UserID,
 		GroupId:     event.GroupID,
 		MessageType: string(event.MessageType),
 		RawMessage:  event.RawMessage,
+		Segments:    segments,
 		Timestamp:   event.Time,
 		Sender: &pb.UserInfo{
 			UserId:   event.Sender.UserID,
 			Nickname: event.Sender.Nickname,
+			Card:     event.Sender.Card,
+			Role:     event.Sender.Role,
 		},
 	}
 }
@@ -4,10 +4,17 @@ package botservice
 
 import (
 	"context"
+	"errors"
 	"log"
+	"time"
 
 	pb "github.com/DaikonSushi/bot-platform/api/proto"
+	"github.com/DaikonSushi/bot-platform/internal/eventbus"
 	"github.com/DaikonSushi/bot-platform/internal/message"
+	"github.com/DaikonSushi/bot-platform/internal/pluginmgr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // MessageSender is the interface for sending messages
@@ -26,10 +33,29 @@ type MessageSender interface {
 	CallNapCatAPI(action string, params map[string]interface{}) ([]byte, error)
 }
 
+// PluginInvoker routes calls between plugins
+type PluginInvoker interface {
+	Invoke(ctx context.Context, source, target, method string, payload []byte, timeout time.Duration) ([]byte, error)
+}
+
+// PluginLogger records lines plugins log through the SDK
+type PluginLogger interface {
+	AppendLog(name, level, line string)
+}
+
+// PluginConfigs looks up the configuration admins set for a plugin
+type PluginConfigs interface {
+	ConfigFor(name string) (*pb.PluginConfig, error)
+}
+
 // Service implements pb.BotServiceServer
 type Service struct {
 	pb.UnimplementedBotServiceServer
-	sender MessageSender
+	sender  MessageSender
+	bus     *eventbus.Bus
+	invoker PluginInvoker
+	logger  PluginLogger
+	configs PluginConfigs
 }
 
 // NewService creates a new BotService
@@ -39,10 +65,38 @@ func NewService(sender MessageSender) *Service {
 	}
 }
 
+// SetEventBus sets the event bus used by Publish
+func (s *Service) SetEventBus(bus *eventbus.Bus) {
+	s.bus = bus
+}
+
+// SetPluginInvoker sets the router used by InvokePlugin
+func (s *Service) SetPluginInvoker(invoker PluginInvoker) {
+	s.invoker = invoker
+}
+
+// SetPluginLogger sets where Log calls are captured per plugin
+func (s *Service) SetPluginLogger(logger PluginLogger) {
+	s.logger = logger
+}
+
+// SetPluginConfigs sets where GetConfig reads plugin configuration
+func (s *Service) SetPluginConfigs(configs PluginConfigs) {
+	s.configs = configs
+}
+
+// caller returns the authenticated plugin behind a call
+func caller(ctx context.Context) string {
+	if name, ok := pluginmgr.CallerFromContext(ctx); ok {
+		return name
+	}
+	return "unknown"
+}
+
 // SendMessage sends a message
 func (s *Service) SendMessage(ctx context.Context, req *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
-	log.Printf("[BotService] SendMessage: type=%s, userId=%d, groupId=%d, segments=%d",
-		req.MessageType, req.UserId, req.GroupId, len(req.Segments))
+	log.Printf("[BotService] SendMessage: plugin=%s, type=%s, userId=%d, groupId=%d, segments=%d",
+		caller(ctx), req.MessageType, req.UserId, req.GroupId, len(req.Segments))
 
 	// Convert protobuf segments to internal message
 	msg := message.NewMessage()
@@ -172,14 +226,18 @@ func (s *Service) GetGroupInfo(ctx context.Context, req *pb.GetGroupInfoRequest)
 
 // Log handles log requests from plugins
 func (s *Service) Log(ctx context.Context, req *pb.LogRequest) (*pb.Empty, error) {
-	log.Printf("[Plugin:Log:%s] %s", req.Level, req.Message)
+	name := caller(ctx)
+	log.Printf("[Plugin:%s:%s] %s", name, req.Level, req.Message)
+	if s.logger != nil {
+		s.logger.AppendLog(name, req.Level, req.Message)
+	}
 	return &pb.Empty{}, nil
 }
 
 // UploadGroupFile uploads a file to a group
 func (s *Service) UploadGroupFile(ctx context.Context, req *pb.UploadGroupFileRequest) (*pb.UploadFileResponse, error) {
-	log.Printf("[BotService] UploadGroupFile: groupId=%d, file=%s, name=%s, folder=%s",
-		req.GroupId, req.FilePath, req.FileName, req.Folder)
+	log.Printf("[BotService] UploadGroupFile: plugin=%s, groupId=%d, file=%s, name=%s, folder=%s",
+		caller(ctx), req.GroupId, req.FilePath, req.FileName, req.Folder)
 	
 	folder := req.Folder
 	if folder == "" {
@@ -201,8 +259,8 @@ func (s *Service) UploadGroupFile(ctx context.Context, req *pb.UploadGroupFileRe
 
 // UploadPrivateFile uploads a file to a private chat
 func (s *Service) UploadPrivateFile(ctx context.Context, req *pb.UploadPrivateFileRequest) (*pb.UploadFileResponse, error) {
-	log.Printf("[BotService] UploadPrivateFile: userId=%d, file=%s, name=%s",
-		req.UserId, req.FilePath, req.FileName)
+	log.Printf("[BotService] UploadPrivateFile: plugin=%s, userId=%d, file=%s, name=%s",
+		caller(ctx), req.UserId, req.FilePath, req.FileName)
 	
 	err := s.sender.UploadPrivateFile(req.UserId, req.FilePath, req.FileName)
 	if err != nil {
@@ -219,7 +277,7 @@ func (s *Service) UploadPrivateFile(ctx context.Context, req *pb.UploadPrivateFi
 
 // CallAPI calls a NapCat API directly
 func (s *Service) CallAPI(ctx context.Context, req *pb.CallAPIRequest) (*pb.CallAPIResponse, error) {
-	log.Printf("[BotService] CallAPI: action=%s, params=%v", req.Action, req.Params)
+	log.Printf("[BotService] CallAPI: plugin=%s, action=%s, params=%v", caller(ctx), req.Action, req.Params)
 	
 	// Convert string map to interface map
 	params := make(map[string]interface{})
@@ -240,3 +298,84 @@ func (s *Service) CallAPI(ctx context.Context, req *pb.CallAPIRequest) (*pb.Call
 		Data:    data,
 	}, nil
 }
+
+// Publish publishes an event on the plugin event bus
+func (s *Service) Publish(ctx context.Context, req *pb.PublishRequest) (*pb.PublishResponse, error) {
+	if s.bus == nil {
+		return &pb.PublishResponse{Error: "event bus is not enabled"}, nil
+	}
+
+	// The source is the authenticated caller, not whatever it claims
+	source := caller(ctx)
+	event := &pb.BusEvent{
+		Topic:   req.Topic,
+		Source:  source,
+		Durable: req.Durable,
+	}
+	switch payload := req.Payload.(type) {
+	case *pb.PublishRequest_Json:
+		event.Payload = &pb.BusEvent_Json{Json: payload.Json}
+	case *pb.PublishRequest_Any:
+		event.Payload = &pb.BusEvent_Any{Any: payload.Any}
+	}
+
+	n, err := s.bus.Publish(event)
+	if err != nil {
+		return &pb.PublishResponse{Error: err.Error()}, nil
+	}
+
+	log.Printf("[BotService] Publish: topic=%s, source=%s, durable=%v, subscribers=%d",
+		req.Topic, source, req.Durable, n)
+	return &pb.PublishResponse{
+		Id:          event.Id,
+		Subscribers: int32(n),
+	}, nil
+}
+
+// InvokePlugin calls a method exposed by another plugin
+func (s *Service) InvokePlugin(ctx context.Context, req *pb.InvokeRequest) (*pb.InvokeResponse, error) {
+	source := caller(ctx)
+	log.Printf("[BotService] InvokePlugin: %s -> %s.%s", source, req.Target, req.Method)
+
+	if s.invoker == nil {
+		return &pb.InvokeResponse{
+			Error: "external plugin manager is not enabled",
+			Code:  pluginmgr.InvokeUnavailable,
+		}, nil
+	}
+
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	payload, err := s.invoker.Invoke(ctx, source, req.Target, req.Method, req.Payload, timeout)
+	if err != nil {
+		code := pluginmgr.InvokeInternal
+		var invokeErr *pluginmgr.InvokeError
+		if errors.As(err, &invokeErr) {
+			code = invokeErr.Code
+		}
+		return &pb.InvokeResponse{
+			Error: err.Error(),
+			Code:  code,
+		}, nil
+	}
+
+	return &pb.InvokeResponse{
+		Payload: payload,
+	}, nil
+}
+
+// GetConfig returns the calling plugin's configuration
+func (s *Service) GetConfig(ctx context.Context, _ *pb.Empty) (*pb.PluginConfig, error) {
+	name, ok := pluginmgr.CallerFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unknown plugin")
+	}
+	if s.configs == nil {
+		return &pb.PluginConfig{Values: map[string]string{}}, nil
+	}
+
+	cfg, err := s.configs.ConfigFor(name)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return cfg, nil
+}
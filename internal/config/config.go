@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
@@ -35,8 +36,80 @@ type PluginManagerConfig struct {
 	Enabled   bool     `yaml:"enabled"`
 	PluginDir string   `yaml:"plugin_dir"`
 	ConfigDir string   `yaml:"config_dir"`
+	DataDir   string   `yaml:"data_dir"` // Runtime state such as durable event queues
 	GRPCPort  int      `yaml:"grpc_port"`
+	GRPCHost  string   `yaml:"grpc_host"` // Address grpc_port listens on, loopback by default
 	AutoStart []string `yaml:"auto_start"`
+	// Transport between the core and spawned plugins: "tcp" uses grpc_port
+	// and a loopback port per plugin, "unix" uses socket files in RuntimeDir
+	Transport  string `yaml:"transport"`
+	RuntimeDir string `yaml:"runtime_dir"` // Private directory for unix sockets
+	// DispatchMode controls non-command message delivery: "parallel" sends
+	// to all subscribed plugins at once, "ordered" calls them by priority
+	// and stops at the first one that reports the message as handled
+	DispatchMode string         `yaml:"dispatch_mode"`
+	Priorities   map[string]int `yaml:"priorities"` // Per-plugin priority overrides, higher runs first
+	// CommandConflict decides who owns a bare command declared by several
+	// running plugins: "reject", "first" or "priority"
+	CommandConflict string            `yaml:"command_conflict"`
+	CommandPins     map[string]string `yaml:"command_pins"` // Bare command -> plugin that always owns it
+	// Remote plugins are started outside the core and register themselves
+	// over gRPC. Registration is disabled unless a token or key is set.
+	RemoteToken string            `yaml:"remote_token"` // Pre-shared token accepted for any plugin
+	RemoteKeys  map[string]string `yaml:"remote_keys"`  // Plugin name -> key accepted for that plugin only
+	// Plugin stdout, stderr and SDK Log calls are captured per plugin into
+	// an in-memory ring and a rotating file in LogDir
+	LogDir         string `yaml:"log_dir"`          // Default: <data_dir>/logs
+	LogBufferLines int    `yaml:"log_buffer_lines"` // Lines kept in memory per plugin
+	LogMaxSizeMB   int    `yaml:"log_max_size_mb"`  // Rotate a plugin's log file at this size
+	LogMaxFiles    int    `yaml:"log_max_files"`    // Rotated files kept per plugin
+	// RestartPolicy decides what happens when a spawned plugin exits on its
+	// own: "always", "on-failure" or "never"
+	RestartPolicy   string            `yaml:"restart_policy"`
+	RestartPolicies map[string]string `yaml:"restart_policies"`    // Per-plugin policy overrides
+	RestartBackoff  int               `yaml:"restart_backoff"`     // Seconds before the first restart, doubled per restart
+	RestartMaxDelay int               `yaml:"restart_max_backoff"` // Upper bound of the restart delay in seconds
+	MaxRestarts     int               `yaml:"max_restarts"`        // Restarts within restart_window before crashloop
+	RestartWindow   int               `yaml:"restart_window"`      // Seconds
+	// Spawned plugins run in their own directory with a scrubbed
+	// environment and resource limits
+	PluginDataDir  string                  `yaml:"plugin_data_dir"` // Default: <data_dir>/plugins, one subdirectory per plugin
+	EnvPassthrough []string                `yaml:"env_passthrough"` // Extra variables plugins inherit besides PATH, LANG, LC_ALL and TZ
+	Limits         PluginLimits            `yaml:"limits"`          // Applied to every plugin
+	PluginLimits   map[string]PluginLimits `yaml:"plugin_limits"`   // Per-plugin overrides
+	CgroupParent   string                  `yaml:"cgroup_parent"`   // Delegated cgroup v2 directory, empty uses rlimits only
+	// GitHub-compatible release APIs by repo host, for GitHub Enterprise or
+	// Gitea. github.com is built in; list it to add a token.
+	ReleaseAPIs map[string]ReleaseAPIConfig `yaml:"release_apis"`
+	// Installed versions are kept side by side under plugin_dir so that
+	// upgrades can be rolled back
+	KeepVersions int `yaml:"keep_versions"` // Versions kept per plugin, including the current and previous one
+	// Downloads are checked against the SHA-256 checksums and signatures
+	// published with them. Unverified downloads need an admin override.
+	TrustedKeys      []string `yaml:"trusted_keys"`      // minisign public keys or base64 ed25519 keys of publishers
+	RequireSignature bool     `yaml:"require_signature"` // Refuse downloads that are only checksummed
+	// Catalog index files (URLs or local paths) listing plugins by name,
+	// for /plugin search and /plugin install <name>
+	Catalogs []string `yaml:"catalogs"`
+	// Installed plugins are checked for new releases and bot admins are
+	// told about them; plugins opted in with /plugin autoupdate upgrade
+	UpdateCheckInterval int `yaml:"update_check_interval"` // Minutes between checks, 0 disables
+}
+
+// ReleaseAPIConfig holds a GitHub-compatible release API
+type ReleaseAPIConfig struct {
+	API   string `yaml:"api"`   // API base URL, e.g. https://git.example.com/api/v1
+	Token string `yaml:"token"` // Optional access token
+}
+
+// PluginLimits holds resource limits of plugin processes, 0 means unlimited
+type PluginLimits struct {
+	MemoryMB   int `yaml:"memory_mb"`
+	CPUPercent int `yaml:"cpu_percent"` // 100 is one full core, needs cgroups
+	OpenFiles  int `yaml:"open_files"`
+	Processes  int `yaml:"processes"`
+	RunAsUID   int `yaml:"run_as_uid"` // 0 keeps the core's user
+	RunAsGID   int `yaml:"run_as_gid"`
 }
 
 // AdminServerConfig holds admin HTTP API settings
@@ -81,9 +154,48 @@ func Load(path string) (*Config, error) {
 	if cfg.PluginManager.ConfigDir == "" {
 		cfg.PluginManager.ConfigDir = "./plugins-config"
 	}
+	if cfg.PluginManager.DataDir == "" {
+		cfg.PluginManager.DataDir = "./plugins-data"
+	}
 	if cfg.PluginManager.GRPCPort == 0 {
 		cfg.PluginManager.GRPCPort = 50051
 	}
+	if cfg.PluginManager.GRPCHost == "" {
+		cfg.PluginManager.GRPCHost = "127.0.0.1"
+	}
+	if cfg.PluginManager.Transport == "" {
+		cfg.PluginManager.Transport = "tcp"
+	}
+	if cfg.PluginManager.RuntimeDir == "" {
+		cfg.PluginManager.RuntimeDir = filepath.Join(cfg.PluginManager.DataDir, "run")
+	}
+	if cfg.PluginManager.LogDir == "" {
+		cfg.PluginManager.LogDir = filepath.Join(cfg.PluginManager.DataDir, "logs")
+	}
+	if cfg.PluginManager.LogBufferLines == 0 {
+		cfg.PluginManager.LogBufferLines = 500
+	}
+	if cfg.PluginManager.LogMaxSizeMB == 0 {
+		cfg.PluginManager.LogMaxSizeMB = 10
+	}
+	if cfg.PluginManager.LogMaxFiles == 0 {
+		cfg.PluginManager.LogMaxFiles = 3
+	}
+	if cfg.PluginManager.PluginDataDir == "" {
+		cfg.PluginManager.PluginDataDir = filepath.Join(cfg.PluginManager.DataDir, "plugins")
+	}
+	if cfg.PluginManager.KeepVersions == 0 {
+		cfg.PluginManager.KeepVersions = 3
+	}
+	if cfg.PluginManager.RestartPolicy == "" {
+		cfg.PluginManager.RestartPolicy = "on-failure"
+	}
+	if cfg.PluginManager.DispatchMode == "" {
+		cfg.PluginManager.DispatchMode = "parallel"
+	}
+	if cfg.PluginManager.CommandConflict == "" {
+		cfg.PluginManager.CommandConflict = "first"
+	}
 	if cfg.AdminServer.Addr == "" {
 		cfg.AdminServer.Addr = ":8080"
 	}
@@ -0,0 +1,466 @@
+// Package eventbus implements the publish/subscribe bus that lets plugins
+// talk to each other through the core.
+//
+// Non-durable events are delivered at most once to subscribers that are
+// online when the event is published. Durable events are written to a
+// per-subscriber queue on disk and redelivered until the subscriber
+// acknowledges them, including across subscriber and core restarts.
+//
+// A queue file is a journal with one record per line: "+" and the
+// base64 wire encoding of a queued event, or "-" and the ID of an event
+// that left the queue. Records are appended as events are published and
+// acknowledged, and the file is compacted once removed events outweigh
+// the ones still queued.
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Handler receives events for one subscriber. Returning nil acknowledges
+// the event; any error leaves a durable event queued for redelivery.
+type Handler interface {
+	Deliver(ctx context.Context, event *pb.BusEvent) error
+}
+
+// HandlerFunc adapts a function to Handler
+type HandlerFunc func(ctx context.Context, event *pb.BusEvent) error
+
+// Deliver calls f(ctx, event)
+func (f HandlerFunc) Deliver(ctx context.Context, event *pb.BusEvent) error {
+	return f(ctx, event)
+}
+
+// subscriber is a named participant. handler is nil while it is offline;
+// durable events keep queueing for it until it is removed.
+type subscriber struct {
+	name     string
+	topics   []string
+	handler  Handler
+	pending  []*pb.BusEvent
+	records  int // records in the queue file
+	flushing bool
+}
+
+// Bus routes events between subscribers
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[string]*subscriber
+	queueDir    string
+	stopRetry   chan struct{}
+}
+
+// deliveryTimeout bounds a single delivery attempt
+const deliveryTimeout = 10 * time.Second
+
+// retryInterval is how often queued durable events are retried
+const retryInterval = 10 * time.Second
+
+// maxPending caps each subscriber's durable queue; the oldest event is
+// dropped to make room for a new one
+const maxPending = 10000
+
+// compactMin is the number of removed events a queue file may hold before
+// it is compacted
+const compactMin = 64
+
+// New creates a bus that persists durable queues under queueDir
+func New(queueDir string) *Bus {
+	os.MkdirAll(queueDir, 0755)
+
+	b := &Bus{
+		subscribers: make(map[string]*subscriber),
+		queueDir:    queueDir,
+		stopRetry:   make(chan struct{}),
+	}
+	go b.retryLoop()
+	return b
+}
+
+// Close stops background redelivery
+func (b *Bus) Close() {
+	close(b.stopRetry)
+}
+
+// Register declares a subscriber's topics without attaching a handler, so
+// durable events published while it is offline are kept for it
+func (b *Bus) Register(name string, topics []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.registerLocked(name, topics)
+}
+
+// Attach registers a subscriber and brings it online. Queued durable
+// events are flushed to it in the background.
+func (b *Bus) Attach(name string, topics []string, h Handler) {
+	b.mu.Lock()
+	sub := b.registerLocked(name, topics)
+	sub.handler = h
+	b.mu.Unlock()
+
+	go b.flush(name)
+}
+
+// Detach takes a subscriber offline. Its durable queue is kept.
+func (b *Bus) Detach(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub, exists := b.subscribers[name]; exists {
+		sub.handler = nil
+	}
+}
+
+// Remove forgets a subscriber and deletes its durable queue
+func (b *Bus) Remove(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, name)
+	os.Remove(b.queuePath(name))
+}
+
+// Pending returns the number of queued durable events for a subscriber
+func (b *Bus) Pending(name string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if sub, exists := b.subscribers[name]; exists {
+		return len(sub.pending)
+	}
+	return 0
+}
+
+// Publish routes an event to every subscriber of its topic except the
+// source itself. It fills in ID and Timestamp and returns the number of
+// subscribers the event was routed to.
+func (b *Bus) Publish(event *pb.BusEvent) (int, error) {
+	if event.Topic == "" {
+		return 0, fmt.Errorf("topic is required")
+	}
+	if event.Id == "" {
+		event.Id = newEventID()
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+
+	b.mu.Lock()
+	online := make([]Handler, 0)
+	durable := make([]string, 0)
+	for _, sub := range b.subscribers {
+		if sub.name == event.Source || !matchAny(sub.topics, event.Topic) {
+			continue
+		}
+		if event.Durable {
+			b.enqueueLocked(sub, event)
+			durable = append(durable, sub.name)
+		} else if sub.handler != nil {
+			online = append(online, sub.handler)
+		}
+	}
+	b.mu.Unlock()
+
+	// At-most-once: fire and forget
+	for _, h := range online {
+		go func(h Handler) {
+			ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+			defer cancel()
+			if err := h.Deliver(ctx, event); err != nil {
+				log.Printf("[EventBus] Dropped event %s (%s): %v", event.Id, event.Topic, err)
+			}
+		}(h)
+	}
+
+	for _, name := range durable {
+		go b.flush(name)
+	}
+
+	return len(online) + len(durable), nil
+}
+
+// flush delivers a subscriber's queued durable events in order, stopping
+// at the first failure so ordering is preserved
+func (b *Bus) flush(name string) {
+	b.mu.Lock()
+	sub, exists := b.subscribers[name]
+	if !exists || sub.flushing {
+		b.mu.Unlock()
+		return
+	}
+	sub.flushing = true
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		sub.flushing = false
+		b.mu.Unlock()
+	}()
+
+	for {
+		b.mu.Lock()
+		if sub.handler == nil || len(sub.pending) == 0 {
+			b.mu.Unlock()
+			return
+		}
+		h := sub.handler
+		event := sub.pending[0]
+		b.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err := h.Deliver(ctx, event)
+		cancel()
+		if err != nil {
+			log.Printf("[EventBus] Delivery of %s to %s failed, will retry: %v", event.Id, name, err)
+			return
+		}
+
+		b.mu.Lock()
+		if len(sub.pending) > 0 && sub.pending[0] == event {
+			sub.pending = sub.pending[1:]
+			if err := b.ackLocked(sub, event); err != nil {
+				log.Printf("[EventBus] Failed to persist queue for %s: %v", name, err)
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// retryLoop periodically retries queued durable events
+func (b *Bus) retryLoop() {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopRetry:
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			names := make([]string, 0)
+			for name, sub := range b.subscribers {
+				if sub.handler != nil && len(sub.pending) > 0 {
+					names = append(names, name)
+				}
+			}
+			b.mu.Unlock()
+
+			for _, name := range names {
+				b.flush(name)
+			}
+		}
+	}
+}
+
+// registerLocked creates or updates a subscriber, loading its durable queue
+// from disk the first time it is seen
+func (b *Bus) registerLocked(name string, topics []string) *subscriber {
+	sub, exists := b.subscribers[name]
+	if !exists {
+		sub = &subscriber{name: name}
+		if err := b.loadQueueLocked(sub); err != nil {
+			log.Printf("[EventBus] Failed to load queue for %s: %v", name, err)
+		}
+		b.subscribers[name] = sub
+	}
+	sub.topics = topics
+	return sub
+}
+
+func (b *Bus) queuePath(name string) string {
+	return filepath.Join(b.queueDir, name+".queue")
+}
+
+// enqueueLocked queues a durable event for a subscriber, dropping the
+// oldest one if the queue is full
+func (b *Bus) enqueueLocked(sub *subscriber, event *pb.BusEvent) {
+	records := make([]string, 0, 2)
+	if len(sub.pending) >= maxPending {
+		dropped := sub.pending[0]
+		sub.pending = sub.pending[1:]
+		records = append(records, "-"+dropped.Id)
+		log.Printf("[EventBus] Queue for %s is full (%d events), dropped oldest event %s (%s)",
+			sub.name, maxPending, dropped.Id, dropped.Topic)
+	}
+
+	data, err := proto.Marshal(event)
+	if err != nil {
+		log.Printf("[EventBus] Failed to persist event %s for %s: %v", event.Id, sub.name, err)
+	} else {
+		records = append(records, "+"+base64.StdEncoding.EncodeToString(data))
+	}
+	sub.pending = append(sub.pending, event)
+
+	if err := b.appendLocked(sub, records); err != nil {
+		log.Printf("[EventBus] Failed to persist queue for %s: %v", sub.name, err)
+	}
+}
+
+// ackLocked records that an event left the queue
+func (b *Bus) ackLocked(sub *subscriber, event *pb.BusEvent) error {
+	if len(sub.pending) == 0 {
+		sub.records = 0
+		err := os.Remove(b.queuePath(sub.name))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return b.appendLocked(sub, []string{"-" + event.Id})
+}
+
+// appendLocked adds records to a subscriber's queue file, compacting it
+// when removed events outweigh queued ones. A failed append rewrites the
+// file so it never holds a partial record.
+func (b *Bus) appendLocked(sub *subscriber, records []string) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if garbage := sub.records + len(records) - len(sub.pending); garbage >= compactMin && garbage >= len(sub.pending) {
+		return b.compactLocked(sub)
+	}
+
+	f, err := os.OpenFile(b.queuePath(sub.name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(strings.Join(records, "\n") + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return b.compactLocked(sub)
+	}
+	sub.records += len(records)
+	return nil
+}
+
+// compactLocked rewrites a subscriber's queue file atomically with only
+// the events still queued
+func (b *Bus) compactLocked(sub *subscriber) error {
+	path := b.queuePath(sub.name)
+	sub.records = 0
+	if len(sub.pending) == 0 {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var sb strings.Builder
+	for _, event := range sub.pending {
+		data, err := proto.Marshal(event)
+		if err != nil {
+			return err
+		}
+		sb.WriteByte('+')
+		sb.WriteString(base64.StdEncoding.EncodeToString(data))
+		sb.WriteByte('\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	sub.records = len(sub.pending)
+	return nil
+}
+
+// loadQueueLocked replays a subscriber's queue file, skipping corrupt
+// records
+func (b *Bus) loadQueueLocked(sub *subscriber) error {
+	f, err := os.Open(b.queuePath(sub.name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 32*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		sub.records++
+		switch {
+		case strings.HasPrefix(line, "+"):
+			data, err := base64.StdEncoding.DecodeString(line[1:])
+			if err != nil {
+				log.Printf("[EventBus] Skipping corrupt event in %s queue: %v", sub.name, err)
+				continue
+			}
+			var event pb.BusEvent
+			if err := proto.Unmarshal(data, &event); err != nil {
+				log.Printf("[EventBus] Skipping corrupt event in %s queue: %v", sub.name, err)
+				continue
+			}
+			sub.pending = append(sub.pending, &event)
+		case strings.HasPrefix(line, "-"):
+			for i, event := range sub.pending {
+				if event.Id == line[1:] {
+					sub.pending = append(sub.pending[:i], sub.pending[i+1:]...)
+					break
+				}
+			}
+		default:
+			log.Printf("[EventBus] Skipping corrupt record in %s queue", sub.name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(sub.pending) > maxPending {
+		log.Printf("[EventBus] Queue for %s holds %d events, dropped the oldest %d",
+			sub.name, len(sub.pending), len(sub.pending)-maxPending)
+		sub.pending = sub.pending[len(sub.pending)-maxPending:]
+	}
+	return nil
+}
+
+// MatchTopic reports whether a subscription pattern matches a topic.
+// "*" matches everything and "prefix.*" matches any topic under prefix.
+func MatchTopic(pattern, topic string) bool {
+	if pattern == "*" || pattern == topic {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, ".*"); ok {
+		return strings.HasPrefix(topic, prefix+".")
+	}
+	return false
+}
+
+func matchAny(patterns []string, topic string) bool {
+	for _, p := range patterns {
+		if MatchTopic(p, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func newEventID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -8,11 +8,21 @@ import (
 	"github.com/DaikonSushi/bot-platform/internal/message"
 )
 
+// CommandResolver decides which plugin owns a bare command when built-in
+// and external plugins declare the same one
+type CommandResolver interface {
+	// ClaimBuiltinCommands enters a built-in plugin's commands into resolution
+	ClaimBuiltinCommands(plugin string, commands []string)
+	// CommandOwner returns the plugin a bare command resolves to
+	CommandOwner(cmd string) string
+}
+
 // Manager manages all registered plugins
 type Manager struct {
 	plugins       []Plugin
 	commandMap    map[string]Plugin
 	commandPrefix string
+	resolver      CommandResolver
 	mu            sync.RWMutex
 }
 
@@ -32,16 +42,62 @@ func (m *Manager) Register(p Plugin) {
 
 	m.plugins = append(m.plugins, p)
 
-	// Register commands
+	if m.resolver != nil {
+		m.resolver.ClaimBuiltinCommands(p.Name(), p.Commands())
+	} else {
+		m.claimLocked(p)
+	}
+
+	log.Printf("[Plugin] Registered plugin: %s (commands: %v)", p.Name(), p.Commands())
+}
+
+// SetCommandResolver hands conflict resolution for bare commands to the
+// external plugin manager, so built-in and external plugins compete under
+// the same policy and pins
+func (m *Manager) SetCommandResolver(r CommandResolver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resolver = r
+	for _, p := range m.plugins {
+		r.ClaimBuiltinCommands(p.Name(), p.Commands())
+	}
+}
+
+// claimLocked registers commands among built-in plugins only, first
+// registration wins. Caller must hold m.mu.
+func (m *Manager) claimLocked(p Plugin) {
 	for _, cmd := range p.Commands() {
 		if existing, ok := m.commandMap[cmd]; ok {
-			log.Printf("[Plugin] Warning: command '%s' already registered by plugin '%s', overwriting with '%s'",
-				cmd, existing.Name(), p.Name())
+			log.Printf("[Plugin] Warning: command '%s' already registered by plugin '%s', use '%s:%s' to reach '%s'",
+				cmd, existing.Name(), p.Name(), cmd, p.Name())
+			continue
 		}
 		m.commandMap[cmd] = p
 	}
+}
+
+// ownerLocked returns the built-in plugin a bare command resolves to. With
+// a resolver the command may belong to an external plugin instead, which
+// leaves it for the external plugin manager. Caller must hold m.mu.
+func (m *Manager) ownerLocked(cmd string) (Plugin, bool) {
+	if m.resolver == nil {
+		plugin, exists := m.commandMap[cmd]
+		return plugin, exists
+	}
 
-	log.Printf("[Plugin] Registered plugin: %s (commands: %v)", p.Name(), p.Commands())
+	owner := m.resolver.CommandOwner(cmd)
+	for _, p := range m.plugins {
+		if p.Name() != owner {
+			continue
+		}
+		for _, c := range p.Commands() {
+			if c == cmd {
+				return p, true
+			}
+		}
+	}
+	return nil, false
 }
 
 // HandleEvent processes an incoming event
@@ -51,7 +107,11 @@ func (m *Manager) HandleEvent(ctx *Context) bool {
 
 	// Check if it's a command
 	if cmd, args, ok := m.parseCommand(ctx.Event); ok {
-		if plugin, exists := m.commandMap[cmd]; exists {
+		if plugin, bare, ok := m.findQualified(cmd); ok {
+			log.Printf("[Plugin] Dispatching command '%s' to plugin '%s'", bare, plugin.Name())
+			return plugin.OnCommand(ctx, bare, args)
+		}
+		if plugin, exists := m.ownerLocked(cmd); exists {
 			log.Printf("[Plugin] Dispatching command '%s' to plugin '%s'", cmd, plugin.Name())
 			return plugin.OnCommand(ctx, cmd, args)
 		}
@@ -88,6 +148,26 @@ func (m *Manager) parseCommand(event *message.Event) (cmd string, args []string,
 	return parts[0], parts[1:], true
 }
 
+// findQualified resolves a "plugin:cmd" command to a registered plugin
+// that declares cmd. Caller must hold m.mu.
+func (m *Manager) findQualified(cmd string) (Plugin, string, bool) {
+	name, bare, ok := strings.Cut(cmd, ":")
+	if !ok {
+		return nil, "", false
+	}
+	for _, p := range m.plugins {
+		if p.Name() != name {
+			continue
+		}
+		for _, c := range p.Commands() {
+			if c == bare {
+				return p, bare, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
 // GetPlugins returns all registered plugins
 func (m *Manager) GetPlugins() []Plugin {
 	m.mu.RLock()
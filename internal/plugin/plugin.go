@@ -1,6 +1,9 @@
 package plugin
 
 import (
+	"context"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
 	"github.com/DaikonSushi/bot-platform/internal/message"
 )
 
@@ -21,6 +24,8 @@ type BotAPI interface {
 	Reply(ctx *Context, msg *message.Message) error
 	// GetLoginInfo gets bot login info
 	GetLoginInfo() (*LoginInfo, error)
+	// Publish publishes a JSON-encoded event on the plugin event bus
+	Publish(source, topic string, payload interface{}, durable bool) error
 }
 
 // LoginInfo represents bot login information
@@ -44,6 +49,14 @@ type Plugin interface {
 	OnCommand(ctx *Context, cmd string, args []string) bool
 }
 
+// EventHandler is implemented by plugins that subscribe to the plugin event bus
+type EventHandler interface {
+	// Topics returns the topics to subscribe to ("prefix.*" and "*" allowed)
+	Topics() []string
+	// OnEvent handles an event. Returning an error leaves a durable event queued.
+	OnEvent(ctx context.Context, event *pb.BusEvent) error
+}
+
 // BasePlugin provides default implementations
 type BasePlugin struct {
 	PluginName        string
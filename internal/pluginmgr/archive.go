@@ -0,0 +1,219 @@
+package pluginmgr
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Archive formats plugin binaries may be shipped in
+const (
+	formatTarGz = "tar.gz"
+	formatZip   = "zip"
+)
+
+// maxBinarySize bounds how much is unpacked from an archive
+const maxBinarySize = 512 << 20
+
+// archiveFormat returns the archive format of a file name, "" for anything
+// that is not an archive
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGz
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip
+	}
+	return ""
+}
+
+// archiveEntry is a regular file found in an archive
+type archiveEntry struct {
+	name string
+	exec bool
+	open func() (io.ReadCloser, error)
+}
+
+// extractBinary unpacks the plugin binary from an archive to dest and
+// returns its file name. The archive must contain exactly one executable,
+// or exactly one file that is not documentation.
+func extractBinary(archive, format, dest string) (string, error) {
+	var entries []archiveEntry
+	var closer io.Closer
+
+	switch format {
+	case formatTarGz:
+		// tar is read sequentially, so the binary is picked by a first
+		// pass over the headers and copied out in a second one
+		names, err := tarEntries(archive)
+		if err != nil {
+			return "", err
+		}
+		entries = names
+	case formatZip:
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return "", fmt.Errorf("invalid zip archive: %w", err)
+		}
+		closer = zr
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			entries = append(entries, archiveEntry{
+				name: f.Name,
+				exec: f.Mode()&0111 != 0,
+				open: f.Open,
+			})
+		}
+	default:
+		return "", fmt.Errorf("unsupported archive format %q", format)
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	entry, err := pickBinary(entries)
+	if err != nil {
+		return "", err
+	}
+
+	var src io.ReadCloser
+	if format == formatTarGz {
+		src, err = openTarEntry(archive, entry.name)
+	} else {
+		src, err = entry.open()
+	}
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(src, maxBinarySize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", entry.name, err)
+	}
+	if n > maxBinarySize {
+		return "", fmt.Errorf("%s is larger than %dMB", entry.name, maxBinarySize>>20)
+	}
+	return path.Base(entry.name), nil
+}
+
+// pickBinary chooses the plugin binary among an archive's files
+func pickBinary(entries []archiveEntry) (archiveEntry, error) {
+	var execs, others []archiveEntry
+	for _, e := range entries {
+		base := strings.ToLower(path.Base(e.name))
+		if strings.HasPrefix(base, ".") || isDocumentation(base) {
+			continue
+		}
+		if e.exec || strings.HasSuffix(base, ".exe") {
+			execs = append(execs, e)
+		} else {
+			others = append(others, e)
+		}
+	}
+
+	switch {
+	case len(execs) == 1:
+		return execs[0], nil
+	case len(execs) > 1:
+		return archiveEntry{}, fmt.Errorf("archive contains several executables: %s", entryNames(execs))
+	case len(others) == 1:
+		return others[0], nil
+	case len(others) > 1:
+		return archiveEntry{}, fmt.Errorf("archive contains no executable and several files: %s", entryNames(others))
+	}
+	return archiveEntry{}, fmt.Errorf("archive contains no plugin binary")
+}
+
+func isDocumentation(base string) bool {
+	for _, prefix := range []string{"readme", "license", "licence", "changelog", "notice"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	for _, ext := range []string{".md", ".txt", ".json", ".yaml", ".yml", ".sha256", ".sig"} {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func entryNames(entries []archiveEntry) string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// tarEntries lists the regular files of a .tar.gz archive
+func tarEntries(archive string) ([]archiveEntry, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tar.gz archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	entries := make([]archiveEntry, 0)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		entries = append(entries, archiveEntry{name: hdr.Name, exec: hdr.Mode&0111 != 0})
+	}
+}
+
+// openTarEntry opens one file of a .tar.gz archive
+func openTarEntry(archive, name string) (io.ReadCloser, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s not found in archive: %w", name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg && hdr.Name == name {
+			return struct {
+				io.Reader
+				io.Closer
+			}{tr, f}, nil
+		}
+	}
+}
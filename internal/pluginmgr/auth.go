@@ -0,0 +1,97 @@
+package pluginmgr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// EnvSecret carries a plugin's per-launch secret to the spawned process
+const EnvSecret = "BOT_PLUGIN_SECRET"
+
+// SecretMetadataKey is the gRPC metadata key plugins send their secret in
+const SecretMetadataKey = "x-plugin-secret"
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the authenticated plugin name
+func WithCaller(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, callerKey{}, name)
+}
+
+// CallerFromContext returns the plugin that made the current BotService call
+func CallerFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(callerKey{}).(string)
+	return name, ok && name != ""
+}
+
+// issueSecret creates a secret for one launch of a plugin. It is kept
+// outside pm.mu because plugins call back while StartPlugin holds it.
+func (pm *PluginManager) issueSecret(name string) string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	secret := hex.EncodeToString(buf)
+
+	pm.authMu.Lock()
+	pm.callers[secret] = name
+	pm.authMu.Unlock()
+	return secret
+}
+
+// revokeSecret forgets a launch secret
+func (pm *PluginManager) revokeSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	pm.authMu.Lock()
+	delete(pm.callers, secret)
+	pm.authMu.Unlock()
+}
+
+// resolveCaller maps a secret to the plugin it was issued to
+func (pm *PluginManager) resolveCaller(secret string) (string, bool) {
+	pm.authMu.RLock()
+	defer pm.authMu.RUnlock()
+	name, ok := pm.callers[secret]
+	return name, ok
+}
+
+// AuthInterceptor rejects BotService calls that do not carry a live
+// per-launch secret or exceed the caller's approved capabilities, and
+// attaches the caller's plugin name to the context.
+// Other services (the plugin stream) authenticate themselves.
+func (pm *PluginManager) AuthInterceptor() grpc.UnaryServerInterceptor {
+	prefix := "/" + pb.BotService_ServiceDesc.ServiceName + "/"
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+
+		var secret string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(SecretMetadataKey); len(values) > 0 {
+				secret = values[0]
+			}
+		}
+
+		name, ok := pm.resolveCaller(secret)
+		if secret == "" || !ok {
+			log.Printf("[PluginMgr] Rejected unauthenticated call to %s", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "unknown plugin")
+		}
+		if err := pm.checkCall(name, strings.TrimPrefix(info.FullMethod, prefix), req); err != nil {
+			return nil, err
+		}
+		return handler(WithCaller(ctx, name), req)
+	}
+}
@@ -0,0 +1,232 @@
+package pluginmgr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// Running plugins are switched to another version blue/green: the new
+// version is started as a second instance next to the running one and
+// health-checked while the old one keeps serving. Only once it is healthy
+// are commands, message subscriptions and events handed over, in one step
+// under pm.mu. The old instance then finishes the calls it is handling and
+// is shut down. A new version that fails is thrown away and the old one
+// never stops serving.
+
+// drainTimeout bounds how long a replaced instance may take to finish the
+// calls it is handling before it is shut down
+const drainTimeout = 30 * time.Second
+
+// drainPoll is how often a draining instance is checked for calls in progress
+const drainPoll = 50 * time.Millisecond
+
+// healthChecks is how many health checks a new instance must pass within
+// upgradeSettle before it takes over
+const healthChecks = 3
+
+// track counts a call into a plugin instance, so that an instance being
+// replaced is not shut down under it. Dispatchers call it while holding
+// pm.mu, which the switch to a new instance takes exclusively.
+func (s *PluginState) track() {
+	s.inflight.Add(1)
+}
+
+// untrack ends a call counted by track
+func (s *PluginState) untrack() {
+	s.inflight.Add(-1)
+}
+
+// switchOver replaces blue, the running instance of a plugin whose
+// lifecycle lock is held, with an instance running next, and records next
+// as the plugin's current version once it has taken over
+func (pm *PluginManager) switchOver(ctx context.Context, blue *PluginState, next *PluginMeta) error {
+	name := next.Name
+	cur := blue.Info
+
+	green, binaryPath, err := pm.prepareInstance(blue, next)
+	if err != nil {
+		return err
+	}
+	log.Printf("[PluginMgr] Starting plugin %s v%s next to v%s", name, next.Version, cur.Version)
+
+	// Both versions were approved; the new one's grant applies from now on
+	pm.grant(name, next.Granted())
+	launched := false
+	err = pm.launch(ctx, green, binaryPath)
+	if err == nil {
+		launched = true
+		err = pm.probe(ctx, green)
+	}
+
+	// The other replicas run the binary that just proved itself
+	var replicas, retired []*PluginState
+	if err == nil {
+		replicas = pm.launchReplicas(ctx, green, binaryPath, 2, next.ReplicaCount())
+
+		pm.mu.Lock()
+		err = pm.checkCommandConflictsLocked(next)
+		if err == nil {
+			err = pm.registry.put(next)
+		}
+		if err == nil {
+			retired = pm.swapLocked(blue, green)
+			pm.attachReplicasLocked(green, replicas)
+		} else {
+			pm.killReplicasLocked(replicas)
+		}
+		pm.mu.Unlock()
+	}
+
+	if err != nil {
+		if launched {
+			pm.shutdownInstance(ctx, green)
+		}
+		pm.grant(name, cur.Granted())
+		log.Printf("[PluginMgr] Plugin %s v%s failed to start, v%s keeps running: %v", name, next.Version, cur.Version, err)
+		return fmt.Errorf("plugin %s v%s failed to start, kept v%s: %w", name, next.Version, cur.Version, err)
+	}
+
+	log.Printf("[PluginMgr] Plugin %s switched from v%s to v%s", name, cur.Version, next.Version)
+	if pm.stopReplicas(ctx, retired, true) > 0 {
+		log.Printf("[PluginMgr] Stopped plugin %s v%s after switching", name, cur.Version)
+	}
+	return nil
+}
+
+// prepareInstance checks that next can run next to blue and returns the
+// instance to launch and its binary
+func (pm *PluginManager) prepareInstance(blue *PluginState, next *PluginMeta) (*PluginState, string, error) {
+	matcher, err := compileMatcher(next)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pm.mu.RLock()
+	err = pm.checkCommandConflictsLocked(next)
+	if err == nil {
+		err = pm.checkConfigLocked(next)
+	}
+	pm.mu.RUnlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	binaryPath := pm.binaryPath(next)
+	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("plugin binary not found: %s", binaryPath)
+	}
+	if err := checkBinary(next, binaryPath); err != nil {
+		return nil, "", err
+	}
+
+	// The two instances take turns, so neither reuses the other's socket
+	// or cgroup
+	instance := "green"
+	if greenInstance(blue.instance) {
+		instance = ""
+	}
+	return &PluginState{
+		Info:     next,
+		Status:   StatusStarting,
+		matcher:  matcher,
+		instance: instance,
+	}, binaryPath, nil
+}
+
+// greenInstance reports whether instance is the green one or one of its
+// replicas. Any replica may be carrying the plugin after a takeover.
+func greenInstance(instance string) bool {
+	return instance == "green" || strings.HasPrefix(instance, "green-")
+}
+
+// probe checks that a freshly started instance stays up and keeps
+// answering its health check
+func (pm *PluginManager) probe(ctx context.Context, state *PluginState) error {
+	for i := 0; i < healthChecks; i++ {
+		select {
+		case <-time.After(upgradeSettle / healthChecks):
+		case <-state.proc.done:
+			return fmt.Errorf("process %s", state.proc.exitDescription())
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err := state.Client.Health(healthCtx, &pb.Empty{})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// swapLocked makes green the running instance of its plugin in place of
+// blue. Commands both versions declare keep their place among claimants.
+// It returns blue and its replicas, which are to be drained. Caller must
+// hold pm.mu.
+func (pm *PluginManager) swapLocked(blue, green *PluginState) []*PluginState {
+	name := green.Info.Name
+
+	// Another replica may have taken over from blue meanwhile
+	if cur, exists := pm.plugins[name]; exists {
+		blue = cur
+	}
+
+	// blue may have crashed in the meantime and given up its commands
+	retired := append([]*PluginState{blue}, blue.replicas...)
+	live := pm.plugins[name] == blue && blue.Status == StatusRunning
+	if live {
+		for _, instance := range retired {
+			if instance.Status == StatusRunning {
+				instance.Status = StatusStopping
+			}
+		}
+	}
+
+	green.Status = StatusRunning
+	green.StartedAt = time.Now()
+	pm.plugins[name] = green
+	if live {
+		pm.swapCommandsLocked(blue.Info, green.Info)
+	} else {
+		pm.claimCommandsLocked(green.Info)
+	}
+
+	if pm.bus != nil {
+		if len(green.Info.EventTopics) > 0 {
+			pm.bus.Attach(name, green.Info.EventTopics, pm.eventHandler(green))
+		} else {
+			pm.bus.Detach(name)
+		}
+	}
+	go pm.supervise(name, green)
+	return retired
+}
+
+// drain waits for a replaced instance to finish the calls it is handling
+// and shuts it down
+func (pm *PluginManager) drain(ctx context.Context, state *PluginState) {
+	deadline := time.After(drainTimeout)
+wait:
+	for state.inflight.Load() > 0 {
+		select {
+		case <-time.After(drainPoll):
+		case <-deadline:
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+	if n := state.inflight.Load(); n > 0 {
+		log.Printf("[PluginMgr] Shutting down plugin %s v%s with %d calls still in progress", state.Info.Name, state.Info.Version, n)
+	}
+
+	pm.shutdownInstance(ctx, state)
+}
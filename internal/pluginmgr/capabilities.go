@@ -0,0 +1,321 @@
+package pluginmgr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Capabilities a plugin requests in its manifest and an admin approves.
+// A plugin whose manifest has no capabilities requests unrestricted access.
+type Capabilities struct {
+	RPCs        []string `json:"rpcs,omitempty"`         // BotService methods, e.g. "SendMessage"
+	Actions     []string `json:"actions,omitempty"`      // NapCat actions for CallAPI, "get_*" matches a prefix
+	Groups      []int64  `json:"groups,omitempty"`       // Groups the plugin may act in, empty means any
+	UploadPaths []string `json:"upload_paths,omitempty"` // Directories files may be uploaded from
+}
+
+// Capability approval states kept in PluginMeta.Approval. Plugins installed
+// before approvals existed have no state and keep unrestricted access.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+)
+
+// alwaysAllowed lists BotService methods every plugin may call
+var alwaysAllowed = []string{"Log", "GetConfig"}
+
+// Approved reports whether the plugin may be started
+func (meta *PluginMeta) Approved() bool {
+	return meta.Approval != ApprovalPending
+}
+
+// Granted returns the capabilities the plugin runs with; nil means unrestricted
+func (meta *PluginMeta) Granted() *Capabilities {
+	if meta.Approval == ApprovalApproved {
+		return meta.ApprovedCapabilities
+	}
+	return nil
+}
+
+// ApproveCapabilities grants an installed or connected remote plugin the
+// capabilities it requests. If an upgrade awaits approval, the new version
+// is approved and switched to instead.
+func (pm *PluginManager) ApproveCapabilities(name string) (*PluginMeta, error) {
+	if meta, ok, err := pm.approveUpgrade(name); ok {
+		return meta, err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if state, exists := pm.plugins[name]; exists && state.Remote {
+		return pm.approveRemoteLocked(state)
+	}
+
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.Approval = ApprovalApproved
+		meta.ApprovedCapabilities = meta.Capabilities
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if state, exists := pm.plugins[name]; exists && state.Status != StatusRunning && !state.Transitional() {
+		state.Info = meta
+	}
+
+	log.Printf("[PluginMgr] Approved capabilities of plugin %s: %s", name, meta.Capabilities)
+	return meta, nil
+}
+
+// carryApproval decides the approval state of a freshly installed meta.
+// An upgrade keeps its approval if it asks for nothing beyond what was
+// approved before; otherwise the admin has to approve again.
+func (pm *PluginManager) carryApproval(meta *PluginMeta) {
+	if prev, err := pm.registry.get(meta.Name); err == nil && prev.Approval == ApprovalApproved &&
+		prev.ApprovedCapabilities.covers(meta.Capabilities) {
+		meta.Approval = ApprovalApproved
+		meta.ApprovedCapabilities = prev.ApprovedCapabilities
+		return
+	}
+
+	// Nothing to approve for a plugin that only logs
+	if meta.Capabilities != nil && meta.Capabilities.empty() {
+		meta.Approval = ApprovalApproved
+		meta.ApprovedCapabilities = meta.Capabilities
+		return
+	}
+
+	meta.Approval = ApprovalPending
+	meta.ApprovedCapabilities = nil
+}
+
+// grant records the capabilities a plugin's calls are checked against.
+// It is kept under authMu because plugins call back while pm.mu is held.
+func (pm *PluginManager) grant(name string, caps *Capabilities) {
+	pm.authMu.Lock()
+	pm.grants[name] = caps
+	pm.authMu.Unlock()
+}
+
+// ungrant forgets a plugin's capabilities
+func (pm *PluginManager) ungrant(name string) {
+	pm.authMu.Lock()
+	delete(pm.grants, name)
+	pm.authMu.Unlock()
+}
+
+// checkCall enforces a plugin's capabilities on one BotService call
+func (pm *PluginManager) checkCall(name, method string, req interface{}) error {
+	pm.authMu.RLock()
+	caps, known := pm.grants[name]
+	pm.authMu.RUnlock()
+
+	if !known {
+		return status.Error(codes.PermissionDenied, "plugin has no capabilities")
+	}
+	if err := caps.permit(method, req); err != nil {
+		log.Printf("[PluginMgr] Denied %s to plugin %s: %v", method, name, err)
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// capabilityInterceptor enforces capabilities on calls made over a plugin stream
+func (pm *PluginManager) capabilityInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	name, _ := CallerFromContext(ctx)
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+	if err := pm.checkCall(name, method, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// permit checks a call against the capabilities; nil allows everything
+func (c *Capabilities) permit(method string, req interface{}) error {
+	if c == nil || containsString(alwaysAllowed, method) {
+		return nil
+	}
+	if !containsString(c.RPCs, method) {
+		return fmt.Errorf("%s is not an approved RPC", method)
+	}
+
+	switch r := req.(type) {
+	case *pb.SendMessageRequest:
+		// Anything but a private message is delivered to GroupId
+		if r.MessageType != "private" {
+			return c.permitGroup(r.GroupId)
+		}
+	case *pb.GetGroupInfoRequest:
+		return c.permitGroup(r.GroupId)
+	case *pb.UploadGroupFileRequest:
+		if err := c.permitGroup(r.GroupId); err != nil {
+			return err
+		}
+		return c.permitPath(r.FilePath)
+	case *pb.UploadPrivateFileRequest:
+		return c.permitPath(r.FilePath)
+	case *pb.CallAPIRequest:
+		if !matchAction(c.Actions, r.Action) {
+			return fmt.Errorf("action %s is not approved", r.Action)
+		}
+		if raw, ok := r.Params["group_id"]; ok {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid group_id %q", raw)
+			}
+			return c.permitGroup(id)
+		}
+	}
+	return nil
+}
+
+func (c *Capabilities) permitGroup(groupID int64) error {
+	if len(c.Groups) == 0 {
+		return nil
+	}
+	for _, id := range c.Groups {
+		if id == groupID {
+			return nil
+		}
+	}
+	return fmt.Errorf("group %d is not approved", groupID)
+}
+
+// permitPath allows an upload of path only if it resolves, symlinks
+// included, to a file inside one of the approved directories
+func (c *Capabilities) permitPath(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("upload path %s is not absolute", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("uploads from %s are not approved: %v", path, err)
+	}
+	for _, dir := range c.UploadPaths {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		if withinDir(resolved, realDir) {
+			return nil
+		}
+	}
+	return fmt.Errorf("uploads from %s are not approved", path)
+}
+
+// withinDir reports whether path is dir or lies beneath it, compared lexically
+func withinDir(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// covers reports whether every capability in req is within c; nil is unrestricted
+func (c *Capabilities) covers(req *Capabilities) bool {
+	if c == nil {
+		return true
+	}
+	if req == nil {
+		return false
+	}
+	for _, rpc := range req.RPCs {
+		if !containsString(c.RPCs, rpc) && !containsString(alwaysAllowed, rpc) {
+			return false
+		}
+	}
+	for _, action := range req.Actions {
+		if !matchAction(c.Actions, action) {
+			return false
+		}
+	}
+	for _, path := range req.UploadPaths {
+		if !c.coversPath(path) {
+			return false
+		}
+	}
+	if len(c.Groups) > 0 {
+		if len(req.Groups) == 0 {
+			return false
+		}
+		for _, id := range req.Groups {
+			if c.permitGroup(id) != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// coversPath reports whether the requested upload directory path lies
+// within one approved by c
+func (c *Capabilities) coversPath(path string) bool {
+	for _, dir := range c.UploadPaths {
+		if withinDir(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Capabilities) empty() bool {
+	for _, rpc := range c.RPCs {
+		if !containsString(alwaysAllowed, rpc) {
+			return false
+		}
+	}
+	return len(c.Actions) == 0 && len(c.UploadPaths) == 0
+}
+
+// String summarizes the capabilities for admins
+func (c *Capabilities) String() string {
+	if c == nil {
+		return "unrestricted"
+	}
+
+	parts := make([]string, 0)
+	if len(c.RPCs) > 0 {
+		parts = append(parts, "rpcs: "+strings.Join(c.RPCs, ", "))
+	}
+	if len(c.Actions) > 0 {
+		parts = append(parts, "actions: "+strings.Join(c.Actions, ", "))
+	}
+	if len(c.Groups) > 0 {
+		groups := make([]string, len(c.Groups))
+		for i, id := range c.Groups {
+			groups[i] = strconv.FormatInt(id, 10)
+		}
+		parts = append(parts, "groups: "+strings.Join(groups, ", "))
+	}
+	if len(c.UploadPaths) > 0 {
+		parts = append(parts, "uploads: "+strings.Join(c.UploadPaths, ", "))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// matchAction reports whether a NapCat action is covered by patterns.
+// "*" matches every action and "get_*" matches actions starting with "get_".
+func matchAction(patterns []string, action string) bool {
+	for _, p := range patterns {
+		if p == action || p == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "*"); ok && strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,403 @@
+package pluginmgr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Catalogs are index files listing installable plugins, so admins can
+// search for plugins and install them by name. A catalog is JSON served
+// over http(s) or read from a path on the core's host:
+//
+//	{
+//	  "plugins": [{
+//	    "name": "showmejm",
+//	    "description": "Downloads JM comics as PDF",
+//	    "repo": "https://github.com/DaikonSushi/plugin-showmejm",
+//	    "tags": ["comic"],
+//	    "versions": [{
+//	      "version": "v1.2.0",
+//	      "assets": {
+//	        "linux/amd64": {"url": "https://...", "sha256": "..."}
+//	      }
+//	    }]
+//	  }]
+//	}
+//
+// Versions are listed newest first. A version without an asset for this
+// platform is installed from the repo's release with the same tag; an
+// entry without versions follows the repo's releases.
+const (
+	catalogTTL     = 10 * time.Minute // catalogs are fetched again after this
+	catalogRetry   = time.Minute      // a catalog that failed to load is tried again after this
+	catalogTimeout = 15 * time.Second // bounds the catalog lookup made to resolve a ref
+	maxCatalogSize = 8 << 20
+)
+
+// ErrNoCatalogs is returned when a catalog is queried but none is configured
+var ErrNoCatalogs = errors.New("no plugin catalogs are configured")
+
+// errNotInCatalog is returned when the catalogs loaded but list no such plugin
+var errNotInCatalog = errors.New("not in any catalog")
+
+// CatalogEntry is a plugin listed in a catalog
+type CatalogEntry struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Repo        string           `json:"repo,omitempty"` // release repo, used for versions without an asset
+	Tags        []string         `json:"tags,omitempty"`
+	Versions    []CatalogVersion `json:"versions,omitempty"`  // newest first
+	Catalog     string           `json:"catalog"`             // catalog the entry was found in
+	Installed   string           `json:"installed,omitempty"` // installed version, set by SearchCatalog
+}
+
+// CatalogVersion is one published version of a catalog entry
+type CatalogVersion struct {
+	Version string                  `json:"version"`
+	Assets  map[string]CatalogAsset `json:"assets,omitempty"` // "linux/amd64" -> binary or archive
+}
+
+// CatalogAsset is a downloadable build of a plugin version
+type CatalogAsset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256,omitempty"` // checked after download if set
+}
+
+// Latest returns the newest listed version, "" if the entry lists none
+func (e *CatalogEntry) Latest() string {
+	if len(e.Versions) == 0 {
+		return ""
+	}
+	return e.Versions[0].Version
+}
+
+// version finds a listed version; "1.2.0" and "v1.2.0" are the same
+func (e *CatalogEntry) version(tag string) (CatalogVersion, bool) {
+	for _, v := range e.Versions {
+		if strings.TrimPrefix(v.Version, "v") == strings.TrimPrefix(tag, "v") {
+			return v, true
+		}
+	}
+	return CatalogVersion{}, false
+}
+
+// cachedCatalog is the last good copy of a catalog and the error of the
+// last attempt to fetch it
+type cachedCatalog struct {
+	entries []CatalogEntry // nil if the catalog was never read
+	err     error
+	expires time.Time
+}
+
+// SetCatalogs configures the catalogs plugins are searched in, as URLs or
+// local paths. A plugin listed in several catalogs is taken from the first.
+func (pm *PluginManager) SetCatalogs(locations []string) {
+	pm.mu.Lock()
+	pm.catalogs = append([]string(nil), locations...)
+	pm.mu.Unlock()
+
+	pm.catalogMu.Lock()
+	pm.catalogCache = make(map[string]*cachedCatalog)
+	pm.catalogMu.Unlock()
+}
+
+// loadCatalogs returns the entries of all catalogs. A catalog that cannot
+// be fetched is served from its last good copy; it is an error only if no
+// catalog could be read at all.
+func (pm *PluginManager) loadCatalogs(ctx context.Context, refresh bool) ([]CatalogEntry, error) {
+	pm.mu.RLock()
+	locations := pm.catalogs
+	pm.mu.RUnlock()
+	if len(locations) == 0 {
+		return nil, ErrNoCatalogs
+	}
+
+	pm.catalogMu.Lock()
+	defer pm.catalogMu.Unlock()
+
+	seen := make(map[string]bool)
+	entries := make([]CatalogEntry, 0)
+	var errs []error
+	for _, location := range locations {
+		cached := pm.catalogCache[location]
+		if cached == nil || refresh || time.Now().After(cached.expires) {
+			fresh, err := readCatalog(ctx, location)
+			if err == nil {
+				cached = &cachedCatalog{entries: fresh, expires: time.Now().Add(catalogTTL)}
+			} else {
+				log.Printf("[PluginMgr] Failed to load catalog %s: %v", location, err)
+				var stale []CatalogEntry
+				if cached != nil {
+					stale = cached.entries
+				}
+				cached = &cachedCatalog{entries: stale, err: err, expires: time.Now().Add(catalogRetry)}
+			}
+			pm.catalogCache[location] = cached
+		}
+		if cached.entries == nil {
+			errs = append(errs, fmt.Errorf("catalog %s: %w", location, cached.err))
+			continue
+		}
+
+		for _, entry := range cached.entries {
+			key := strings.ToLower(entry.Name)
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(entries) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return entries, nil
+}
+
+// readCatalog fetches and parses one catalog
+func readCatalog(ctx context.Context, location string) ([]CatalogEntry, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		data, err = fetchCatalog(ctx, location)
+	} else {
+		data, err = os.ReadFile(strings.TrimPrefix(location, "file://"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Plugins []CatalogEntry `json:"plugins"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %w", err)
+	}
+
+	entries := make([]CatalogEntry, 0, len(file.Plugins))
+	for _, entry := range file.Plugins {
+		if !validStoreName.MatchString(entry.Name) {
+			log.Printf("[PluginMgr] Skipping catalog entry with invalid name %q in %s", entry.Name, location)
+			continue
+		}
+		entry.Catalog = location
+		entry.Installed = ""
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func fetchCatalog(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned %d", location, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCatalogSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxCatalogSize {
+		return nil, fmt.Errorf("%s is larger than %dMB", location, maxCatalogSize>>20)
+	}
+	return data, nil
+}
+
+// SearchCatalog finds plugins whose name, description or tags contain the
+// query; an empty query lists every plugin. Name matches come first.
+func (pm *PluginManager) SearchCatalog(ctx context.Context, query string, refresh bool) ([]CatalogEntry, error) {
+	entries, err := pm.loadCatalogs(ctx, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	rank := func(e *CatalogEntry) int {
+		name := strings.ToLower(e.Name)
+		switch {
+		case query == "" || name == query:
+			return 0
+		case strings.HasPrefix(name, query):
+			return 1
+		case strings.Contains(name, query):
+			return 2
+		case strings.Contains(strings.ToLower(e.Description), query):
+			return 3
+		}
+		for _, tag := range e.Tags {
+			if strings.Contains(strings.ToLower(tag), query) {
+				return 3
+			}
+		}
+		return -1
+	}
+
+	matches := make([]CatalogEntry, 0)
+	ranks := make(map[string]int)
+	for _, entry := range entries {
+		if r := rank(&entry); r >= 0 {
+			ranks[entry.Name] = r
+			matches = append(matches, entry)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if ranks[matches[i].Name] != ranks[matches[j].Name] {
+			return ranks[matches[i].Name] < ranks[matches[j].Name]
+		}
+		return matches[i].Name < matches[j].Name
+	})
+
+	pm.mu.RLock()
+	for i := range matches {
+		if meta, err := pm.registry.get(matches[i].Name); err == nil {
+			matches[i].Installed = meta.Version
+		}
+	}
+	pm.mu.RUnlock()
+	return matches, nil
+}
+
+// catalogEntry looks a plugin up by name
+func (pm *PluginManager) catalogEntry(ctx context.Context, name string) (*CatalogEntry, error) {
+	entries, err := pm.loadCatalogs(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if strings.EqualFold(entries[i].Name, name) {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s is %w", name, errNotInCatalog)
+}
+
+// catalogSource installs plugins by their catalog name, e.g. "showmejm"
+// or "showmejm@v1.2.0"
+type catalogSource struct {
+	pm       *PluginManager
+	releases *releaseSource
+	verify   *verifier
+}
+
+func (s *catalogSource) Name() string { return "catalog" }
+
+func (s *catalogSource) Match(ctx context.Context, ref string) bool {
+	name, _ := splitRef(ref)
+	if !validStoreName.MatchString(name) {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, catalogTimeout)
+	defer cancel()
+	// A catalog that failed to load is claimed anyway, so Fetch reports
+	// why instead of the ref looking like nothing we can install
+	_, err := s.pm.catalogEntry(ctx, name)
+	return !errors.Is(err, ErrNoCatalogs) && !errors.Is(err, errNotInCatalog)
+}
+
+func (s *catalogSource) Versions(ctx context.Context, ref string) ([]string, error) {
+	name, _ := splitRef(ref)
+	entry, err := s.pm.catalogEntry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.Versions) == 0 && entry.Repo != "" && s.releases.Match(ctx, entry.Repo) {
+		return s.releases.Versions(ctx, entry.Repo)
+	}
+
+	tags := make([]string, 0, len(entry.Versions))
+	for _, v := range entry.Versions {
+		tags = append(tags, v.Version)
+	}
+	return tags, nil
+}
+
+func (s *catalogSource) Fetch(ctx context.Context, ref, dest string) (*Artifact, error) {
+	name, tag := splitRef(ref)
+	entry, err := s.pm.catalogEntry(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		tag = entry.Latest()
+	}
+
+	version, listed := entry.version(tag)
+	if tag != "" && !listed && len(entry.Versions) > 0 {
+		return nil, fmt.Errorf("catalog lists no version %s of %s", tag, entry.Name)
+	}
+	if asset, ok := version.Assets[runtime.GOOS+"/"+runtime.GOARCH]; ok {
+		artifact, err := s.fetchAsset(ctx, asset, dest)
+		if err != nil {
+			return nil, err
+		}
+		artifact.Release = version.Version
+		artifact.RepoURL = entry.Repo
+		return artifact, nil
+	}
+
+	// Fall back to the repo's release of the same tag
+	if entry.Repo == "" || !s.releases.Match(ctx, entry.Repo) {
+		return nil, fmt.Errorf("catalog has no %s/%s build of %s", runtime.GOOS, runtime.GOARCH, strings.TrimSpace(entry.Name+" "+tag))
+	}
+	repoRef := entry.Repo
+	if tag != "" {
+		repoRef += "@" + tag
+	}
+	return s.releases.Fetch(ctx, repoRef, dest)
+}
+
+// fetchAsset downloads a catalog asset. A checksum listed in the catalog
+// must match; checksums and signatures published next to the asset are
+// checked too.
+func (s *catalogSource) fetchAsset(ctx context.Context, asset CatalogAsset, dest string) (*Artifact, error) {
+	u, err := url.Parse(asset.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid asset URL %q", asset.URL)
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return nil, fmt.Errorf("cannot tell a file name from %s", asset.URL)
+	}
+
+	check := func(file string) (string, error) {
+		level, err := s.verify.verify(ctx, name, file, sidecarNames(name), func(ctx context.Context, sidecar string) ([]byte, error) {
+			return fetchSmall(ctx, u.ResolveReference(&url.URL{Path: url.PathEscape(sidecar)}).String(), "")
+		})
+		if err != nil || asset.SHA256 == "" {
+			return level, err
+		}
+		sum, err := fileSHA256(file)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(sum, asset.SHA256) {
+			return "", fmt.Errorf("checksum mismatch for %s: catalog lists %s, download is %s", name, asset.SHA256, sum)
+		}
+		if level == "" {
+			level = VerifiedChecksum
+		}
+		return level, nil
+	}
+
+	log.Printf("[PluginMgr] Downloading %s...", asset.URL)
+	return fetchBinary(ctx, asset.URL, name, "", dest, check)
+}
@@ -0,0 +1,212 @@
+package pluginmgr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeCatalog writes a catalog listing plugins to a temp dir and returns
+// its path
+func writeCatalog(t *testing.T, plugins []CatalogEntry) string {
+	t.Helper()
+
+	data, err := json.Marshal(map[string][]CatalogEntry{"plugins": plugins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serveAssets serves files by name and 404s everything else, like a
+// release without checksum files next to its assets
+func serveAssets(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCatalog(t *testing.T) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	srv := serveAssets(t, map[string]string{
+		"echo-1.1.0":  "echo build for this platform",
+		"echo-other":  "echo build for another platform",
+		"echo-1.0.0":  "older echo build",
+		"tamper-v1":   "not what the catalog promised",
+		"showmejm-v2": "showmejm build",
+	})
+	asset := func(name, content string) CatalogAsset {
+		return CatalogAsset{URL: srv.URL + "/" + name, SHA256: sha256Hex(content)}
+	}
+
+	pm := NewPluginManager(t.TempDir(), t.TempDir(), 0)
+	t.Cleanup(pm.Shutdown)
+	pm.SetCatalogs([]string{writeCatalog(t, []CatalogEntry{
+		{
+			Name:        "echo",
+			Description: "Repeats what it is told",
+			Versions: []CatalogVersion{
+				{Version: "v1.1.0", Assets: map[string]CatalogAsset{
+					platform:     asset("echo-1.1.0", "echo build for this platform"),
+					"plan9/mips": asset("echo-other", "echo build for another platform"),
+				}},
+				{Version: "v1.0.0", Assets: map[string]CatalogAsset{
+					platform: asset("echo-1.0.0", "older echo build"),
+				}},
+			},
+		},
+		{
+			Name: "tamper",
+			Versions: []CatalogVersion{
+				{Version: "v1", Assets: map[string]CatalogAsset{
+					platform: asset("tamper-v1", "the build the catalog was written for"),
+				}},
+			},
+		},
+		{
+			Name:        "showmejm",
+			Description: "Downloads JM comics as PDF",
+			Tags:        []string{"comic"},
+			Versions: []CatalogVersion{
+				{Version: "v2", Assets: map[string]CatalogAsset{
+					"plan9/mips": asset("showmejm-v2", "showmejm build"),
+				}},
+			},
+		},
+	})})
+
+	t.Run("search", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"echo", "showmejm", "tamper"}},
+			{"ECHO", []string{"echo"}},
+			{"m", []string{"showmejm", "tamper"}},
+			{"comic", []string{"showmejm"}},
+			{"nothing like it", []string{}},
+		}
+		for _, tt := range tests {
+			entries, err := pm.SearchCatalog(context.Background(), tt.query, false)
+			if err != nil {
+				t.Fatalf("search %q: %v", tt.query, err)
+			}
+			got := make([]string, 0, len(entries))
+			for _, e := range entries {
+				got = append(got, e.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("search %q = %v, want %v", tt.query, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		tests := []struct {
+			ref    string
+			source string // "" if the ref must not resolve
+		}{
+			{"echo", "catalog"},
+			{"Echo@v1.0.0", "catalog"},
+			{"missing", ""},
+			{"owner/repo", "github"},
+			{srv.URL + "/echo-1.1.0", "url"},
+		}
+		for _, tt := range tests {
+			src, err := pm.resolveSource(context.Background(), tt.ref)
+			if tt.source == "" {
+				if err == nil {
+					t.Errorf("resolve %q: expected an error, got source %s", tt.ref, src.Name())
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("resolve %q: %v", tt.ref, err)
+			} else if src.Name() != tt.source {
+				t.Errorf("resolve %q = %s, want %s", tt.ref, src.Name(), tt.source)
+			}
+		}
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		tests := []struct {
+			ref     string
+			content string // binary expected on success
+			release string
+			err     string // substring of the expected error
+		}{
+			{ref: "echo", content: "echo build for this platform", release: "v1.1.0"},
+			{ref: "echo@1.0.0", content: "older echo build", release: "v1.0.0"},
+			{ref: "echo@v9", err: "lists no version v9"},
+			{ref: "tamper", err: "checksum mismatch"},
+			{ref: "showmejm", err: "has no " + platform + " build"},
+		}
+		for _, tt := range tests {
+			src, err := pm.resolveSource(context.Background(), tt.ref)
+			if err != nil {
+				t.Fatalf("resolve %q: %v", tt.ref, err)
+			}
+			dest := filepath.Join(t.TempDir(), "plugin")
+			artifact, err := src.Fetch(context.Background(), tt.ref, dest)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("fetch %q: expected error containing %q, got %v", tt.ref, tt.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("fetch %q: %v", tt.ref, err)
+				continue
+			}
+			data, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.content || artifact.Release != tt.release || artifact.Verified != VerifiedChecksum {
+				t.Errorf("fetch %q got %q release %s verified %q, want %q release %s verified by checksum",
+					tt.ref, data, artifact.Release, artifact.Verified, tt.content, tt.release)
+			}
+		}
+	})
+}
+
+func TestCatalogLoadError(t *testing.T) {
+	pm := NewPluginManager(t.TempDir(), t.TempDir(), 0)
+	t.Cleanup(pm.Shutdown)
+	pm.SetCatalogs([]string{filepath.Join(t.TempDir(), "missing.json")})
+
+	src, err := pm.resolveSource(context.Background(), "echo")
+	if err != nil {
+		t.Fatalf("a name should resolve to the catalog even if it failed to load: %v", err)
+	}
+	_, err = src.Fetch(context.Background(), "echo", filepath.Join(t.TempDir(), "plugin"))
+	if err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Fatalf("expected the catalog load error, got %v", err)
+	}
+}
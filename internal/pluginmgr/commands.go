@@ -0,0 +1,305 @@
+package pluginmgr
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Command conflict policies applied when two running plugins declare the
+// same bare command
+const (
+	ConflictReject   = "reject"   // refuse to start the second plugin
+	ConflictFirst    = "first"    // the plugin that started first keeps the command
+	ConflictPriority = "priority" // the plugin with the higher priority owns the command
+)
+
+// CommandSeparator splits a fully-qualified command into plugin and command,
+// e.g. "weather:forecast"
+const CommandSeparator = ":"
+
+// SetCommandPolicy configures conflict resolution for bare commands.
+// pins maps a bare command to the plugin that should always own it.
+func (pm *PluginManager) SetCommandPolicy(policy string, pins map[string]string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	switch policy {
+	case ConflictReject, ConflictPriority:
+	default:
+		policy = ConflictFirst
+	}
+	pm.conflictPolicy = policy
+
+	pm.commandPins = make(map[string]string)
+	for cmd, name := range pins {
+		pm.commandPins[cmd] = name
+	}
+	for cmd := range pm.commandClaims {
+		pm.resolveCommandLocked(cmd)
+	}
+}
+
+// CommandPolicy returns the configured conflict policy
+func (pm *PluginManager) CommandPolicy() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.conflictPolicy
+}
+
+// PinCommand makes plugin the owner of a bare command regardless of policy.
+// The pin takes effect whenever that plugin is running.
+func (pm *PluginManager) PinCommand(cmd, plugin string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, exists := pm.plugins[plugin]; !exists && !pm.builtins[plugin] {
+		return fmt.Errorf("plugin %s not found", plugin)
+	}
+	pm.commandPins[cmd] = plugin
+	pm.resolveCommandLocked(cmd)
+	return nil
+}
+
+// UnpinCommand removes a pin and re-resolves the command by policy
+func (pm *PluginManager) UnpinCommand(cmd string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, pinned := pm.commandPins[cmd]; !pinned {
+		return fmt.Errorf("command %s is not pinned", cmd)
+	}
+	delete(pm.commandPins, cmd)
+	pm.resolveCommandLocked(cmd)
+	return nil
+}
+
+// CommandPins returns a copy of the current pins
+func (pm *PluginManager) CommandPins() map[string]string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make(map[string]string)
+	for cmd, name := range pm.commandPins {
+		result[cmd] = name
+	}
+	return result
+}
+
+// CommandConflict describes a bare command claimed by more than one running plugin
+type CommandConflict struct {
+	Command  string   `json:"command"`
+	Owner    string   `json:"owner"`
+	Plugins  []string `json:"plugins"`
+	PinnedTo string   `json:"pinned_to,omitempty"`
+}
+
+// CommandConflicts returns all bare commands claimed by several running plugins
+func (pm *PluginManager) CommandConflicts() []CommandConflict {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]CommandConflict, 0)
+	for cmd, claims := range pm.commandClaims {
+		if len(claims) < 2 {
+			continue
+		}
+		result = append(result, CommandConflict{
+			Command:  cmd,
+			Owner:    pm.commandIndex[cmd],
+			Plugins:  append([]string(nil), claims...),
+			PinnedTo: pm.commandPins[cmd],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Command < result[j].Command
+	})
+	return result
+}
+
+// splitQualifiedCommand splits "plugin:cmd" into its parts
+func splitQualifiedCommand(cmd string) (plugin, bare string, ok bool) {
+	plugin, bare, ok = strings.Cut(cmd, CommandSeparator)
+	if !ok || plugin == "" || bare == "" {
+		return "", cmd, false
+	}
+	return plugin, bare, true
+}
+
+// checkCommandConflictsLocked returns an error if starting meta would
+// introduce an unresolved conflict under the reject policy
+func (pm *PluginManager) checkCommandConflictsLocked(meta *PluginMeta) error {
+	if pm.conflictPolicy != ConflictReject {
+		return nil
+	}
+
+	for _, cmd := range meta.Commands {
+		if _, pinned := pm.commandPins[cmd]; pinned {
+			continue
+		}
+		for _, other := range pm.commandClaims[cmd] {
+			if other != meta.Name {
+				return fmt.Errorf("command /%s is already provided by plugin %s (conflict policy: reject); pin the command or use /%s%s%s",
+					cmd, other, meta.Name, CommandSeparator, cmd)
+			}
+		}
+	}
+	return nil
+}
+
+// claimCommandsLocked registers a started plugin's commands and resolves owners
+func (pm *PluginManager) claimCommandsLocked(meta *PluginMeta) {
+	for _, cmd := range meta.Commands {
+		claims := pm.commandClaims[cmd]
+		found := false
+		for _, name := range claims {
+			if name == meta.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pm.commandClaims[cmd] = append(claims, meta.Name)
+		}
+		pm.resolveCommandLocked(cmd)
+		pm.logShadowedLocked(cmd, meta.Name)
+	}
+}
+
+// ClaimBuiltinCommands enters a built-in plugin's commands into conflict
+// resolution alongside external plugins, so the same policy and pins
+// decide between them. Built-in plugins are part of the core and count as
+// started before any external plugin. Under the reject policy a command
+// another plugin already provides is skipped for the built-in.
+func (pm *PluginManager) ClaimBuiltinCommands(name string, commands []string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.builtins[name] = true
+	for _, cmd := range commands {
+		claims := pm.commandClaims[cmd]
+		if containsString(claims, name) {
+			continue
+		}
+		if _, pinned := pm.commandPins[cmd]; !pinned && pm.conflictPolicy == ConflictReject && len(claims) > 0 {
+			log.Printf("[PluginMgr] Command '%s' of built-in plugin '%s' skipped: already provided by plugin '%s' (conflict policy: reject)",
+				cmd, name, claims[0])
+			continue
+		}
+
+		// After the built-ins that claimed it before, ahead of external plugins
+		i := 0
+		for i < len(claims) && pm.builtins[claims[i]] {
+			i++
+		}
+		next := make([]string, 0, len(claims)+1)
+		next = append(next, claims[:i]...)
+		next = append(next, name)
+		pm.commandClaims[cmd] = append(next, claims[i:]...)
+
+		pm.resolveCommandLocked(cmd)
+		pm.logShadowedLocked(cmd, name)
+	}
+}
+
+// CommandOwner returns the plugin, built-in or external, a bare command
+// resolves to, or "" if no plugin declares it
+func (pm *PluginManager) CommandOwner(cmd string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.commandIndex[cmd]
+}
+
+// logShadowedLocked reports a command claim that lost to another plugin
+func (pm *PluginManager) logShadowedLocked(cmd, name string) {
+	if owner := pm.commandIndex[cmd]; owner != name {
+		log.Printf("[PluginMgr] Command '%s' of plugin '%s' is shadowed by plugin '%s', use '%s%s%s' to reach it",
+			cmd, name, owner, name, CommandSeparator, cmd)
+	}
+}
+
+// releaseCommandsLocked removes a plugin's claims. Commands it owned are
+// handed to the next claimant instead of being dropped.
+func (pm *PluginManager) releaseCommandsLocked(meta *PluginMeta) {
+	for _, cmd := range meta.Commands {
+		claims := pm.commandClaims[cmd]
+		for i, name := range claims {
+			if name == meta.Name {
+				claims = append(claims[:i], claims[i+1:]...)
+				break
+			}
+		}
+		if len(claims) == 0 {
+			delete(pm.commandClaims, cmd)
+		} else {
+			pm.commandClaims[cmd] = claims
+		}
+		pm.resolveCommandLocked(cmd)
+	}
+}
+
+// claimantPriorityLocked returns the priority of a command claimant.
+// Built-in plugins only have the configured override.
+func (pm *PluginManager) claimantPriorityLocked(name string) int {
+	if state, exists := pm.plugins[name]; exists && !pm.builtins[name] {
+		return pm.priorityLocked(state.Info)
+	}
+	return pm.priorityLocked(&PluginMeta{Name: name})
+}
+
+// swapCommandsLocked moves a plugin's claims from its old manifest to the
+// one it runs now. Commands both declare keep their place among the
+// claimants, so a plugin switching versions does not lose them to another.
+func (pm *PluginManager) swapCommandsLocked(old, next *PluginMeta) {
+	dropped := make([]string, 0)
+	for _, cmd := range old.Commands {
+		if !containsString(next.Commands, cmd) {
+			dropped = append(dropped, cmd)
+		}
+	}
+	pm.releaseCommandsLocked(&PluginMeta{Name: old.Name, Commands: dropped})
+	pm.claimCommandsLocked(next)
+}
+
+// resolveCommandLocked recomputes the owner of a bare command
+func (pm *PluginManager) resolveCommandLocked(cmd string) {
+	claims := pm.commandClaims[cmd]
+	if len(claims) == 0 {
+		delete(pm.commandIndex, cmd)
+		return
+	}
+
+	owner := claims[0]
+	if pm.conflictPolicy == ConflictPriority {
+		best := pm.claimantPriorityLocked(owner)
+		for _, name := range claims[1:] {
+			if p := pm.claimantPriorityLocked(name); p > best {
+				owner, best = name, p
+			}
+		}
+	}
+
+	if pinned, ok := pm.commandPins[cmd]; ok {
+		for _, name := range claims {
+			if name == pinned {
+				owner = pinned
+				break
+			}
+		}
+	}
+
+	if prev, exists := pm.commandIndex[cmd]; exists && prev != owner {
+		log.Printf("[PluginMgr] Command '%s' now handled by plugin '%s' (was '%s')", cmd, owner, prev)
+	}
+	pm.commandIndex[cmd] = owner
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
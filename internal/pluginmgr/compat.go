@@ -0,0 +1,245 @@
+package pluginmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// CoreVersion is the version of the bot core that plugin manifests are
+// checked against. Release builds set it with
+// -ldflags "-X github.com/DaikonSushi/bot-platform/internal/pluginmgr.CoreVersion=1.2.3".
+var CoreVersion = "1.0.0"
+
+// MinSDKVersion is the oldest plugin SDK the core works with. Plugins
+// built before the SDK reported its version are accepted as legacy.
+const MinSDKVersion = "1.0.0"
+
+// Protocol features the core and SDK announce in the handshake. A plugin
+// only relies on a feature both sides list.
+const (
+	FeatureStream       = "stream"       // protocol v2 single stream
+	FeatureEvents       = "events"       // event bus Publish and OnEvent
+	FeatureInvoke       = "invoke"       // plugin-to-plugin InvokePlugin
+	FeatureCapabilities = "capabilities" // capability manifests and enforcement
+	FeatureConfig       = "config"       // config schema, GetConfig and OnConfigChange
+	FeatureFilters      = "filters"      // handle_all_messages and message filters
+)
+
+// CoreFeatures lists the features this core supports
+var CoreFeatures = []string{FeatureStream, FeatureEvents, FeatureInvoke, FeatureCapabilities, FeatureConfig, FeatureFilters}
+
+// Environment variables telling a spawned plugin about the core. v2
+// plugins receive the same in the welcome frame.
+const (
+	EnvCoreVersion  = "BOT_CORE_VERSION"
+	EnvCoreFeatures = "BOT_CORE_FEATURES" // comma separated
+)
+
+// coreEnv describes the core to a spawned plugin
+func coreEnv() []string {
+	return []string{
+		EnvCoreVersion + "=" + CoreVersion,
+		EnvCoreFeatures + "=" + strings.Join(CoreFeatures, ","),
+	}
+}
+
+// errIncompatible marks plugins refused by the version handshake
+var errIncompatible = errors.New("incompatible plugin")
+
+// checkCompatible refuses plugins whose manifest excludes this core or
+// whose SDK is too old for it
+func checkCompatible(meta *PluginMeta) error {
+	if err := compatibility(meta); err != nil {
+		return fmt.Errorf("%w: %v", errIncompatible, err)
+	}
+	return nil
+}
+
+func compatibility(meta *PluginMeta) error {
+	if meta.CoreVersion != "" {
+		ok, err := versionInRange(CoreVersion, meta.CoreVersion)
+		if err != nil {
+			return fmt.Errorf("plugin %s declares an invalid core version range %q: %w", meta.Name, meta.CoreVersion, err)
+		}
+		if !ok {
+			return fmt.Errorf("plugin %s v%s requires core %s, but this core is %s",
+				meta.Name, meta.Version, meta.CoreVersion, CoreVersion)
+		}
+	}
+
+	if meta.SDKVersion != "" {
+		older, err := compareVersions(meta.SDKVersion, MinSDKVersion)
+		if err != nil {
+			return fmt.Errorf("plugin %s reports an invalid SDK version %q: %w", meta.Name, meta.SDKVersion, err)
+		}
+		if older < 0 {
+			return fmt.Errorf("plugin %s was built with SDK %s, but this core needs SDK %s or newer; rebuild the plugin",
+				meta.Name, meta.SDKVersion, MinSDKVersion)
+		}
+	}
+
+	if meta.ProtocolVersion > 1 && meta.SDKVersion != "" && !containsString(meta.Features, FeatureStream) {
+		return fmt.Errorf("plugin %s claims protocol v%d but its SDK lacks the %s feature", meta.Name, meta.ProtocolVersion, FeatureStream)
+	}
+
+	if err := validateSchema(meta.ConfigSchema); err != nil {
+		return fmt.Errorf("plugin %s declares an invalid config schema: %w", meta.Name, err)
+	}
+	return nil
+}
+
+// checkHandshake checks what a starting plugin reports about itself. The
+// binary may have been replaced since its manifest was installed.
+func checkHandshake(meta *PluginMeta, info *pb.PluginInfo) error {
+	if info == nil {
+		return fmt.Errorf("%w: plugin %s sent no info", errIncompatible, meta.Name)
+	}
+	if info.Name != meta.Name {
+		return fmt.Errorf("%w: plugin %s reports itself as %q", errIncompatible, meta.Name, info.Name)
+	}
+	if err := checkCompatible(metaFromInfo(info)); err != nil {
+		return err
+	}
+	if info.SdkVersion == "" {
+		log.Printf("[PluginMgr] Plugin %s was built with an SDK that predates the version handshake; rebuild it to get compatibility checks", meta.Name)
+	}
+	return nil
+}
+
+// handshakeUnary asks a v1 plugin about itself once it is up
+func (pm *PluginManager) handshakeUnary(ctx context.Context, meta *PluginMeta, client pb.PluginServiceClient) error {
+	infoCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	info, err := client.GetInfo(infoCtx, &pb.Empty{})
+	if err != nil {
+		return fmt.Errorf("failed to get plugin info: %w", err)
+	}
+	return checkHandshake(meta, info)
+}
+
+// handshakeStream checks the hello of a v2 plugin the core launched
+func (pm *PluginManager) handshakeStream(hello *pb.Hello) error {
+	pm.mu.RLock()
+	state, exists := pm.plugins[hello.PluginName]
+	pm.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("plugin %s is not installed", hello.PluginName)
+	}
+	return checkHandshake(state.Info, hello.Info)
+}
+
+// versionInRange reports whether version satisfies a range such as
+// ">=1.2.0, <2.0.0". Constraints are separated by commas or spaces and all
+// must hold. Supported operators are =, !=, >, >=, <, <=, ^ (same major)
+// and ~ (same minor); a bare version means =.
+func versionInRange(version, constraints string) (bool, error) {
+	fields := make([]string, 0)
+	for _, field := range strings.FieldsFunc(constraints, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		// Join an operator written apart from its version, as in ">= 1.2"
+		if n := len(fields); n > 0 && strings.TrimLeft(fields[n-1], "=!<>^~") == "" {
+			fields[n-1] += field
+			continue
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return false, fmt.Errorf("empty range")
+	}
+
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	for _, field := range fields {
+		rest := strings.TrimLeft(field, "=!<>^~")
+		op := field[:len(field)-len(rest)]
+		bound, err := parseVersion(rest)
+		if err != nil {
+			return false, err
+		}
+		cmp := compareParsed(v, bound)
+
+		var ok bool
+		switch op {
+		case "", "=", "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case "^":
+			ok = cmp >= 0 && v[0] == bound[0]
+		case "~":
+			ok = cmp >= 0 && v[0] == bound[0] && v[1] == bound[1]
+		default:
+			return false, fmt.Errorf("unknown operator %q", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// compareVersions compares two versions, returning -1, 0 or 1
+func compareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return compareParsed(va, vb), nil
+}
+
+// parseVersion parses "v1.2.3" into its numbers. Missing parts count as 0
+// and pre-release or build suffixes are ignored.
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func compareParsed(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,477 @@
+package pluginmgr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// Config field types a plugin may declare in its schema
+const (
+	ConfigString   = "string"
+	ConfigInt      = "int"
+	ConfigFloat    = "float"
+	ConfigBool     = "bool"
+	ConfigDuration = "duration" // Go duration, e.g. "1m30s"
+)
+
+// settingsDir holds the per-plugin config files, next to the metas
+const settingsDir = "settings"
+
+// secretMask replaces secret values shown to admins
+const secretMask = "******"
+
+// configPushTimeout bounds how long a plugin has to accept a new config
+const configPushTimeout = 5 * time.Second
+
+// ErrInvalidConfig marks config changes the plugin's schema rejects
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ConfigField declares one setting in a plugin's config schema
+type ConfigField struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type,omitempty"` // ConfigString if empty
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Required    bool     `json:"required,omitempty"` // the plugin is not started without it
+	Secret      bool     `json:"secret,omitempty"`   // masked when shown to admins
+	Options     []string `json:"options,omitempty"`  // allowed values, empty means any
+}
+
+// ConfigSetting is a schema field with its current value, as shown to
+// admins. Secret values are masked.
+type ConfigSetting struct {
+	ConfigField
+	Value string `json:"value"`
+	Set   bool   `json:"set"` // an admin set it, otherwise Value is the default
+}
+
+// PluginConfig is a plugin's configuration as shown to admins
+type PluginConfig struct {
+	Name     string          `json:"name"`
+	Revision uint64          `json:"revision"`
+	Settings []ConfigSetting `json:"settings"`
+	Missing  []string        `json:"missing,omitempty"` // required settings without a value
+
+	// Outcome of the last change for a running plugin: whether it took
+	// the new values without a restart, and why not
+	Applied    bool   `json:"applied,omitempty"`
+	ApplyError string `json:"apply_error,omitempty"`
+}
+
+// storedConfig is the config file the core keeps for a plugin
+type storedConfig struct {
+	Revision uint64            `json:"revision"`
+	Values   map[string]string `json:"values"`
+}
+
+// normalize checks a value against the field and returns it in the
+// canonical form of its type
+func (f ConfigField) normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch f.Type {
+	case "", ConfigString:
+	case ConfigInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be an integer", f.Key)
+		}
+		value = strconv.FormatInt(n, 10)
+	case ConfigFloat:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a number", f.Key)
+		}
+		value = strconv.FormatFloat(n, 'f', -1, 64)
+	case ConfigBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", f.Key)
+		}
+		value = strconv.FormatBool(b)
+	case ConfigDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a duration such as 30s or 5m", f.Key)
+		}
+		value = d.String()
+	default:
+		return "", fmt.Errorf("%s has unknown type %q", f.Key, f.Type)
+	}
+
+	if len(f.Options) > 0 && !containsString(f.Options, value) {
+		return "", fmt.Errorf("%s must be one of %s", f.Key, strings.Join(f.Options, ", "))
+	}
+	return value, nil
+}
+
+// validateSchema checks the config schema a plugin declares
+func validateSchema(fields []ConfigField) error {
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		if f.Key == "" || strings.ContainsAny(f.Key, " \t\n=") {
+			return fmt.Errorf("invalid config key %q", f.Key)
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("config key %s is declared twice", f.Key)
+		}
+		seen[f.Key] = true
+		switch f.Type {
+		case "", ConfigString, ConfigInt, ConfigFloat, ConfigBool, ConfigDuration:
+		default:
+			return fmt.Errorf("config key %s has unknown type %q", f.Key, f.Type)
+		}
+		if f.Default != "" {
+			if _, err := f.normalize(f.Default); err != nil {
+				return fmt.Errorf("invalid default: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// schemaField finds a key in a plugin's schema
+func schemaField(meta *PluginMeta, key string) (ConfigField, bool) {
+	for _, f := range meta.ConfigSchema {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return ConfigField{}, false
+}
+
+// configPath returns where a plugin's config is kept
+func (pm *PluginManager) configPath(name string) string {
+	return filepath.Join(pm.configDir, settingsDir, name+".json")
+}
+
+// loadConfig reads a plugin's config; a plugin never configured has none
+func (pm *PluginManager) loadConfig(name string) (*storedConfig, error) {
+	cfg := &storedConfig{Values: make(map[string]string)}
+	data, err := os.ReadFile(pm.configPath(name))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("corrupt config of plugin %s: %w", name, err)
+	}
+	if cfg.Values == nil {
+		cfg.Values = make(map[string]string)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes a plugin's config. It may hold secrets, so only the
+// core's user can read it.
+func (pm *PluginManager) saveConfig(name string, cfg *storedConfig) error {
+	path := pm.configPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// configMeta returns the manifest that declares a plugin's schema: the
+// running plugin's, which also covers remote plugins, or the installed one
+func (pm *PluginManager) configMeta(name string) (*PluginMeta, error) {
+	pm.mu.RLock()
+	state, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+	if exists && state.Info != nil && (state.Remote || state.Status == StatusRunning) {
+		return state.Info, nil
+	}
+	return pm.registry.get(name)
+}
+
+// effectiveConfig resolves every schema field to its value or default.
+// Values the schema no longer declares are left out.
+func effectiveConfig(meta *PluginMeta, cfg *storedConfig) map[string]string {
+	values := make(map[string]string, len(meta.ConfigSchema))
+	for _, f := range meta.ConfigSchema {
+		if v, ok := cfg.Values[f.Key]; ok {
+			values[f.Key] = v
+		} else if f.Default != "" {
+			values[f.Key], _ = f.normalize(f.Default)
+		}
+	}
+	return values
+}
+
+// missingConfig lists required settings that have no value
+func missingConfig(meta *PluginMeta, cfg *storedConfig) []string {
+	values := effectiveConfig(meta, cfg)
+	missing := make([]string, 0)
+	for _, f := range meta.ConfigSchema {
+		if f.Required && values[f.Key] == "" {
+			missing = append(missing, f.Key)
+		}
+	}
+	return missing
+}
+
+// configView builds what admins see of a plugin's config
+func configView(meta *PluginMeta, cfg *storedConfig) *PluginConfig {
+	values := effectiveConfig(meta, cfg)
+	view := &PluginConfig{
+		Name:     meta.Name,
+		Revision: cfg.Revision,
+		Settings: make([]ConfigSetting, 0, len(meta.ConfigSchema)),
+		Missing:  missingConfig(meta, cfg),
+	}
+	for _, f := range meta.ConfigSchema {
+		_, set := cfg.Values[f.Key]
+		setting := ConfigSetting{ConfigField: f, Value: values[f.Key], Set: set}
+		if f.Secret && setting.Value != "" {
+			setting.Value = secretMask
+			if setting.Default != "" {
+				setting.Default = secretMask
+			}
+		}
+		view.Settings = append(view.Settings, setting)
+	}
+	return view
+}
+
+// checkConfigLocked refuses to start a plugin whose required settings are
+// missing. The caller must hold pm.mu.
+func (pm *PluginManager) checkConfigLocked(meta *PluginMeta) error {
+	if len(meta.ConfigSchema) == 0 {
+		return nil
+	}
+	cfg, err := pm.loadConfig(meta.Name)
+	if err != nil {
+		return err
+	}
+	if missing := missingConfig(meta, cfg); len(missing) > 0 {
+		return fmt.Errorf("plugin %s needs %s to be configured; set it with /plugin config %s set <key> <value>",
+			meta.Name, strings.Join(missing, ", "), meta.Name)
+	}
+	return nil
+}
+
+// GetPluginConfig returns a plugin's settings with secrets masked
+func (pm *PluginManager) GetPluginConfig(name string) (*PluginConfig, error) {
+	meta, err := pm.configMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	cfg, err := pm.loadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return configView(meta, cfg), nil
+}
+
+// UpdatePluginConfig sets and unsets settings of a plugin. Every value is
+// checked against the plugin's schema before anything is saved. A running
+// plugin is sent the new config; one built before config pushes existed
+// picks it up when it is next started.
+func (pm *PluginManager) UpdatePluginConfig(ctx context.Context, name string, set map[string]string, unset []string) (*PluginConfig, error) {
+	meta, err := pm.configMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.ConfigSchema) == 0 {
+		return nil, fmt.Errorf("%w: plugin %s has no settings", ErrInvalidConfig, name)
+	}
+
+	normalized := make(map[string]string, len(set))
+	for key, value := range set {
+		f, ok := schemaField(meta, key)
+		if !ok {
+			return nil, fmt.Errorf("%w: plugin %s has no setting %s", ErrInvalidConfig, name, key)
+		}
+		if normalized[key], err = f.normalize(value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	}
+	for _, key := range unset {
+		if _, ok := schemaField(meta, key); !ok {
+			return nil, fmt.Errorf("%w: plugin %s has no setting %s", ErrInvalidConfig, name, key)
+		}
+	}
+
+	// One update at a time, without blocking the plugin's own GetConfig
+	// calls while it decides whether to accept
+	pm.configUpdateMu.Lock()
+	defer pm.configUpdateMu.Unlock()
+
+	pm.configMu.Lock()
+	cfg, err := pm.loadConfig(name)
+	pm.configMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &storedConfig{Revision: cfg.Revision, Values: make(map[string]string, len(cfg.Values))}
+	for key, value := range cfg.Values {
+		next.Values[key] = value
+	}
+	for key, value := range normalized {
+		next.Values[key] = value
+	}
+	for _, key := range unset {
+		delete(next.Values, key)
+	}
+	before := effectiveConfig(meta, cfg)
+	after := effectiveConfig(meta, next)
+
+	changed := make([]string, 0)
+	for _, f := range meta.ConfigSchema {
+		if before[f.Key] != after[f.Key] {
+			changed = append(changed, f.Key)
+		}
+	}
+	if len(changed) == 0 {
+		if err := pm.storeConfig(name, next); err != nil {
+			return nil, err
+		}
+		return configView(meta, next), nil
+	}
+	next.Revision++
+
+	// The new config is only saved once the plugin accepted it
+	err = pm.pushConfig(ctx, name, &pb.PluginConfig{
+		Values:   after,
+		Revision: next.Revision,
+		Changed:  changed,
+	}, &pb.PluginConfig{
+		Values:   before,
+		Revision: cfg.Revision,
+		Changed:  changed,
+	})
+	if errors.Is(err, errConfigRejected) {
+		log.Printf("[PluginMgr] Plugin %s rejected its new config: %v", name, err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if err := pm.storeConfig(name, next); err != nil {
+		return nil, err
+	}
+	view := configView(meta, next)
+	log.Printf("[PluginMgr] Changed config of plugin %s: %s (revision %d)", name, strings.Join(changed, ", "), next.Revision)
+
+	switch {
+	case err == nil:
+		view.Applied = true
+	case errors.Is(err, errNotRunning):
+		// Read when the plugin starts
+	default:
+		view.ApplyError = err.Error()
+		log.Printf("[PluginMgr] Plugin %s did not apply its new config: %v", name, err)
+	}
+	return view, nil
+}
+
+// storeConfig saves a plugin's config file
+func (pm *PluginManager) storeConfig(name string, cfg *storedConfig) error {
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+	return pm.saveConfig(name, cfg)
+}
+
+// errNotRunning is returned by pushConfig when there is nothing to push to
+var errNotRunning = errors.New("plugin is not running")
+
+// errConfigRejected is returned by pushConfig when the plugin refused the
+// new config
+var errConfigRejected = errors.New("plugin rejected the config")
+
+// pushConfig hands a running plugin its new config, on every replica. If
+// one of them rejects it, the ones that already accepted are handed the
+// previous config again.
+func (pm *PluginManager) pushConfig(ctx context.Context, name string, cfg, previous *pb.PluginConfig) error {
+	pm.mu.RLock()
+	state, exists := pm.plugins[name]
+	var instances []*PluginState
+	var features []string
+	if exists && state.Status == StatusRunning {
+		instances = state.instancesLocked()
+		features = state.Info.Features
+		for _, instance := range instances {
+			instance.track()
+		}
+	}
+	pm.mu.RUnlock()
+
+	if len(instances) == 0 {
+		return errNotRunning
+	}
+	defer func() {
+		for _, instance := range instances {
+			instance.untrack()
+		}
+	}()
+	if !containsString(features, FeatureConfig) {
+		return fmt.Errorf("plugin %s predates config reloads, restart it to apply the change", name)
+	}
+
+	for i, instance := range instances {
+		err := pushConfigTo(ctx, instance.Client, cfg)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, errConfigRejected) {
+			for _, accepted := range instances[:i] {
+				if err := pushConfigTo(ctx, accepted.Client, previous); err != nil {
+					log.Printf("[PluginMgr] Failed to restore the config of a replica of plugin %s: %v", name, err)
+				}
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// pushConfigTo hands one instance of a plugin its new config
+func pushConfigTo(ctx context.Context, client pb.PluginServiceClient, cfg *pb.PluginConfig) error {
+	pushCtx, cancel := context.WithTimeout(ctx, configPushTimeout)
+	defer cancel()
+	result, err := client.OnConfigChange(pushCtx, cfg)
+	if err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return fmt.Errorf("%w: %s", errConfigRejected, result.Error)
+	}
+	return nil
+}
+
+// ConfigFor returns the effective config of a plugin, secrets included.
+// It serves the plugin's own GetConfig calls.
+func (pm *PluginManager) ConfigFor(name string) (*pb.PluginConfig, error) {
+	meta, err := pm.configMeta(name)
+	if err != nil {
+		return nil, err
+	}
+	pm.configMu.Lock()
+	defer pm.configMu.Unlock()
+
+	cfg, err := pm.loadConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PluginConfig{
+		Values:   effectiveConfig(meta, cfg),
+		Revision: cfg.Revision,
+	}, nil
+}
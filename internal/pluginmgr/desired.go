@@ -0,0 +1,110 @@
+package pluginmgr
+
+import (
+	"context"
+	"log"
+	"sort"
+)
+
+// Desired states kept in PluginMeta.Desired. Every start or stop an admin
+// asks for is recorded, so the core can bring the plugins back after a
+// restart. Plugins without a recorded state follow plugin_manager.auto_start.
+const (
+	DesiredEnabled  = "enabled"
+	DesiredDisabled = "disabled"
+)
+
+// setDesired records whether an admin wants a plugin running. Remote
+// plugins have no meta and nothing to record.
+func (pm *PluginManager) setDesired(name, desired string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	_, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.Desired = desired
+		return nil
+	})
+	if err != nil {
+		if !isNotInstalled(err) {
+			log.Printf("[PluginMgr] Failed to record desired state of %s: %v", name, err)
+		}
+		return
+	}
+	if state, exists := pm.plugins[name]; exists && state.Info != nil && !state.Remote {
+		state.Info.Desired = desired
+	}
+}
+
+// DesiredState returns whether a plugin should be running and whether it
+// currently is not where it should be. Remote plugins come and go on their
+// own and have no desired state.
+func (pm *PluginManager) DesiredState(state *PluginState) (desired string, drift bool) {
+	if state.Remote || state.Info == nil {
+		return "", false
+	}
+
+	pm.mu.RLock()
+	desired = pm.desiredLocked(state.Info)
+	pm.mu.RUnlock()
+
+	if state.Transitional() {
+		return desired, false
+	}
+	running := state.Status == StatusRunning
+	return desired, running != (desired == DesiredEnabled)
+}
+
+// desiredLocked resolves a plugin's desired state, falling back to the
+// auto_start list. Caller must hold pm.mu.
+func (pm *PluginManager) desiredLocked(meta *PluginMeta) string {
+	if meta.Desired != "" {
+		return meta.Desired
+	}
+	if containsString(pm.autoStart, meta.Name) {
+		return DesiredEnabled
+	}
+	return DesiredDisabled
+}
+
+// ReconcilePlugins starts every installed plugin that should be running,
+// dependencies first. autoStart lists the plugins to start that no admin
+// has started or stopped yet. It is called once at startup, after
+// LoadInstalledPlugins.
+func (pm *PluginManager) ReconcilePlugins(ctx context.Context, autoStart []string) {
+	pm.mu.Lock()
+	pm.autoStart = append([]string(nil), autoStart...)
+
+	enabled := make([]string, 0)
+	stopped := make(map[string]bool)
+	for name, state := range pm.plugins {
+		if state.Remote || state.Info == nil {
+			continue
+		}
+		switch {
+		case pm.desiredLocked(state.Info) == DesiredEnabled:
+			enabled = append(enabled, name)
+		case state.Info.Desired == DesiredDisabled:
+			stopped[name] = true
+		}
+	}
+	installed := make(map[string]bool, len(pm.plugins))
+	for name := range pm.plugins {
+		installed[name] = true
+	}
+	pm.mu.Unlock()
+	sort.Strings(enabled)
+
+	for _, name := range autoStart {
+		switch {
+		case !installed[name]:
+			log.Printf("[PluginMgr] auto_start lists %s, which is not installed", name)
+		case stopped[name]:
+			log.Printf("[PluginMgr] Not starting %s from auto_start: an admin stopped it", name)
+		}
+	}
+	if len(enabled) > 0 {
+		log.Printf("[PluginMgr] Starting plugins that should be running: %v", enabled)
+	}
+
+	pm.startInOrder(ctx, enabled, stopped)
+}
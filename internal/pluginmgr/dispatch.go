@@ -0,0 +1,140 @@
+package pluginmgr
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// Dispatch modes for non-command messages
+const (
+	DispatchParallel = "parallel" // all subscribed plugins at once, results ignored
+	DispatchOrdered  = "ordered"  // by priority, first handled result stops propagation
+)
+
+// Dispatch roles reported for a plugin
+const (
+	RoleHandler  = "handler"  // takes part in ordered dispatch
+	RoleObserver = "observer" // always runs in parallel, never short-circuits
+)
+
+// messageCallTimeout bounds a single OnMessage call in ordered mode so one
+// slow plugin cannot hold up the plugins queued behind it indefinitely
+const messageCallTimeout = 10 * time.Second
+
+// maxPendingMessages bounds how many messages a plugin may have in delivery
+// at once
+const maxPendingMessages = 64
+
+// SetDispatchPolicy configures how non-command messages are delivered.
+// priorities overrides the priority declared in each plugin's manifest.
+func (pm *PluginManager) SetDispatchPolicy(mode string, priorities map[string]int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if mode != DispatchOrdered {
+		mode = DispatchParallel
+	}
+	pm.dispatchMode = mode
+	pm.priorities = priorities
+}
+
+// DispatchMode returns the configured message dispatch mode
+func (pm *PluginManager) DispatchMode() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.dispatchMode
+}
+
+// Priority returns the effective priority of a plugin: the configured
+// override if present, otherwise the value from its manifest
+func (pm *PluginManager) Priority(meta *PluginMeta) int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.priorityLocked(meta)
+}
+
+func (pm *PluginManager) priorityLocked(meta *PluginMeta) int {
+	if p, ok := pm.priorities[meta.Name]; ok {
+		return p
+	}
+	return meta.Priority
+}
+
+// Role returns the dispatch role of a plugin
+func (meta *PluginMeta) Role() string {
+	if meta.ObserveOnly {
+		return RoleObserver
+	}
+	return RoleHandler
+}
+
+// sortByPriority orders plugins by descending priority, ties broken by name.
+// Caller must hold pm.mu.
+func (pm *PluginManager) sortByPriority(plugins []*PluginState) {
+	sort.SliceStable(plugins, func(i, j int) bool {
+		pi, pj := pm.priorityLocked(plugins[i].Info), pm.priorityLocked(plugins[j].Info)
+		if pi != pj {
+			return pi > pj
+		}
+		return plugins[i].Info.Name < plugins[j].Info.Name
+	})
+}
+
+// notifyObservers delivers a message to observer plugins in parallel
+func (pm *PluginManager) notifyObservers(ctx context.Context, observers []*PluginState, event *pb.MessageEvent) {
+	for _, plugin := range observers {
+		pm.deliverMessage(ctx, plugin, event)
+	}
+}
+
+// deliverMessage calls a plugin's OnMessage in the background. A plugin
+// that already has maxPendingMessages in delivery misses the message, so a
+// slow plugin in a busy group cannot pile up goroutines. Either way it
+// ends the call DispatchMessage counted with track.
+func (pm *PluginManager) deliverMessage(ctx context.Context, p *PluginState, event *pb.MessageEvent) {
+	if p.pending.Add(1) > maxPendingMessages {
+		p.pending.Add(-1)
+		p.untrack()
+		if n := p.dropped.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("[PluginMgr] Plugin %s is falling behind, %d messages dropped", p.Info.Name, n)
+		}
+		return
+	}
+
+	go func() {
+		defer p.untrack()
+		defer p.pending.Add(-1)
+		if _, err := p.Client.OnMessage(ctx, event); err != nil {
+			log.Printf("[PluginMgr] Plugin %s OnMessage error: %v", p.Info.Name, err)
+		}
+	}()
+}
+
+// dispatchOrdered calls handler plugins one at a time in priority order and
+// stops at the first one that reports the message as handled
+func (pm *PluginManager) dispatchOrdered(ctx context.Context, handlers []*PluginState, event *pb.MessageEvent) bool {
+	defer func() {
+		for _, p := range handlers {
+			p.untrack()
+		}
+	}()
+
+	for _, p := range handlers {
+		callCtx, cancel := context.WithTimeout(ctx, messageCallTimeout)
+		result, err := p.Client.OnMessage(callCtx, event)
+		cancel()
+
+		if err != nil {
+			log.Printf("[PluginMgr] Plugin %s OnMessage error: %v", p.Info.Name, err)
+			continue
+		}
+		if result.Handled {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package pluginmgr
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// MessageFilter declares which non-command messages a plugin wants to receive.
+// It is read from the plugin manifest (--info output) and evaluated by the core
+// before any gRPC call is made.
+type MessageFilter struct {
+	Keywords     []string `json:"keywords,omitempty"`      // Substring triggers
+	Patterns     []string `json:"patterns,omitempty"`      // Regular expression triggers
+	GroupIDs     []int64  `json:"group_ids,omitempty"`     // Only these groups
+	UserIDs      []int64  `json:"user_ids,omitempty"`      // Only these senders
+	SegmentTypes []string `json:"segment_types,omitempty"` // Required segment types, e.g. "image"
+	MessageTypes []string `json:"message_types,omitempty"` // "private" and/or "group"
+}
+
+// messageMatcher is the compiled form of a plugin's message subscription
+type messageMatcher struct {
+	keywords     []string
+	patterns     []*regexp.Regexp
+	groups       map[int64]bool
+	users        map[int64]bool
+	segmentTypes []string
+	messageTypes map[string]bool
+}
+
+// compileMatcher builds a matcher from plugin metadata.
+// A plugin that neither sets handle_all_messages nor declares a filter
+// receives no messages at all (nil matcher). A declared filter always
+// applies, with or without handle_all_messages. Plugins built before the
+// SDK announced FeatureFilters never had a say and keep receiving every
+// message.
+func compileMatcher(meta *PluginMeta) (*messageMatcher, error) {
+	f := meta.MessageFilter
+	if f == nil {
+		if meta.HandleAllMessages {
+			return &messageMatcher{}, nil
+		}
+		if !containsString(meta.Features, FeatureFilters) {
+			log.Printf("[PluginMgr] Plugin %s predates message filters, delivering all messages; rebuild it with a current SDK to subscribe selectively", meta.Name)
+			return &messageMatcher{}, nil
+		}
+		return nil, nil
+	}
+
+	m := &messageMatcher{
+		keywords:     f.Keywords,
+		segmentTypes: f.SegmentTypes,
+	}
+
+	for _, expr := range f.Patterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message filter pattern %q: %w", expr, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+
+	if len(f.GroupIDs) > 0 {
+		m.groups = make(map[int64]bool)
+		for _, id := range f.GroupIDs {
+			m.groups[id] = true
+		}
+	}
+	if len(f.UserIDs) > 0 {
+		m.users = make(map[int64]bool)
+		for _, id := range f.UserIDs {
+			m.users[id] = true
+		}
+	}
+	if len(f.MessageTypes) > 0 {
+		m.messageTypes = make(map[string]bool)
+		for _, t := range f.MessageTypes {
+			m.messageTypes[t] = true
+		}
+	}
+
+	return m, nil
+}
+
+// hasTriggers reports whether the matcher has keyword or pattern triggers
+func (m *messageMatcher) hasTriggers() bool {
+	return len(m.keywords) > 0 || len(m.patterns) > 0
+}
+
+// Match reports whether the event should be delivered to the plugin
+func (m *messageMatcher) Match(event *pb.MessageEvent) bool {
+	if m == nil {
+		return false
+	}
+
+	if m.messageTypes != nil && !m.messageTypes[event.MessageType] {
+		return false
+	}
+	if m.groups != nil && !m.groups[event.GroupId] {
+		return false
+	}
+	if m.users != nil && !m.users[event.UserId] {
+		return false
+	}
+
+	for _, segType := range m.segmentTypes {
+		found := false
+		for _, seg := range event.Segments {
+			if seg.Type == segType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !m.hasTriggers() {
+		return true
+	}
+	for _, kw := range m.keywords {
+		if strings.Contains(event.RawMessage, kw) {
+			return true
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(event.RawMessage) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package pluginmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// Invoke error codes reported to the caller
+const (
+	InvokeNotFound         = "not_found"
+	InvokeUnavailable      = "unavailable"
+	InvokeUnimplemented    = "unimplemented"
+	InvokeDeadlineExceeded = "deadline_exceeded"
+	InvokeInternal         = "internal"
+)
+
+// Invoke call deadlines
+const (
+	defaultInvokeTimeout = 10 * time.Second
+	maxInvokeTimeout     = 60 * time.Second
+)
+
+// InvokeError is returned by Invoke with a machine-readable code
+type InvokeError struct {
+	Code    string
+	Message string
+}
+
+func (e *InvokeError) Error() string {
+	return e.Message
+}
+
+// Invoke calls a method exposed by a running plugin. The call is bounded
+// by timeout (clamped to the core limits) and by ctx.
+func (pm *PluginManager) Invoke(ctx context.Context, source, target, method string, payload []byte, timeout time.Duration) ([]byte, error) {
+	pm.mu.RLock()
+	state, exists := pm.plugins[target]
+	running := exists && state.Status == StatusRunning
+	replica := state
+	if running {
+		replica = state.pickLocked(nil)
+		replica.track()
+	}
+	pm.mu.RUnlock()
+
+	if !exists {
+		return nil, &InvokeError{InvokeNotFound, fmt.Sprintf("plugin %s not found", target)}
+	}
+	if !running {
+		return nil, &InvokeError{InvokeUnavailable, fmt.Sprintf("plugin %s is not running (status: %s)", target, state.Status)}
+	}
+	defer replica.untrack()
+	if !containsString(state.Info.Methods, method) {
+		return nil, &InvokeError{InvokeUnimplemented, fmt.Sprintf("plugin %s does not provide method %s", target, method)}
+	}
+
+	if timeout <= 0 {
+		timeout = defaultInvokeTimeout
+	}
+	if timeout > maxInvokeTimeout {
+		timeout = maxInvokeTimeout
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := replica.Client.OnInvoke(callCtx, &pb.InvokeRequest{
+		Target:    target,
+		Method:    method,
+		Payload:   payload,
+		Source:    source,
+		TimeoutMs: timeout.Milliseconds(),
+	})
+	if err != nil {
+		if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return nil, &InvokeError{InvokeDeadlineExceeded, fmt.Sprintf("call to %s.%s timed out after %s", target, method, timeout)}
+		}
+		return nil, &InvokeError{InvokeUnavailable, fmt.Sprintf("call to %s.%s failed: %v", target, method, err)}
+	}
+	if resp.Error != "" {
+		code := resp.Code
+		if code == "" {
+			code = InvokeInternal
+		}
+		return nil, &InvokeError{code, resp.Error}
+	}
+	return resp.Payload, nil
+}
+
+// orderByDependencies returns names with every installed dependency placed
+// before its dependents. Dependencies that are installed but not listed are
+// added. Cycles are reported and the affected plugins keep their order.
+// Caller must hold pm.mu.
+func (pm *PluginManager) orderByDependencies(names []string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int)
+	result := make([]string, 0, len(names))
+
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch marks[name] {
+		case done:
+			return
+		case visiting:
+			log.Printf("[PluginMgr] Dependency cycle detected: %v -> %s", path, name)
+			return
+		}
+		marks[name] = visiting
+
+		if state, exists := pm.plugins[name]; exists {
+			for _, dep := range state.Info.DependsOn {
+				if _, installed := pm.plugins[dep]; !installed {
+					log.Printf("[PluginMgr] Plugin %s depends on %s, which is not installed", name, dep)
+					continue
+				}
+				visit(dep, append(path, name))
+			}
+		}
+
+		marks[name] = done
+		result = append(result, name)
+	}
+
+	for _, name := range names {
+		visit(name, nil)
+	}
+	return result
+}
+
+// missingDependencies returns the dependencies of meta that are not running.
+// Caller must hold pm.mu.
+func (pm *PluginManager) missingDependencies(meta *PluginMeta) []string {
+	missing := make([]string, 0)
+	for _, dep := range meta.DependsOn {
+		if state, exists := pm.plugins[dep]; !exists || state.Status != StatusRunning {
+			missing = append(missing, dep)
+		}
+	}
+	return missing
+}
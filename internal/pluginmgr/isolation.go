@@ -0,0 +1,210 @@
+package pluginmgr
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits restricts what one plugin process may use. Zero means unlimited.
+// Memory, CPU and process limits are enforced with cgroups v2 when a
+// cgroup parent is configured; memory and process limits fall back to
+// rlimits otherwise, and CPU is not limited.
+type Limits struct {
+	MemoryMB   int `json:"memory_mb,omitempty"`   // cgroup memory.max, or RLIMIT_DATA without cgroups
+	CPUPercent int `json:"cpu_percent,omitempty"` // cgroup cpu.max, 100 is one full core
+	OpenFiles  int `json:"open_files,omitempty"`  // RLIMIT_NOFILE
+	Processes  int `json:"processes,omitempty"`   // cgroup pids.max, or RLIMIT_NPROC (counted per user) without cgroups
+	UID        int `json:"uid,omitempty"`         // run the plugin as this user, 0 keeps the core's user
+	GID        int `json:"gid,omitempty"`         // and group, 0 keeps the core's group
+}
+
+// Isolation configures the sandbox spawned plugins run in
+type Isolation struct {
+	DataDir        string            // each plugin gets DataDir/<name> as working and data directory
+	Limits         Limits            // applied to every plugin
+	Overrides      map[string]Limits // plugin name -> limits, non-zero fields replace the defaults
+	EnvPassthrough []string          // extra variables plugins inherit from the core's environment
+	CgroupParent   string            // delegated cgroup v2 directory, "" disables cgroups
+}
+
+// baseEnv lists the variables every plugin inherits from the core. Anything
+// else, such as the NapCat token, must be passed through explicitly.
+var baseEnv = []string{"PATH", "LANG", "LC_ALL", "TZ"}
+
+// Environment variables describing a plugin's sandbox
+const (
+	EnvPluginName = "BOT_PLUGIN_NAME"
+	EnvDataDir    = "BOT_PLUGIN_DATA_DIR"
+)
+
+// SetIsolation configures per-plugin directories, environment and limits.
+// A cgroup parent that cannot be used is logged and limits fall back to
+// rlimits.
+func (pm *PluginManager) SetIsolation(iso Isolation) error {
+	if iso.DataDir != "" {
+		dir, err := filepath.Abs(iso.DataDir)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create plugin data dir: %w", err)
+		}
+		iso.DataDir = dir
+	}
+
+	if iso.CgroupParent != "" {
+		if err := setupCgroups(iso.CgroupParent); err != nil {
+			log.Printf("[PluginMgr] Warning: cgroups unavailable, using rlimits only: %v", err)
+			iso.CgroupParent = ""
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.isolation = iso
+	return nil
+}
+
+// LimitsFor returns the limits a plugin runs with
+func (pm *PluginManager) LimitsFor(name string) Limits {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.limitsForLocked(name)
+}
+
+func (pm *PluginManager) limitsForLocked(name string) Limits {
+	limits := pm.isolation.Limits
+	o, ok := pm.isolation.Overrides[name]
+	if !ok {
+		return limits
+	}
+	if o.MemoryMB != 0 {
+		limits.MemoryMB = o.MemoryMB
+	}
+	if o.CPUPercent != 0 {
+		limits.CPUPercent = o.CPUPercent
+	}
+	if o.OpenFiles != 0 {
+		limits.OpenFiles = o.OpenFiles
+	}
+	if o.Processes != 0 {
+		limits.Processes = o.Processes
+	}
+	if o.UID != 0 {
+		limits.UID = o.UID
+	}
+	if o.GID != 0 {
+		limits.GID = o.GID
+	}
+	return limits
+}
+
+// newSandbox prepares the directory, environment and limits of one
+// launch. extraEnv carries the launch's BOT_PLUGIN_* variables.
+func (pm *PluginManager) newSandbox(name, instance string, extraEnv []string) (*sandbox, error) {
+	pm.mu.RLock()
+	iso := pm.isolation
+	limits := pm.limitsForLocked(name)
+	transport := pm.transport
+	pm.mu.RUnlock()
+
+	if limits.UID != 0 && transport == TransportUnix && limits.UID != os.Getuid() {
+		return nil, fmt.Errorf("plugin %s runs as uid %d, which cannot reach the core over the unix transport", name, limits.UID)
+	}
+
+	sb := &sandbox{name: name, limits: limits}
+
+	env := make([]string, 0, len(baseEnv)+len(iso.EnvPassthrough)+len(extraEnv)+4)
+	for _, key := range append(baseEnv, iso.EnvPassthrough...) {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	env = append(env, EnvPluginName+"="+name)
+
+	if iso.DataDir != "" {
+		dir := filepath.Join(iso.DataDir, name)
+		if err := os.MkdirAll(filepath.Join(dir, "tmp"), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create data dir of plugin %s: %w", name, err)
+		}
+		if limits.UID != 0 || limits.GID != 0 {
+			if err := chownTree(dir, limits.UID, limits.GID); err != nil {
+				return nil, fmt.Errorf("failed to hand data dir to uid %d: %w", limits.UID, err)
+			}
+		}
+		sb.dir = dir
+		env = append(env,
+			EnvDataDir+"="+dir,
+			"HOME="+dir,
+			"TMPDIR="+filepath.Join(dir, "tmp"),
+		)
+	}
+	sb.env = append(env, extraEnv...)
+
+	if iso.CgroupParent != "" {
+		sb.cgroup = filepath.Join(iso.CgroupParent, instanceName(name, instance))
+	}
+	return sb, nil
+}
+
+// updateViolations refreshes the limits a running plugin has run into and
+// logs new ones
+func (pm *PluginManager) updateViolations(state *PluginState) {
+	if state.proc == nil {
+		return
+	}
+	violations := state.proc.sb.violations()
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if strings.Join(violations, ", ") == strings.Join(state.Violations, ", ") {
+		return
+	}
+	state.Violations = violations
+	if len(violations) > 0 {
+		log.Printf("[PluginMgr] Plugin %s hit its limits: %s", state.Info.Name, strings.Join(violations, ", "))
+	}
+}
+
+// chownTree hands a plugin's directory to the user it runs as
+func chownTree(dir string, uid, gid int) error {
+	if uid == 0 {
+		uid = os.Getuid()
+	}
+	if gid == 0 {
+		gid = os.Getgid()
+	}
+	return filepath.Walk(dir, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// String summarizes the limits for admins
+func (l Limits) String() string {
+	parts := make([]string, 0)
+	if l.MemoryMB > 0 {
+		parts = append(parts, fmt.Sprintf("memory %dMB", l.MemoryMB))
+	}
+	if l.CPUPercent > 0 {
+		parts = append(parts, fmt.Sprintf("cpu %d%%", l.CPUPercent))
+	}
+	if l.OpenFiles > 0 {
+		parts = append(parts, fmt.Sprintf("files %d", l.OpenFiles))
+	}
+	if l.Processes > 0 {
+		parts = append(parts, fmt.Sprintf("processes %d", l.Processes))
+	}
+	if l.UID != 0 || l.GID != 0 {
+		parts = append(parts, fmt.Sprintf("uid %d gid %d", l.UID, l.GID))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,288 @@
+//go:build linux
+
+package pluginmgr
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroupControllers are enabled for plugin cgroups under the parent
+var cgroupControllers = []string{"memory", "cpu", "pids"}
+
+// cpuPeriod is the cpu.max period in microseconds
+const cpuPeriod = 100000
+
+// sandbox applies a plugin's limits to its process
+type sandbox struct {
+	name   string
+	limits Limits
+	dir    string   // working and data directory, "" if none
+	env    []string // scrubbed environment
+	cgroup string   // cgroup directory, "" without cgroups
+
+	mu       sync.Mutex
+	cgroupFD *os.File // open cgroup directory the process is cloned into, until it started
+	final    []string // violations recorded when the process exited
+}
+
+// execWrapperArg starts the core binary as the exec wrapper, see
+// RunExecWrapper
+const execWrapperArg = "__plugin-exec"
+
+// setupCgroups checks that parent is a writable cgroup v2 directory and
+// enables the controllers plugin cgroups need. The parent must not
+// contain processes itself, e.g. a systemd unit's delegated subtree.
+func setupCgroups(parent string) error {
+	data, err := os.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory: %w", parent, err)
+	}
+	available := strings.Fields(string(data))
+
+	enable := make([]string, 0, len(cgroupControllers))
+	for _, c := range cgroupControllers {
+		if !containsString(available, c) {
+			return fmt.Errorf("controller %s is not delegated to %s", c, parent)
+		}
+		enable = append(enable, "+"+c)
+	}
+
+	control := filepath.Join(parent, "cgroup.subtree_control")
+	if err := os.WriteFile(control, []byte(strings.Join(enable, " ")), 0644); err != nil {
+		return fmt.Errorf("failed to enable controllers in %s: %w", parent, err)
+	}
+	return nil
+}
+
+// configure sets up the working directory, environment, user, rlimits
+// and cgroup of a command before it starts. The process is cloned straight
+// into its cgroup and rlimits are applied by the exec wrapper, so the
+// plugin never runs outside its limits.
+func (sb *sandbox) configure(cmd *exec.Cmd) error {
+	cmd.Env = sb.env
+	if sb.dir != "" {
+		cmd.Dir = sb.dir
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+
+	if sb.limits.UID != 0 || sb.limits.GID != 0 {
+		uid, gid := sb.limits.UID, sb.limits.GID
+		if uid == 0 {
+			uid = os.Getuid()
+		}
+		if gid == 0 {
+			gid = os.Getgid()
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), NoSetGroups: true}
+	}
+
+	if rlimits := sb.rlimits(); rlimits != "" {
+		self, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to find the exec wrapper: %w", err)
+		}
+		cmd.Args = append([]string{self, execWrapperArg, rlimits, cmd.Path}, cmd.Args...)
+		cmd.Path = self
+	}
+
+	if sb.cgroup == "" {
+		if sb.limits.CPUPercent > 0 {
+			log.Printf("[PluginMgr] Warning: cpu limit of plugin %s needs cgroups and is not applied", sb.name)
+		}
+		return nil
+	}
+
+	// A leftover cgroup from a previous launch is reused if it is empty
+	if err := os.Mkdir(sb.cgroup, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	settings := map[string]string{
+		"memory.max": "max",
+		"cpu.max":    "max",
+		"pids.max":   "max",
+	}
+	if sb.limits.MemoryMB > 0 {
+		settings["memory.max"] = strconv.FormatInt(int64(sb.limits.MemoryMB)<<20, 10)
+	}
+	if sb.limits.CPUPercent > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", sb.limits.CPUPercent*cpuPeriod/100, cpuPeriod)
+	}
+	if sb.limits.Processes > 0 {
+		settings["pids.max"] = strconv.Itoa(sb.limits.Processes)
+	}
+	for file, value := range settings {
+		if err := os.WriteFile(filepath.Join(sb.cgroup, file), []byte(value), 0644); err != nil {
+			return fmt.Errorf("failed to set %s: %w", file, err)
+		}
+	}
+
+	fd, err := os.Open(sb.cgroup)
+	if err != nil {
+		return fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	sb.mu.Lock()
+	sb.cgroupFD = fd
+	sb.mu.Unlock()
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+	return nil
+}
+
+// rlimits encodes the limits the exec wrapper applies, e.g.
+// "nofile=1024,nproc=32"; "" if there are none. Memory and processes are
+// limited by the cgroup when there is one.
+func (sb *sandbox) rlimits() string {
+	limits := make([]string, 0, 3)
+	if sb.limits.OpenFiles > 0 {
+		limits = append(limits, "nofile="+strconv.Itoa(sb.limits.OpenFiles))
+	}
+	if sb.cgroup == "" && sb.limits.MemoryMB > 0 {
+		limits = append(limits, "data="+strconv.FormatInt(int64(sb.limits.MemoryMB)<<20, 10))
+	}
+	if sb.cgroup == "" && sb.limits.Processes > 0 {
+		limits = append(limits, "nproc="+strconv.Itoa(sb.limits.Processes))
+	}
+	return strings.Join(limits, ",")
+}
+
+// started releases what was only needed to start the process
+func (sb *sandbox) started(pid int) error {
+	sb.closeCgroupFD()
+	return nil
+}
+
+func (sb *sandbox) closeCgroupFD() {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if sb.cgroupFD != nil {
+		sb.cgroupFD.Close()
+		sb.cgroupFD = nil
+	}
+}
+
+// RunExecWrapper makes the process act as the exec wrapper when the core
+// binary was started as one, and does not return in that case. The
+// wrapper runs as the plugin's user, sets the rlimits it was given on
+// itself and execs the plugin, which inherits them. It must be called
+// first thing in main.
+func RunExecWrapper() {
+	if len(os.Args) < 5 || os.Args[1] != execWrapperArg {
+		return
+	}
+	rlimits, path, argv := os.Args[2], os.Args[3], os.Args[4:]
+
+	if err := applyRlimits(rlimits); err != nil {
+		fmt.Fprintf(os.Stderr, "plugin exec wrapper: %v\n", err)
+		os.Exit(126)
+	}
+	err := syscall.Exec(path, argv, os.Environ())
+	fmt.Fprintf(os.Stderr, "plugin exec wrapper: failed to exec %s: %v\n", path, err)
+	os.Exit(127)
+}
+
+// rlimitResources maps the names used by sandbox.rlimits to resources
+var rlimitResources = map[string]int{
+	"nofile": unix.RLIMIT_NOFILE,
+	"data":   unix.RLIMIT_DATA,
+	"nproc":  unix.RLIMIT_NPROC,
+}
+
+func applyRlimits(rlimits string) error {
+	for _, limit := range strings.Split(rlimits, ",") {
+		name, raw, _ := strings.Cut(limit, "=")
+		resource, ok := rlimitResources[name]
+		if !ok {
+			return fmt.Errorf("unknown rlimit %q", name)
+		}
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s limit %q", name, raw)
+		}
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("failed to limit %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// exited records the final violations and removes the cgroup
+func (sb *sandbox) exited() {
+	sb.closeCgroupFD()
+	if sb.cgroup == "" {
+		return
+	}
+	final := sb.readViolations()
+
+	sb.mu.Lock()
+	sb.final = final
+	sb.mu.Unlock()
+
+	if err := os.Remove(sb.cgroup); err != nil && !os.IsNotExist(err) {
+		log.Printf("[PluginMgr] Failed to remove cgroup of plugin %s: %v", sb.name, err)
+	}
+}
+
+// violations describes how often the plugin ran into its limits
+func (sb *sandbox) violations() []string {
+	sb.mu.Lock()
+	final := sb.final
+	sb.mu.Unlock()
+	if final != nil {
+		return final
+	}
+	return sb.readViolations()
+}
+
+func (sb *sandbox) readViolations() []string {
+	result := make([]string, 0)
+	if sb.cgroup == "" {
+		return result
+	}
+
+	memory := readCgroupStats(filepath.Join(sb.cgroup, "memory.events"))
+	if memory["oom_kill"] > 0 {
+		result = append(result, fmt.Sprintf("memory limit: %d OOM kills", memory["oom_kill"]))
+	} else if memory["max"] > 0 {
+		result = append(result, fmt.Sprintf("memory limit reached %d times", memory["max"]))
+	}
+	if pids := readCgroupStats(filepath.Join(sb.cgroup, "pids.events")); pids["max"] > 0 {
+		result = append(result, fmt.Sprintf("process limit reached %d times", pids["max"]))
+	}
+	if cpu := readCgroupStats(filepath.Join(sb.cgroup, "cpu.stat")); cpu["nr_throttled"] > 0 {
+		result = append(result, fmt.Sprintf("cpu throttled %d times", cpu["nr_throttled"]))
+	}
+	return result
+}
+
+// readCgroupStats parses a flat-keyed cgroup file such as memory.events
+func readCgroupStats(path string) map[string]uint64 {
+	stats := make(map[string]uint64)
+	f, err := os.Open(path)
+	if err != nil {
+		return stats
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stats[fields[0]] = n
+		}
+	}
+	return stats
+}
@@ -0,0 +1,51 @@
+//go:build !linux
+
+package pluginmgr
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+)
+
+// sandbox applies a plugin's limits to its process. Outside Linux only the
+// working directory and environment are isolated.
+type sandbox struct {
+	name   string
+	limits Limits
+	dir    string
+	env    []string
+	cgroup string
+}
+
+func setupCgroups(parent string) error {
+	return fmt.Errorf("cgroups are only supported on Linux")
+}
+
+func (sb *sandbox) configure(cmd *exec.Cmd) error {
+	cmd.Env = sb.env
+	if sb.dir != "" {
+		cmd.Dir = sb.dir
+	}
+	if sb.limits.UID != 0 || sb.limits.GID != 0 {
+		return fmt.Errorf("running plugins as another user is only supported on Linux")
+	}
+	return nil
+}
+
+func (sb *sandbox) started(pid int) error {
+	if sb.limits != (Limits{}) {
+		log.Printf("[PluginMgr] Warning: resource limits of plugin %s are only enforced on Linux", sb.name)
+	}
+	return nil
+}
+
+func (sb *sandbox) exited() {}
+
+// RunExecWrapper is only needed on Linux, where plugin rlimits are applied
+// by re-executing the core binary
+func RunExecWrapper() {}
+
+func (sb *sandbox) violations() []string {
+	return []string{}
+}
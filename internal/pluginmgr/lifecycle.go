@@ -0,0 +1,90 @@
+package pluginmgr
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Plugin lifecycle states kept in PluginState.Status.
+//
+//	installing -> stopped -> starting -> running -> stopping -> stopped
+//	                            |           |
+//	                            v           v
+//	                          error <-> crashloop
+//
+// Only running plugins receive messages, commands and events. Transitions
+// of one plugin are serialized by its lifecycle lock and never hold pm.mu
+// while a process is spawned, dialed or shut down.
+const (
+	StatusInstalling = "installing"
+	StatusStarting   = "starting"
+	StatusRunning    = "running"
+	StatusStopping   = "stopping"
+	StatusStopped    = "stopped"
+	StatusError      = "error"
+	StatusCrashloop  = "crashloop" // kept crashing and is no longer restarted
+)
+
+// Transitional reports whether the plugin is between two stable states
+func (s *PluginState) Transitional() bool {
+	switch s.Status {
+	case StatusInstalling, StatusStarting, StatusStopping:
+		return true
+	}
+	return false
+}
+
+// shutdownTimeout bounds how long Shutdown waits for plugins in the middle
+// of a transition before their processes are killed
+const shutdownTimeout = 15 * time.Second
+
+// lifecyclePoll is how often a waiting Shutdown retries a busy lock
+const lifecyclePoll = 50 * time.Millisecond
+
+// lockLifecycle takes the lifecycle lock of a plugin. A start, stop,
+// install or uninstall already in progress makes it fail right away
+// instead of queueing behind a slow plugin.
+func (pm *PluginManager) lockLifecycle(name string) (func(), error) {
+	pm.mu.Lock()
+	if pm.shuttingDown {
+		pm.mu.Unlock()
+		return nil, fmt.Errorf("plugin manager is shutting down")
+	}
+	lock := pm.lifecycleLockLocked(name)
+	status := StatusInstalling
+	if state, exists := pm.plugins[name]; exists {
+		status = state.Status
+	}
+	pm.mu.Unlock()
+
+	if !lock.TryLock() {
+		return nil, fmt.Errorf("plugin %s is busy (%s), try again later", name, status)
+	}
+	return lock.Unlock, nil
+}
+
+// waitLifecycle takes the lifecycle lock of a plugin, waiting for a
+// transition in progress to finish. It gives up at deadline.
+func (pm *PluginManager) waitLifecycle(name string, deadline time.Time) (func(), bool) {
+	pm.mu.Lock()
+	lock := pm.lifecycleLockLocked(name)
+	pm.mu.Unlock()
+
+	for !lock.TryLock() {
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+		time.Sleep(lifecyclePoll)
+	}
+	return lock.Unlock, true
+}
+
+func (pm *PluginManager) lifecycleLockLocked(name string) *sync.Mutex {
+	lock, ok := pm.lifecycle[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		pm.lifecycle[name] = lock
+	}
+	return lock
+}
@@ -0,0 +1,309 @@
+package pluginmgr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Log streams a captured line can come from
+const (
+	LogStdout = "stdout" // process standard output
+	LogStderr = "stderr" // process standard error
+	LogSDK    = "sdk"    // BotService.Log calls, Level is set
+)
+
+// Default log capture limits, see SetLogging
+const (
+	defaultLogBufferLines = 500
+	defaultLogMaxSize     = 10 << 20
+	defaultLogMaxFiles    = 3
+)
+
+// LogEntry is one captured line of plugin output
+type LogEntry struct {
+	Time   time.Time `json:"time"`
+	Plugin string    `json:"plugin"`
+	Stream string    `json:"stream"`
+	Level  string    `json:"level,omitempty"`
+	Line   string    `json:"line"`
+}
+
+// String formats the entry the way it is written to the log file
+func (e LogEntry) String() string {
+	tag := e.Stream
+	if e.Level != "" {
+		tag = e.Level
+	}
+	return fmt.Sprintf("%s [%s] %s", e.Time.Format(time.RFC3339), tag, e.Line)
+}
+
+// pluginLog keeps the recent output of one plugin in memory and on disk
+type pluginLog struct {
+	name string
+	path string // empty when file capture is off
+
+	mu       sync.Mutex
+	ring     []LogEntry
+	next     int // ring index the next entry goes to
+	full     bool
+	file     *os.File
+	size     int64
+	maxSize  int64
+	maxFiles int
+	watchers map[chan LogEntry]struct{}
+}
+
+// SetLogging configures where plugin output is captured. dir holds one
+// rotating file per plugin (empty keeps logs in memory only), bufferLines
+// sizes the in-memory ring, and files are rotated at maxSize bytes with
+// maxFiles old files kept. Zero values select the defaults.
+func (pm *PluginManager) SetLogging(dir string, bufferLines int, maxSize int64, maxFiles int) error {
+	if dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(abs, 0750); err != nil {
+			return fmt.Errorf("failed to create log dir: %w", err)
+		}
+		dir = abs
+	}
+	if bufferLines <= 0 {
+		bufferLines = defaultLogBufferLines
+	}
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultLogMaxFiles
+	}
+
+	pm.logMu.Lock()
+	defer pm.logMu.Unlock()
+	pm.logDir = dir
+	pm.logBufferLines = bufferLines
+	pm.logMaxSize = maxSize
+	pm.logMaxFiles = maxFiles
+	return nil
+}
+
+// pluginLogFor returns the capture of a plugin, creating it on first use
+func (pm *PluginManager) pluginLogFor(name string) *pluginLog {
+	pm.logMu.Lock()
+	defer pm.logMu.Unlock()
+
+	if l, ok := pm.logs[name]; ok {
+		return l
+	}
+
+	l := &pluginLog{
+		name:     name,
+		ring:     make([]LogEntry, pm.logBufferLines),
+		maxSize:  pm.logMaxSize,
+		maxFiles: pm.logMaxFiles,
+		watchers: make(map[chan LogEntry]struct{}),
+	}
+	if pm.logDir != "" {
+		l.path = filepath.Join(pm.logDir, name+".log")
+	}
+	pm.logs[name] = l
+	return l
+}
+
+// AppendLog records a line logged by a plugin through the SDK
+func (pm *PluginManager) AppendLog(name, level, line string) {
+	pm.pluginLogFor(name).append(LogEntry{
+		Time:   time.Now(),
+		Plugin: name,
+		Stream: LogSDK,
+		Level:  level,
+		Line:   line,
+	})
+}
+
+// Logs returns up to n of the most recent lines captured from a plugin
+func (pm *PluginManager) Logs(name string, n int) ([]LogEntry, error) {
+	pm.mu.RLock()
+	_, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+
+	pm.logMu.Lock()
+	l, ok := pm.logs[name]
+	pm.logMu.Unlock()
+	if !ok {
+		if !exists {
+			return nil, fmt.Errorf("plugin %s not found", name)
+		}
+		return []LogEntry{}, nil
+	}
+	return l.tail(n), nil
+}
+
+// FollowLogs returns up to n recent lines of a plugin and a channel that
+// receives every line captured afterwards. Call stop to unsubscribe.
+// A follower that falls behind misses lines rather than blocking the plugin.
+func (pm *PluginManager) FollowLogs(name string, n int) ([]LogEntry, <-chan LogEntry, func()) {
+	l := pm.pluginLogFor(name)
+	ch := make(chan LogEntry, 64)
+
+	l.mu.Lock()
+	recent := l.tailLocked(n)
+	l.watchers[ch] = struct{}{}
+	l.mu.Unlock()
+
+	stop := func() {
+		l.mu.Lock()
+		delete(l.watchers, ch)
+		l.mu.Unlock()
+	}
+	return recent, ch, stop
+}
+
+// logWriter returns a writer that captures a plugin's process output
+func (pm *PluginManager) logWriter(name, stream string) io.Writer {
+	return &lineWriter{log: pm.pluginLogFor(name), stream: stream}
+}
+
+// closeLogs closes all open log files
+func (pm *PluginManager) closeLogs() {
+	pm.logMu.Lock()
+	defer pm.logMu.Unlock()
+	for _, l := range pm.logs {
+		l.mu.Lock()
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *pluginLog) append(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ring[l.next] = entry
+	l.next = (l.next + 1) % len(l.ring)
+	if l.next == 0 {
+		l.full = true
+	}
+
+	if l.path != "" {
+		if err := l.writeLocked(entry.String() + "\n"); err != nil {
+			log.Printf("[PluginMgr] Failed to write log of plugin %s: %v", l.name, err)
+		}
+	}
+
+	for ch := range l.watchers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+func (l *pluginLog) tail(n int) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tailLocked(n)
+}
+
+func (l *pluginLog) tailLocked(n int) []LogEntry {
+	count := l.next
+	if l.full {
+		count = len(l.ring)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	entries := make([]LogEntry, 0, n)
+	for i := l.next - n; i < l.next; i++ {
+		entries = append(entries, l.ring[(i+len(l.ring))%len(l.ring)])
+	}
+	return entries
+}
+
+// writeLocked appends to the log file, rotating it when it grows too large
+func (l *pluginLog) writeLocked(line string) error {
+	if l.file != nil && l.size+int64(len(line)) > l.maxSize {
+		l.file.Close()
+		l.file = nil
+		l.rotateLocked()
+	}
+
+	if l.file == nil {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return err
+		}
+		l.file = f
+		l.size = info.Size()
+	}
+
+	n, err := l.file.WriteString(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotateLocked shifts name.log to name.log.1, name.log.1 to name.log.2 and so on
+func (l *pluginLog) rotateLocked() {
+	os.Remove(fmt.Sprintf("%s.%d", l.path, l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	os.Rename(l.path, l.path+".1")
+}
+
+// lineWriter splits process output into log entries
+type lineWriter struct {
+	log    *pluginLog
+	stream string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// maxLogLine caps how much of an unterminated line is buffered
+const maxLogLine = 64 << 10
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= maxLogLine {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) emit(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	w.log.append(LogEntry{
+		Time:   time.Now(),
+		Plugin: w.log.name,
+		Stream: w.stream,
+		Line:   string(line),
+	})
+}
@@ -4,18 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/DaikonSushi/bot-platform/api/proto"
+	"github.com/DaikonSushi/bot-platform/internal/eventbus"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -23,14 +22,30 @@ import (
 
 // PluginState represents the runtime state of a plugin
 type PluginState struct {
-	Info      *PluginMeta
-	Process   *os.Process
-	Client    pb.PluginServiceClient
-	Conn      *grpc.ClientConn
-	Port      int
-	Status    string // "running", "stopped", "error"
-	StartedAt time.Time
-	LastError string
+	Info       *PluginMeta
+	Process    *os.Process
+	Client     pb.PluginServiceClient
+	Conn       *grpc.ClientConn
+	Port       int
+	Socket     string // unix socket of a v1 plugin, "" over TCP
+	Protocol   uint32 // plugin protocol version in use
+	Status     string // StatusRunning, StatusStopped, ... see lifecycle.go
+	StartedAt  time.Time
+	LastError  string          // why the plugin last stopped, including its exit code or signal
+	Restarts   int             // automatic restarts since an admin last started it
+	Violations []string        // resource limits the current process ran into
+	Remote     bool            // registered itself over gRPC instead of being spawned
+	matcher    *messageMatcher // compiled message subscription, nil means none
+	secret     string          // per-launch secret the plugin authenticates with
+	proc       *child          // spawned process, nil for remote plugins
+	restarts   []time.Time     // automatic restarts within the restart window
+	instance   string          // tells side-by-side processes apart, see instanceName
+	inflight   atomic.Int64    // calls in progress, see track
+	replicas   []*PluginState  // instances running next to this one, see replicas.go
+	slot       int             // where sticky calls go among the replicas, see pickLocked
+	rotation   atomic.Uint32   // spreads calls over equally busy replicas
+	pending    atomic.Int32    // messages being delivered, see deliverMessage
+	dropped    atomic.Int64    // messages skipped because delivery fell behind
 }
 
 // PluginMeta represents plugin metadata
@@ -40,8 +55,60 @@ type PluginMeta struct {
 	Description string   `json:"description"`
 	Author      string   `json:"author"`
 	Commands    []string `json:"commands"`
-	RepoURL     string   `json:"repo_url"`    // GitHub repo URL
-	BinaryName  string   `json:"binary_name"` // Binary file name
+	RepoURL     string   `json:"repo_url"`           // GitHub repo URL
+	Source      string   `json:"source,omitempty"`   // What the plugin was installed from: repo URL, URL or path
+	BinaryName  string   `json:"binary_name"`        // Binary file name
+	Path        string   `json:"path,omitempty"`     // Binary path in the version store, relative to the plugin dir
+	Release     string   `json:"release,omitempty"`  // Release tag the binary came from
+	Verified    string   `json:"verified,omitempty"` // How the download was verified, see verify.go
+	SHA256      string   `json:"sha256,omitempty"`   // Checksum of the binary, checked before it is started
+
+	// Version store: the version an upgrade replaced, for rollback, and
+	// whether an admin holds the plugin at its current version
+	PreviousVersion string `json:"previous_version,omitempty"`
+	Held            bool   `json:"held,omitempty"`
+
+	// Whether an admin last started or stopped the plugin, see desired.go
+	Desired string `json:"desired,omitempty"`
+
+	// How many instances of the plugin run side by side and whether calls
+	// about one group or user stay with one of them, see replicas.go
+	Replicas int    `json:"replicas,omitempty"`
+	Sticky   string `json:"sticky,omitempty"`
+
+	// Update checks: how far the plugin may be upgraded on its own and
+	// what the last check found, see updates.go
+	AutoUpdate string        `json:"auto_update,omitempty"`
+	Update     *UpdateStatus `json:"update,omitempty"`
+
+	// Message subscription declared in the plugin manifest
+	HandleAllMessages bool           `json:"handle_all_messages"`
+	MessageFilter     *MessageFilter `json:"message_filter,omitempty"`
+
+	// Dispatch policy declared in the plugin manifest
+	Priority    int  `json:"priority"`     // Higher runs first in ordered mode
+	ObserveOnly bool `json:"observe_only"` // Runs in parallel and never stops propagation
+
+	EventTopics []string `json:"event_topics,omitempty"` // Event bus subscriptions
+	Methods     []string `json:"methods,omitempty"`      // Methods other plugins may invoke
+	DependsOn   []string `json:"depends_on,omitempty"`   // Plugins that must be started first
+
+	ProtocolVersion uint32 `json:"protocol_version,omitempty"` // Highest protocol the plugin speaks, 0 means 1
+
+	// Version handshake: the SDK the plugin was built with, the protocol
+	// features it supports and the core versions it declares it works with
+	SDKVersion  string   `json:"sdk_version,omitempty"`
+	Features    []string `json:"features,omitempty"`
+	CoreVersion string   `json:"core_version,omitempty"` // e.g. ">=1.0.0, <2.0.0"
+
+	// Capabilities requested in the manifest and the set an admin approved
+	Capabilities         *Capabilities `json:"capabilities,omitempty"`
+	Approval             string        `json:"approval,omitempty"` // ApprovalPending or ApprovalApproved
+	ApprovedCapabilities *Capabilities `json:"approved_capabilities,omitempty"`
+
+	// Settings admins may change, see config.go; the values are kept
+	// apart from the meta
+	ConfigSchema []ConfigField `json:"config_schema,omitempty"`
 }
 
 // PortPool manages reusable ports
@@ -106,12 +173,65 @@ type PluginManager struct {
 	plugins      map[string]*PluginState
 	pluginDir    string
 	configDir    string
+	registry     *registry // records of installed plugins
 	portPool     *PortPool
 	grpcPort     int // BotService gRPC port for plugins to call back
 	botService   pb.BotServiceServer
 	commandIndex map[string]string // command -> plugin name
 	healthTicker *time.Ticker
 	stopHealth   chan struct{}
+	dispatchMode string         // DispatchParallel or DispatchOrdered
+	priorities   map[string]int // plugin name -> priority override
+
+	commandClaims  map[string][]string // command -> running plugins declaring it, in start order
+	builtins       map[string]bool     // built-in plugins taking part in command resolution
+	commandPins    map[string]string   // command -> plugin pinned by an admin
+	conflictPolicy string              // ConflictReject, ConflictFirst or ConflictPriority
+
+	bus *eventbus.Bus // plugin event bus, nil if disabled
+
+	streamMu       sync.Mutex
+	pendingStreams map[string]*pendingLaunch // plugin name -> v2 launch awaiting its stream
+
+	remoteToken     string                   // pre-shared token for remote plugins
+	remoteKeys      map[string]string        // plugin name -> remote registration key
+	remoteApprovals map[string]*Capabilities // plugin name -> capabilities approved for a remote plugin
+
+	transport  string // TransportTCP or TransportUnix
+	runtimeDir string // private socket directory in unix mode
+
+	authMu  sync.RWMutex
+	callers map[string]string        // per-launch secret -> plugin name
+	grants  map[string]*Capabilities // plugin name -> enforced capabilities, nil means unrestricted
+
+	logMu          sync.Mutex
+	logs           map[string]*pluginLog // plugin name -> captured output
+	logDir         string                // rotating log files, empty keeps logs in memory only
+	logBufferLines int
+	logMaxSize     int64
+	logMaxFiles    int
+
+	restartPolicy RestartPolicy
+	lifecycle     map[string]*sync.Mutex // plugin name -> lock serializing its transitions
+	shuttingDown  bool                   // set by Shutdown, refuses new transitions
+	childMu       sync.Mutex
+	children      map[*child]bool // plugin processes that have not exited yet
+	isolation     Isolation
+
+	releaseAPIs  map[string]ReleaseAPI // repo host -> GitHub-compatible release API
+	keepVersions int                   // versions of each plugin kept on disk
+	verifier     *verifier             // checks downloads against published checksums and signatures
+
+	catalogs     []string // catalog URLs or paths, searched in order
+	catalogMu    sync.Mutex
+	catalogCache map[string]*cachedCatalog // catalog location -> last good copy
+
+	updateCheck UpdateCheck // background update checker and who it notifies
+
+	configMu       sync.Mutex // serializes changes to plugin config files
+	configUpdateMu sync.Mutex // serializes config updates while the plugin is asked to apply them
+
+	autoStart []string // plugins started at boot unless an admin stopped them
 }
 
 // NewPluginManager creates a new plugin manager
@@ -120,10 +240,38 @@ func NewPluginManager(pluginDir, configDir string, grpcPort int) *PluginManager
 		plugins:      make(map[string]*PluginState),
 		pluginDir:    pluginDir,
 		configDir:    configDir,
+		registry:     newRegistry(configDir, pluginDir),
 		portPool:     NewPortPool(50100, 51000), // Allow up to 900 plugins
 		grpcPort:     grpcPort,
 		commandIndex: make(map[string]string),
 		stopHealth:   make(chan struct{}),
+		dispatchMode: DispatchParallel,
+
+		commandClaims:  make(map[string][]string),
+		commandPins:    make(map[string]string),
+		builtins:       make(map[string]bool),
+		conflictPolicy: ConflictFirst,
+
+		pendingStreams:  make(map[string]*pendingLaunch),
+		remoteKeys:      make(map[string]string),
+		remoteApprovals: make(map[string]*Capabilities),
+		transport:       TransportTCP,
+		callers:         make(map[string]string),
+		grants:          make(map[string]*Capabilities),
+
+		logs:           make(map[string]*pluginLog),
+		logBufferLines: defaultLogBufferLines,
+		logMaxSize:     defaultLogMaxSize,
+		logMaxFiles:    defaultLogMaxFiles,
+
+		restartPolicy: DefaultRestartPolicy,
+		lifecycle:     make(map[string]*sync.Mutex),
+		children:      make(map[*child]bool),
+
+		releaseAPIs:  defaultReleaseAPIs,
+		keepVersions: defaultKeepVersions,
+		verifier:     &verifier{},
+		catalogCache: make(map[string]*cachedCatalog),
 	}
 
 	// Start health check goroutine
@@ -153,9 +301,11 @@ func (pm *PluginManager) startHealthCheck() {
 func (pm *PluginManager) checkPluginHealth() {
 	pm.mu.RLock()
 	plugins := make([]*PluginState, 0)
+	replicas := make(map[*PluginState][]*PluginState)
 	for _, state := range pm.plugins {
-		if state.Status == "running" {
+		if state.Status == StatusRunning {
 			plugins = append(plugins, state)
+			replicas[state] = state.instancesLocked()[1:]
 		}
 	}
 	pm.mu.RUnlock()
@@ -167,52 +317,120 @@ func (pm *PluginManager) checkPluginHealth() {
 
 		if err != nil {
 			log.Printf("[PluginMgr] Plugin %s health check failed: %v", state.Info.Name, err)
-			pm.handlePluginCrash(state.Info.Name)
+			pm.handlePluginCrash(state.Info.Name, state, "health check failed: "+err.Error())
+			continue
+		}
+		pm.updateViolations(state)
+
+		// Replicas are checked and restarted on their own
+		for _, replica := range replicas[state] {
+			pm.checkReplicaHealth(state, replica)
 		}
 	}
 }
 
-// handlePluginCrash handles a crashed plugin
-func (pm *PluginManager) handlePluginCrash(name string) {
+// exitGrace is how long a plugin whose stream or health check failed is
+// given to exit on its own, so its exit code can be recorded
+const exitGrace = time.Second
+
+// handlePluginCrash cleans up after a plugin that exited, lost its stream
+// or failed a health check, and restarts it according to the restart
+// policy. state is the instance that failed; nothing is done if it no
+// longer carries the plugin.
+func (pm *PluginManager) handlePluginCrash(name string, state *PluginState, reason string) {
+	pm.mu.RLock()
+	running := pm.plugins[name] == state && state.Status == StatusRunning
+	pm.mu.RUnlock()
+	if !running {
+		return
+	}
+
+	// Make sure the process is gone, giving it a moment to exit on its own
+	killed := false
+	if state.proc != nil {
+		select {
+		case <-state.proc.done:
+		case <-time.After(exitGrace):
+			state.proc.kill()
+			killed = true
+		}
+	}
+
 	pm.mu.Lock()
-	state, exists := pm.plugins[name]
-	if !exists || state.Status != "running" {
-		pm.mu.Unlock()
+	defer pm.mu.Unlock()
+
+	if pm.plugins[name] != state || state.Status != StatusRunning {
 		return
 	}
 
-	// Mark as error
-	state.Status = "error"
-	state.LastError = "plugin crashed or became unresponsive"
+	closeSession(state)
+
+	// Remote plugins are only known while connected; they re-register
+	// themselves when they come back
+	if state.Remote {
+		pm.deregisterRemoteLocked(state)
+		log.Printf("[PluginMgr] Remote plugin %s disconnected, deregistered", name)
+		return
+	}
+
+	failed := recordExit(state, reason, killed)
+	state.Status = StatusError
+
+	// Another replica carries on with the plugin if one runs
+	if successor := state.successorLocked(); successor != nil {
+		pm.releaseInstance(state)
+		pm.takeOverLocked(name, state, successor, failed)
+		return
+	}
 
 	// Release port
 	if state.Port > 0 {
 		pm.portPool.Release(state.Port)
 	}
+	if state.Socket != "" {
+		os.Remove(state.Socket)
+	}
+	pm.revokeSecret(state.secret)
+	pm.ungrant(name)
 
 	// Clean up connection
 	if state.Conn != nil {
 		state.Conn.Close()
 	}
 
-	// Remove command index
-	for _, cmd := range state.Info.Commands {
-		delete(pm.commandIndex, cmd)
-	}
+	// None of its replicas runs; the plugin is restarted with all of them
+	pm.killReplicasLocked(state.replicas)
+	state.replicas = nil
 
-	pm.mu.Unlock()
+	// Hand commands over to other claimants
+	pm.releaseCommandsLocked(state.Info)
 
-	log.Printf("[PluginMgr] Plugin %s crashed, attempting restart...", name)
+	if pm.bus != nil {
+		pm.bus.Detach(name)
+	}
 
-	// Attempt to restart the plugin
-	go func() {
-		time.Sleep(5 * time.Second)
-		if err := pm.StartPlugin(context.Background(), name); err != nil {
-			log.Printf("[PluginMgr] Failed to restart plugin %s: %v", name, err)
-		} else {
-			log.Printf("[PluginMgr] Successfully restarted plugin %s", name)
-		}
-	}()
+	log.Printf("[PluginMgr] Plugin %s crashed: %s", name, state.LastError)
+	pm.scheduleRestartLocked(name, state, failed)
+}
+
+// recordExit records how the process of an instance ended and reports
+// whether it failed. An instance killed after reason was detected is
+// recorded with reason rather than the signal it was killed with.
+func recordExit(state *PluginState, reason string, killed bool) bool {
+	failed := true
+	state.LastError = reason
+	if state.proc == nil {
+		return failed
+	}
+	if !killed {
+		state.LastError = "process " + state.proc.exitDescription()
+		failed = state.proc.failed()
+	}
+	state.Violations = state.proc.sb.violations()
+	if len(state.Violations) > 0 {
+		state.LastError += " (" + strings.Join(state.Violations, ", ") + ")"
+	}
+	return failed
 }
 
 // SetBotService sets the bot service for plugins to call back
@@ -220,112 +438,121 @@ func (pm *PluginManager) SetBotService(svc pb.BotServiceServer) {
 	pm.botService = svc
 }
 
-// InstallFromGitHub downloads and installs a plugin from GitHub releases
-func (pm *PluginManager) InstallFromGitHub(ctx context.Context, repoURL string) (*PluginMeta, error) {
-	// Parse repo URL: https://github.com/owner/repo
-	parts := strings.Split(strings.TrimPrefix(repoURL, "https://github.com/"), "/")
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("invalid GitHub URL: %s", repoURL)
-	}
-	owner, repo := parts[0], parts[1]
+// SetEventBus sets the event bus running plugins are subscribed to
+func (pm *PluginManager) SetEventBus(bus *eventbus.Bus) {
+	pm.bus = bus
+}
 
-	// Get latest release info
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, repo)
-	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
+// eventHandler delivers bus events to an external plugin's OnEvent RPC,
+// on the least busy of its replicas
+func (pm *PluginManager) eventHandler(state *PluginState) eventbus.Handler {
+	return eventbus.HandlerFunc(func(ctx context.Context, event *pb.BusEvent) error {
+		pm.mu.RLock()
+		replica := state.pickLocked(nil)
+		replica.track()
+		pm.mu.RUnlock()
+		defer replica.untrack()
+		result, err := replica.Client.OnEvent(ctx, event)
+		if err != nil {
+			return err
+		}
+		if result.Error != "" {
+			return fmt.Errorf("%s", result.Error)
+		}
+		return nil
+	})
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// Install fetches a plugin from ref and installs it. ref may be a repo URL
+// served by a GitHub-compatible release API, optionally with a release tag
+// as in owner/repo@v1.2.0, an http(s) URL or a local path, pointing at a
+// binary or a .tar.gz or .zip archive of one. Downloads without a matching
+// checksum or signature are refused unless allowUnverified is set. The new
+// version is kept next to the ones installed before and becomes current; a
+// running plugin keeps running its old version until it is restarted.
+func (pm *PluginManager) Install(ctx context.Context, ref string, allowUnverified bool) (*PluginMeta, error) {
+	meta, tmpPath, err := pm.stage(ctx, ref, allowUnverified)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch release info: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer os.Remove(tmpPath)
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("GitHub API returned %d", resp.StatusCode)
+	// Registering the plugin must not race a start or uninstall of it
+	unlock, err := pm.lockLifecycle(meta.Name)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	var release struct {
-		TagName string `json:"tag_name"`
-		Assets  []struct {
-			Name               string `json:"name"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		} `json:"assets"`
+	if cur, err := pm.registry.get(meta.Name); err == nil && cur.Held && cur.Version != meta.Version {
+		return nil, fmt.Errorf("plugin %s is held at v%s, unhold it to install v%s", meta.Name, cur.Version, meta.Version)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, fmt.Errorf("failed to parse release info: %w", err)
+	pm.mu.Lock()
+	prev, exists := pm.plugins[meta.Name]
+	state := prev
+	if !exists || prev.Status == StatusStopped {
+		state = &PluginState{Info: meta, Status: StatusInstalling}
+		pm.plugins[meta.Name] = state
 	}
+	pm.mu.Unlock()
 
-	// Find the right binary for current OS/arch
-	osName := runtime.GOOS
-	archName := runtime.GOARCH
-	expectedSuffix := fmt.Sprintf("%s_%s", osName, archName)
-	if osName == "windows" {
-		expectedSuffix += ".exe"
+	// Move the binary into the version store and make it current
+	err = pm.storeVersion(meta, tmpPath)
+	if err == nil {
+		pm.carryApproval(meta)
+		err = pm.activate(ctx, meta, false)
 	}
 
-	var downloadURL, binaryName string
-	for _, asset := range release.Assets {
-		if strings.Contains(asset.Name, expectedSuffix) {
-			downloadURL = asset.BrowserDownloadURL
-			binaryName = asset.Name
-			break
+	pm.mu.Lock()
+	if state.Status == StatusInstalling {
+		if err == nil {
+			state.Status = StatusStopped
+		} else if exists {
+			pm.plugins[meta.Name] = prev
+		} else {
+			delete(pm.plugins, meta.Name)
 		}
 	}
+	pm.mu.Unlock()
 
-	if downloadURL == "" {
-		return nil, fmt.Errorf("no binary found for %s/%s", osName, archName)
-	}
-
-	// Download binary
-	log.Printf("[PluginMgr] Downloading %s...", binaryName)
-
-	pluginPath := filepath.Join(pm.pluginDir, binaryName)
-	if err := pm.downloadFile(ctx, downloadURL, pluginPath); err != nil {
-		return nil, fmt.Errorf("failed to download: %w", err)
-	}
-
-	// Make executable
-	if osName != "windows" {
-		os.Chmod(pluginPath, 0755)
-	}
-
-	// Get plugin info by running with --info flag
-	meta, err := pm.getPluginInfo(pluginPath)
 	if err != nil {
-		os.Remove(pluginPath)
-		return nil, fmt.Errorf("failed to get plugin info: %w", err)
+		return nil, fmt.Errorf("failed to install plugin: %w", err)
 	}
-	meta.RepoURL = repoURL
-	meta.BinaryName = binaryName
-
-	// Save plugin meta
-	metaPath := filepath.Join(pm.configDir, meta.Name+".json")
-	metaFile, _ := os.Create(metaPath)
-	json.NewEncoder(metaFile).Encode(meta)
-	metaFile.Close()
 
-	log.Printf("[PluginMgr] Installed plugin: %s v%s", meta.Name, meta.Version)
+	if meta.Approved() {
+		log.Printf("[PluginMgr] Installed plugin: %s v%s (verified: %s)", meta.Name, meta.Version, meta.Verified)
+	} else {
+		log.Printf("[PluginMgr] Installed plugin: %s v%s (verified: %s), capabilities awaiting approval: %s",
+			meta.Name, meta.Version, meta.Verified, meta.Capabilities)
+	}
 	return meta, nil
 }
 
-// downloadFile downloads a file from URL
-func (pm *PluginManager) downloadFile(ctx context.Context, url, dest string) error {
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	resp, err := http.DefaultClient.Do(req)
+// writeFileAtomic replaces a file by renaming a fully written temporary
+// file over it
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer os.Remove(tmp.Name())
 
-	out, err := os.Create(dest)
-	if err != nil {
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
 		return err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
 // getPluginInfo runs the plugin binary with --info to get metadata
@@ -346,58 +573,273 @@ func (pm *PluginManager) getPluginInfo(binaryPath string) (*PluginMeta, error) {
 	return &meta, nil
 }
 
-// StartPlugin starts a plugin by name
+// StartPlugin starts a plugin by name. Restart history is reset, so this
+// also brings a plugin back from crashloop.
 func (pm *PluginManager) StartPlugin(ctx context.Context, name string) error {
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Started again after the next restart, even if this attempt fails
+	pm.setDesired(name, DesiredEnabled)
+	return pm.runPlugin(ctx, name, false)
+}
+
+// startPlugin starts a plugin. The process is spawned and connected
+// without holding pm.mu, so dispatch to other plugins carries on while it
+// boots. restart carries the crash history over for the restart policy.
+func (pm *PluginManager) startPlugin(ctx context.Context, name string, restart bool) error {
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return pm.runPlugin(ctx, name, restart)
+}
+
+// runPlugin starts a plugin whose lifecycle lock is held
+func (pm *PluginManager) runPlugin(ctx context.Context, name string, restart bool) error {
+	state, meta, binaryPath, err := pm.prepareStart(name, restart)
+	if err != nil {
+		return err
+	}
+	if err := checkBinary(meta, binaryPath); err != nil {
+		pm.failStart(state, err)
+		return err
+	}
+
+	// The plugin is held to its approved capabilities
+	pm.grant(name, meta.Granted())
+
+	if err := pm.launch(ctx, state, binaryPath); err != nil {
+		pm.ungrant(name)
+		pm.failStart(state, err)
+		return err
+	}
+	replicas := pm.launchReplicas(ctx, state, binaryPath, 2, meta.ReplicaCount())
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// Another plugin may have claimed a command while this one booted
+	if err := pm.checkCommandConflictsLocked(meta); err != nil {
+		state.proc.kill()
+		pm.releaseInstance(state)
+		pm.killReplicasLocked(replicas)
+		pm.ungrant(name)
+		state.Status = StatusError
+		state.LastError = err.Error()
+		return err
+	}
+
+	state.Status = StatusRunning
+	state.StartedAt = time.Now()
+	go pm.supervise(name, state)
+	pm.attachReplicasLocked(state, replicas)
+
+	// Index commands, resolving conflicts with other running plugins
+	pm.claimCommandsLocked(meta)
+
+	if pm.bus != nil && len(meta.EventTopics) > 0 {
+		pm.bus.Attach(name, meta.EventTopics, pm.eventHandler(state))
+	}
+
+	if len(replicas) > 0 {
+		log.Printf("[PluginMgr] Started plugin: %s with %d replicas", name, 1+len(replicas))
+	} else if state.Protocol >= 2 {
+		log.Printf("[PluginMgr] Started plugin: %s over stream", name)
+	} else if state.Socket != "" {
+		log.Printf("[PluginMgr] Started plugin: %s on %s", name, state.Socket)
+	} else {
+		log.Printf("[PluginMgr] Started plugin: %s on port %d", name, state.Port)
+	}
+	return nil
+}
+
+// launch spawns the process of a plugin instance and connects to it,
+// filling in state. On failure nothing the launch took is left behind.
+func (pm *PluginManager) launch(ctx context.Context, state *PluginState, binaryPath string) error {
+	meta := state.Info
+	name := meta.Name
+
+	// The plugin presents this secret on every BotService call
+	secret := pm.issueSecret(name)
+	state.secret = secret
+
+	if meta.ProtocolVersion >= 2 {
+		// v2: the plugin dials back over a single stream, no port needed
+		proc, session, err := pm.launchStream(ctx, name, state.instance, binaryPath, secret)
+		if err != nil {
+			pm.revokeSecret(secret)
+			return err
+		}
+		state.proc = proc
+		state.Client = session
+		state.Protocol = 2
+	} else {
+		proc, port, conn, err := pm.launchUnary(ctx, name, state.instance, binaryPath, secret)
+		if err != nil {
+			pm.revokeSecret(secret)
+			return err
+		}
+		state.proc = proc
+		state.Client = pb.NewPluginServiceClient(conn)
+		state.Conn = conn
+		state.Port = port
+		state.Protocol = 1
+		if pm.transport == TransportUnix {
+			state.Socket = pm.pluginSocket(instanceName(name, state.instance))
+		}
+		if err := pm.handshakeUnary(ctx, meta, state.Client); err != nil {
+			proc.kill()
+			pm.releaseInstance(state)
+			return err
+		}
+	}
+	state.Process = state.proc.cmd.Process
+	return nil
+}
+
+// releaseInstance frees what the launch of an instance took: its
+// connection, port, socket and secret. The process must have exited or
+// been killed.
+func (pm *PluginManager) releaseInstance(state *PluginState) {
+	if state.Conn != nil {
+		state.Conn.Close()
+	}
+	closeSession(state)
+	if state.Port > 0 {
+		pm.portPool.Release(state.Port)
+	}
+	if state.Socket != "" {
+		os.Remove(state.Socket)
+	}
+	pm.revokeSecret(state.secret)
+}
+
+// prepareStart validates a plugin and registers it as starting
+func (pm *PluginManager) prepareStart(name string, restart bool) (*PluginState, *PluginMeta, string, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
 	// Check if already running
-	if state, exists := pm.plugins[name]; exists && state.Status == "running" {
-		return fmt.Errorf("plugin %s is already running", name)
+	prev, exists := pm.plugins[name]
+	if exists && prev.Status == StatusRunning {
+		return nil, nil, "", fmt.Errorf("plugin %s is already running", name)
+	}
+	if exists && prev.Remote {
+		return nil, nil, "", fmt.Errorf("plugin %s is a connected remote plugin", name)
 	}
 
 	// Load plugin meta
-	metaPath := filepath.Join(pm.configDir, name+".json")
-	metaFile, err := os.Open(metaPath)
+	meta, err := pm.registry.get(name)
 	if err != nil {
-		return fmt.Errorf("plugin %s not found, install it first", name)
+		return nil, nil, "", err
 	}
-	defer metaFile.Close()
 
-	var meta PluginMeta
-	if err := json.NewDecoder(metaFile).Decode(&meta); err != nil {
-		return fmt.Errorf("invalid plugin meta: %w", err)
+	// The core may have been upgraded since the plugin was installed
+	if err := checkCompatible(meta); err != nil {
+		return nil, nil, "", err
+	}
+
+	if !meta.Approved() {
+		return nil, nil, "", fmt.Errorf("plugin %s requests capabilities that have not been approved (%s); approve them with /plugin approve %s",
+			name, meta.Capabilities, name)
+	}
+
+	// Compile message subscription before spawning anything
+	matcher, err := compileMatcher(meta)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if err := pm.checkCommandConflictsLocked(meta); err != nil {
+		return nil, nil, "", err
+	}
+
+	if err := pm.checkConfigLocked(meta); err != nil {
+		return nil, nil, "", err
+	}
+
+	if missing := pm.missingDependencies(meta); len(missing) > 0 {
+		log.Printf("[PluginMgr] Warning: plugin %s depends on %v, which are not running", name, missing)
 	}
 
 	// Find binary
-	binaryPath := filepath.Join(pm.pluginDir, meta.BinaryName)
+	binaryPath := pm.binaryPath(meta)
 	if _, err := os.Stat(binaryPath); os.IsNotExist(err) {
-		return fmt.Errorf("plugin binary not found: %s", binaryPath)
+		return nil, nil, "", fmt.Errorf("plugin binary not found: %s", binaryPath)
 	}
 
-	// Allocate port from pool
-	port, err := pm.portPool.Acquire()
-	if err != nil {
-		return fmt.Errorf("failed to allocate port: %w", err)
+	state := &PluginState{
+		Info:    meta,
+		Status:  StatusStarting,
+		matcher: matcher,
+	}
+	if restart && exists {
+		state.Restarts = prev.Restarts
+		state.restarts = prev.restarts
+	}
+	pm.plugins[name] = state
+	return state, meta, binaryPath, nil
+}
+
+// failStart records why a plugin could not be started
+func (pm *PluginManager) failStart(state *PluginState, err error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	state.Status = StatusError
+	state.LastError = err.Error()
+}
+
+// launchUnary spawns a v1 plugin and dials its gRPC server, which listens
+// on a pooled port or, in unix mode, on a socket in the runtime directory
+func (pm *PluginManager) launchUnary(ctx context.Context, name, instance, binaryPath, secret string) (*child, int, *grpc.ClientConn, error) {
+	var port int
+	var target string
+	args := pm.coreArgs()
+
+	if pm.transport == TransportUnix {
+		socket := pm.pluginSocket(instanceName(name, instance))
+		os.Remove(socket)
+		target = "unix:" + socket
+		args = append(args, "--listen-socket", socket)
+	} else {
+		// Allocate port from pool
+		var err error
+		port, err = pm.portPool.Acquire()
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to allocate port: %w", err)
+		}
+		target = fmt.Sprintf("127.0.0.1:%d", port)
+		args = append(args, "--port", fmt.Sprintf("%d", port))
+	}
+
+	release := func() {
+		if port > 0 {
+			pm.portPool.Release(port)
+		}
 	}
 
 	// Start plugin process
-	cmd := exec.Command(binaryPath,
-		"--port", fmt.Sprintf("%d", port),
-		"--core-addr", fmt.Sprintf("127.0.0.1:%d", pm.grpcPort),
-	)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	sb, err := pm.newSandbox(name, instance, append(coreEnv(), fmt.Sprintf("%s=%s", EnvSecret, secret)))
+	if err != nil {
+		release()
+		return nil, 0, nil, err
+	}
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Stdout = pm.logWriter(name, LogStdout)
+	cmd.Stderr = pm.logWriter(name, LogStderr)
 
-	if err := cmd.Start(); err != nil {
-		pm.portPool.Release(port)
-		return fmt.Errorf("failed to start plugin: %w", err)
+	proc, err := pm.startChild(cmd, sb)
+	if err != nil {
+		release()
+		return nil, 0, nil, fmt.Errorf("failed to start plugin: %w", err)
 	}
 
 	// Wait for plugin to be ready with retries
-	var conn *grpc.ClientConn
-	var client pb.PluginServiceClient
-
 	maxRetries := 10
 	retryInterval := 200 * time.Millisecond
 
@@ -406,9 +848,9 @@ func (pm *PluginManager) StartPlugin(ctx context.Context, name string) error {
 
 		// Use context with timeout instead of deprecated grpc.WithTimeout
 		dialCtx, dialCancel := context.WithTimeout(ctx, 2*time.Second)
-		conn, err = grpc.DialContext(
+		conn, err := grpc.DialContext(
 			dialCtx,
-			fmt.Sprintf("127.0.0.1:%d", port),
+			target,
 			grpc.WithTransportCredentials(insecure.NewCredentials()),
 			grpc.WithBlock(),
 		)
@@ -416,14 +858,14 @@ func (pm *PluginManager) StartPlugin(ctx context.Context, name string) error {
 
 		if err != nil {
 			if i == maxRetries-1 {
-				cmd.Process.Kill()
-				pm.portPool.Release(port)
-				return fmt.Errorf("failed to connect to plugin after %d retries: %w", maxRetries, err)
+				proc.kill()
+				release()
+				return nil, 0, nil, fmt.Errorf("failed to connect to plugin after %d retries: %w", maxRetries, err)
 			}
 			continue
 		}
 
-		client = pb.NewPluginServiceClient(conn)
+		client := pb.NewPluginServiceClient(conn)
 
 		// Verify connection with health check
 		healthCtx, healthCancel := context.WithTimeout(ctx, 2*time.Second)
@@ -432,53 +874,134 @@ func (pm *PluginManager) StartPlugin(ctx context.Context, name string) error {
 
 		if err != nil || !healthResp.Healthy {
 			conn.Close()
-			if i == maxRetries-1 {
-				cmd.Process.Kill()
-				pm.portPool.Release(port)
-				return fmt.Errorf("plugin health check failed after %d retries", maxRetries)
-			}
 			continue
 		}
 
 		// Success!
-		break
+		return proc, port, conn, nil
 	}
 
-	// Register plugin
-	state := &PluginState{
-		Info:      &meta,
-		Process:   cmd.Process,
-		Client:    client,
-		Conn:      conn,
-		Port:      port,
-		Status:    "running",
-		StartedAt: time.Now(),
+	proc.kill()
+	release()
+	return nil, 0, nil, fmt.Errorf("plugin health check failed after %d retries", maxRetries)
+}
+
+// launchStream spawns a v2 plugin and waits for it to open its stream.
+// A plugin built with an older SDK ignores the session and is killed.
+func (pm *PluginManager) launchStream(ctx context.Context, name, instance, binaryPath, secret string) (*child, *streamSession, error) {
+	launch := pm.expectStream(name)
+	defer pm.cancelStream(name, launch)
+
+	sb, err := pm.newSandbox(name, instance, append(coreEnv(),
+		fmt.Sprintf("%s=%d", EnvProtocol, ProtocolVersion),
+		fmt.Sprintf("%s=%s", EnvSession, launch.session),
+		fmt.Sprintf("%s=%s", EnvSecret, secret),
+	))
+	if err != nil {
+		return nil, nil, err
 	}
-	pm.plugins[name] = state
+	cmd := exec.Command(binaryPath, pm.coreArgs()...)
+	cmd.Stdout = pm.logWriter(name, LogStdout)
+	cmd.Stderr = pm.logWriter(name, LogStderr)
 
-	// Index commands
-	for _, cmd := range meta.Commands {
-		pm.commandIndex[cmd] = name
+	proc, err := pm.startChild(cmd, sb)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start plugin: %w", err)
 	}
 
-	log.Printf("[PluginMgr] Started plugin: %s on port %d", name, port)
-	return nil
+	select {
+	case session := <-launch.ready:
+		return proc, session, nil
+	case err := <-launch.rejected:
+		// Let the plugin log the refusal before it goes
+		select {
+		case <-proc.done:
+		case <-time.After(exitGrace):
+			proc.kill()
+		}
+		return nil, nil, err
+	case <-proc.done:
+		return nil, nil, fmt.Errorf("plugin %s before connecting", proc.exitDescription())
+	case <-time.After(streamConnectTimeout):
+		proc.kill()
+		return nil, nil, fmt.Errorf("plugin did not connect within %s", streamConnectTimeout)
+	case <-ctx.Done():
+		proc.kill()
+		return nil, nil, ctx.Err()
+	}
 }
 
-// StopPlugin stops a running plugin
+// StopPlugin stops a running plugin and keeps it stopped across restarts
 func (pm *PluginManager) StopPlugin(ctx context.Context, name string) error {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
+	pm.setDesired(name, DesiredDisabled)
+	return pm.stopPlugin(ctx, name)
+}
+
+// stopPlugin stops a plugin whose lifecycle lock is held. The plugin is
+// taken out of dispatch first and then shut down without holding pm.mu.
+func (pm *PluginManager) stopPlugin(ctx context.Context, name string) error {
+	pm.mu.Lock()
 	state, exists := pm.plugins[name]
 	if !exists {
+		pm.mu.Unlock()
 		return fmt.Errorf("plugin %s not found", name)
 	}
 
-	if state.Status != "running" {
+	// Stopping a crashed plugin cancels its pending restart
+	if state.Status == StatusError || state.Status == StatusCrashloop {
+		state.Status = StatusStopped
+		pm.mu.Unlock()
+		log.Printf("[PluginMgr] Stopped plugin: %s (was %s)", name, state.LastError)
+		return nil
+	}
+
+	if state.Status != StatusRunning {
+		pm.mu.Unlock()
 		return fmt.Errorf("plugin %s is not running", name)
 	}
 
+	// Hand commands over to other claimants and stop delivering events
+	state.Status = StatusStopping
+	pm.releaseCommandsLocked(state.Info)
+	if pm.bus != nil {
+		pm.bus.Detach(name)
+	}
+	replicas := state.replicas
+	state.replicas = nil
+	for _, replica := range replicas {
+		if replica.Status == StatusRunning {
+			replica.Status = StatusStopping
+		}
+	}
+	pm.mu.Unlock()
+
+	pm.shutdownInstance(ctx, state)
+	pm.stopReplicas(ctx, replicas, false)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.ungrant(name)
+
+	if state.Remote {
+		pm.deregisterRemoteLocked(state)
+		log.Printf("[PluginMgr] Stopped remote plugin: %s", name)
+		return nil
+	}
+
+	state.Status = StatusStopped
+	log.Printf("[PluginMgr] Stopped plugin: %s", name)
+	return nil
+}
+
+// shutdownInstance asks a plugin instance to exit, kills it if it does not
+// and frees what its launch took. Dispatch is left alone.
+func (pm *PluginManager) shutdownInstance(ctx context.Context, state *PluginState) {
 	// Send shutdown command
 	if state.Client != nil {
 		shutdownCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
@@ -487,92 +1010,101 @@ func (pm *PluginManager) StopPlugin(ctx context.Context, name string) error {
 	}
 
 	// Wait for process to exit gracefully
-	done := make(chan struct{})
-	go func() {
-		if state.Process != nil {
-			state.Process.Wait()
-		}
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		// Process exited gracefully
-	case <-time.After(5 * time.Second):
-		// Timeout, force kill
-		if state.Process != nil {
-			state.Process.Kill()
-			state.Process.Wait()
+	if state.proc != nil {
+		select {
+		case <-state.proc.done:
+			// Process exited gracefully
+		case <-time.After(5 * time.Second):
+			// Timeout, force kill
+			state.proc.kill()
 		}
 	}
 
-	// Close connection
-	if state.Conn != nil {
-		state.Conn.Close()
-	}
-
-	// Release port back to pool
-	if state.Port > 0 {
-		pm.portPool.Release(state.Port)
-	}
-
-	// Remove command index
-	for _, cmd := range state.Info.Commands {
-		delete(pm.commandIndex, cmd)
-	}
-
-	state.Status = "stopped"
-	log.Printf("[PluginMgr] Stopped plugin: %s", name)
-	return nil
+	pm.releaseInstance(state)
 }
 
-// Shutdown stops all plugins and cleans up
+// Shutdown stops all plugins and cleans up. A plugin in the middle of a
+// start or stop is waited for up to shutdownTimeout; any plugin process
+// still alive after that is killed.
 func (pm *PluginManager) Shutdown() {
 	// Stop health check
 	close(pm.stopHealth)
 
-	// Stop all running plugins
-	pm.mu.RLock()
-	names := make([]string, 0)
-	for name, state := range pm.plugins {
-		if state.Status == "running" {
-			names = append(names, name)
-		}
+	// Admins can't start anything from here on
+	pm.mu.Lock()
+	pm.shuttingDown = true
+	names := make([]string, 0, len(pm.plugins))
+	for name := range pm.plugins {
+		names = append(names, name)
 	}
-	pm.mu.RUnlock()
+	pm.mu.Unlock()
 
+	// Stopped for the shutdown only; they come back on the next start
+	deadline := time.Now().Add(shutdownTimeout)
 	for _, name := range names {
-		pm.StopPlugin(context.Background(), name)
+		unlock, ok := pm.waitLifecycle(name, deadline)
+		if !ok {
+			log.Printf("[PluginMgr] Plugin %s is still busy at shutdown, killing it", name)
+			continue
+		}
+		pm.mu.RLock()
+		state, exists := pm.plugins[name]
+		running := exists && state.Status == StatusRunning
+		pm.mu.RUnlock()
+		if running {
+			pm.stopPlugin(context.Background(), name)
+		}
+		unlock()
+	}
+
+	if n := pm.killChildren(); n > 0 {
+		log.Printf("[PluginMgr] Killed %d plugin processes left at shutdown", n)
 	}
+	pm.closeLogs()
 }
 
 // UninstallPlugin removes a plugin
 func (pm *PluginManager) UninstallPlugin(ctx context.Context, name string) error {
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Stop if running
-	pm.StopPlugin(ctx, name)
+	pm.stopPlugin(ctx, name)
 
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	// Load meta to get binary name
-	metaPath := filepath.Join(pm.configDir, name+".json")
-	metaFile, err := os.Open(metaPath)
-	if err == nil {
-		var meta PluginMeta
-		json.NewDecoder(metaFile).Decode(&meta)
-		metaFile.Close()
+	// The record goes first: a plugin whose files could not all be
+	// removed is still uninstalled, never half installed
+	meta, err := pm.registry.remove(name)
+	if err != nil {
+		return err
+	}
 
-		// Remove binary
-		binaryPath := filepath.Join(pm.pluginDir, meta.BinaryName)
-		os.Remove(binaryPath)
+	// Remove binary and every stored version
+	if meta != nil {
+		os.Remove(pm.binaryPath(meta))
+		if meta.Path != "" && meta.BinaryName != "" {
+			os.Remove(filepath.Join(pm.pluginDir, meta.BinaryName))
+		}
+	}
+	if validStoreName.MatchString(name) {
+		os.RemoveAll(filepath.Join(pm.pluginDir, versionsDir, name))
 	}
 
-	// Remove meta file
-	os.Remove(metaPath)
+	// Remove config values
+	os.Remove(pm.configPath(name))
 
 	// Remove from map
 	delete(pm.plugins, name)
 
+	if pm.bus != nil {
+		pm.bus.Remove(name)
+	}
+
 	log.Printf("[PluginMgr] Uninstalled plugin: %s", name)
 	return nil
 }
@@ -582,35 +1114,57 @@ func (pm *PluginManager) ListPlugins() []*PluginState {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
-	// Load all installed plugins from config dir
-	files, _ := filepath.Glob(filepath.Join(pm.configDir, "*.json"))
+	// An unreadable registry is logged when it is opened; the plugins
+	// known at runtime are still listed
+	metas, _ := pm.registry.list()
 
 	result := make([]*PluginState, 0)
-	for _, f := range files {
-		name := strings.TrimSuffix(filepath.Base(f), ".json")
+	listed := make(map[string]bool)
+	for _, meta := range metas {
+		listed[meta.Name] = true
 
-		if state, exists := pm.plugins[name]; exists {
+		if state, exists := pm.plugins[meta.Name]; exists {
 			result = append(result, state)
 		} else {
 			// Plugin installed but not running
-			metaFile, _ := os.Open(f)
-			var meta PluginMeta
-			json.NewDecoder(metaFile).Decode(&meta)
-			metaFile.Close()
-
 			result = append(result, &PluginState{
-				Info:   &meta,
-				Status: "stopped",
+				Info:   meta,
+				Status: StatusStopped,
 			})
 		}
 	}
+
+	// Remote plugins and plugins still being installed have no record
+	for name, state := range pm.plugins {
+		if !listed[name] && (state.Remote || state.Status == StatusInstalling) {
+			result = append(result, state)
+		}
+	}
 	return result
 }
 
-// GetPluginByCommand finds plugin that handles a command
+// GetPluginByCommand finds plugin that handles a command.
+// A fully-qualified "plugin:cmd" bypasses conflict resolution and always
+// reaches the named plugin if it is running and declares cmd.
 func (pm *PluginManager) GetPluginByCommand(cmd string) *PluginState {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
+	return pm.pluginByCommandLocked(cmd)
+}
+
+func (pm *PluginManager) pluginByCommandLocked(cmd string) *PluginState {
+	if pluginName, bare, ok := splitQualifiedCommand(cmd); ok {
+		state, exists := pm.plugins[pluginName]
+		if !exists || state.Status != StatusRunning {
+			return nil
+		}
+		for _, c := range state.Info.Commands {
+			if c == bare {
+				return state
+			}
+		}
+		return nil
+	}
 
 	pluginName, exists := pm.commandIndex[cmd]
 	if !exists {
@@ -626,7 +1180,7 @@ func (pm *PluginManager) GetRunningPlugins() []*PluginState {
 
 	result := make([]*PluginState, 0)
 	for _, state := range pm.plugins {
-		if state.Status == "running" {
+		if state.Status == StatusRunning {
 			result = append(result, state)
 		}
 	}
@@ -640,42 +1194,66 @@ func (pm *PluginManager) GetAllCommands() map[string]*PluginMeta {
 
 	result := make(map[string]*PluginMeta)
 	for cmd, pluginName := range pm.commandIndex {
-		if state, exists := pm.plugins[pluginName]; exists && state.Status == "running" {
+		if state, exists := pm.plugins[pluginName]; exists && state.Status == StatusRunning {
 			result[cmd] = state.Info
 		}
 	}
 	return result
 }
 
-// DispatchMessage dispatches a message to all running plugins
-func (pm *PluginManager) DispatchMessage(ctx context.Context, event *pb.MessageEvent) {
+// DispatchMessage dispatches a message to running plugins whose
+// subscription matches it. Filters are evaluated here so plugins that
+// are not interested never see an RPC. Observer plugins always run in
+// parallel; in ordered mode the remaining plugins are called by priority
+// until one handles the message. Returns true if a plugin handled it.
+func (pm *PluginManager) DispatchMessage(ctx context.Context, event *pb.MessageEvent) bool {
 	pm.mu.RLock()
-	plugins := make([]*PluginState, 0)
+	observers := make([]*PluginState, 0)
+	handlers := make([]*PluginState, 0)
 	for _, state := range pm.plugins {
-		if state.Status == "running" {
-			plugins = append(plugins, state)
+		if state.Status != StatusRunning || !state.matcher.Match(event) {
+			continue
+		}
+		replica := state.pickLocked(event)
+		replica.track()
+		if state.Info.ObserveOnly {
+			observers = append(observers, replica)
+		} else {
+			handlers = append(handlers, replica)
 		}
 	}
+	pm.sortByPriority(handlers)
+	mode := pm.dispatchMode
 	pm.mu.RUnlock()
 
-	for _, plugin := range plugins {
-		go func(p *PluginState) {
-			_, err := p.Client.OnMessage(ctx, event)
-			if err != nil {
-				log.Printf("[PluginMgr] Plugin %s OnMessage error: %v", p.Info.Name, err)
-			}
-		}(plugin)
+	if mode != DispatchOrdered {
+		pm.notifyObservers(ctx, append(observers, handlers...), event)
+		return false
 	}
+
+	pm.notifyObservers(ctx, observers, event)
+	return pm.dispatchOrdered(ctx, handlers, event)
 }
 
 // DispatchCommand dispatches a command to the appropriate plugin
 func (pm *PluginManager) DispatchCommand(ctx context.Context, event *pb.CommandEvent) bool {
-	log.Printf("[PluginMgr] DispatchCommand: looking for command '%s', commandIndex: %v", event.Command, pm.commandIndex)
-	plugin := pm.GetPluginByCommand(event.Command)
+	pm.mu.RLock()
+	plugin := pm.pluginByCommandLocked(event.Command)
+	if plugin != nil {
+		plugin = plugin.pickLocked(event.Message)
+		plugin.track()
+	}
+	pm.mu.RUnlock()
 	if plugin == nil {
 		log.Printf("[PluginMgr] No plugin found for command: %s", event.Command)
 		return false
 	}
+	defer plugin.untrack()
+
+	// Plugins only know their bare command names
+	if _, bare, ok := splitQualifiedCommand(event.Command); ok {
+		event.Command = bare
+	}
 
 	log.Printf("[PluginMgr] Dispatching command '%s' to plugin '%s'", event.Command, plugin.Info.Name)
 	result, err := plugin.Client.OnCommand(ctx, event)
@@ -688,38 +1266,82 @@ func (pm *PluginManager) DispatchCommand(ctx context.Context, event *pb.CommandE
 
 // LoadInstalledPlugins loads all installed plugins at startup
 func (pm *PluginManager) LoadInstalledPlugins() error {
-	files, err := filepath.Glob(filepath.Join(pm.configDir, "*.json"))
+	metas, err := pm.registry.list()
 	if err != nil {
 		return err
 	}
 
-	for _, f := range files {
-		metaFile, err := os.Open(f)
-		if err != nil {
+	for _, meta := range metas {
+		// Register in plugins map (but don't start)
+		pm.plugins[meta.Name] = &PluginState{
+			Info:   meta,
+			Status: StatusStopped,
+		}
+
+		// Keep durable events for subscribers that are not running yet
+		if pm.bus != nil && len(meta.EventTopics) > 0 {
+			pm.bus.Register(meta.Name, meta.EventTopics)
+		}
+	}
+	return nil
+}
+
+// startInOrder starts plugins without recording a desired state.
+// Dependencies are started before their dependents unless an admin
+// stopped them, and a plugin is skipped if one of its dependencies failed
+// to start.
+func (pm *PluginManager) startInOrder(ctx context.Context, names []string, stopped map[string]bool) {
+	pm.mu.RLock()
+	ordered := pm.orderByDependencies(names)
+	pm.mu.RUnlock()
+
+	failed := make(map[string]bool)
+	for _, name := range ordered {
+		if stopped[name] && !containsString(names, name) {
+			log.Printf("[PluginMgr] Not starting %s for its dependents: an admin stopped it", name)
 			continue
 		}
 
-		var meta PluginMeta
-		if err := json.NewDecoder(metaFile).Decode(&meta); err != nil {
-			metaFile.Close()
+		pm.mu.RLock()
+		var deps []string
+		running := false
+		if state, exists := pm.plugins[name]; exists {
+			deps = state.Info.DependsOn
+			running = state.Status == StatusRunning
+		}
+		pm.mu.RUnlock()
+
+		if running {
 			continue
 		}
-		metaFile.Close()
 
-		// Register in plugins map (but don't start)
-		pm.plugins[meta.Name] = &PluginState{
-			Info:   &meta,
-			Status: "stopped",
+		skip := false
+		for _, dep := range deps {
+			if failed[dep] {
+				log.Printf("[PluginMgr] Skipping auto-start of %s: dependency %s failed to start", name, dep)
+				skip = true
+				break
+			}
+		}
+		if skip {
+			failed[name] = true
+			continue
 		}
-	}
-	return nil
-}
 
-// AutoStartPlugins starts all plugins marked for auto-start
-func (pm *PluginManager) AutoStartPlugins(ctx context.Context, autoStart []string) {
-	for _, name := range autoStart {
-		if err := pm.StartPlugin(ctx, name); err != nil {
+		if err := pm.startPlugin(ctx, name, false); err != nil {
 			log.Printf("[PluginMgr] Failed to auto-start %s: %v", name, err)
+			failed[name] = true
+			continue
+		}
+
+		// A dependency started for its dependents is wanted from now on
+		if !containsString(names, name) {
+			pm.mu.RLock()
+			unset := pm.plugins[name].Info.Desired == ""
+			pm.mu.RUnlock()
+			if unset {
+				pm.setDesired(name, DesiredEnabled)
+			}
 		}
 	}
 }
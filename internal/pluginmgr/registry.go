@@ -0,0 +1,467 @@
+package pluginmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Installed plugins are recorded in a single registry file in the config
+// directory, next to a copy of its previous contents:
+//
+//	<config_dir>/registry.json
+//	<config_dir>/registry.json.bak
+//
+// Every change rewrites the file atomically, so it always holds a complete
+// set of records. Older cores kept one <name>.json per plugin; those are
+// migrated into the registry the first time it is opened.
+const (
+	registryFile   = "registry.json"
+	registrySchema = 1 // current layout of the registry file
+)
+
+// registryData is the content of the registry file
+type registryData struct {
+	Schema  int                        `json:"schema"`
+	Plugins map[string]json.RawMessage `json:"plugins"`
+}
+
+// registry owns the records of installed plugins: their manifests, current
+// and previous versions, desired state, install source and checksums.
+// Changes are transactions: a change that cannot be written is not applied.
+// Records are handed out as copies, so callers cannot change them behind
+// the registry's back.
+type registry struct {
+	mu       sync.Mutex
+	path     string
+	storeDir string // version store, for rebuilding lost records
+	loaded   bool
+	err      error                      // why the registry could not be opened
+	records  map[string]json.RawMessage // plugin name -> encoded PluginMeta
+	data     []byte                     // file contents last written or read
+}
+
+func newRegistry(configDir, pluginDir string) *registry {
+	return &registry{
+		path:     filepath.Join(configDir, registryFile),
+		storeDir: filepath.Join(pluginDir, versionsDir),
+	}
+}
+
+// notInstalledError is returned for plugins without a record
+type notInstalledError struct{ name string }
+
+func (e *notInstalledError) Error() string {
+	return fmt.Sprintf("plugin %s not found, install it first", e.name)
+}
+
+func errNotInstalled(name string) error {
+	return &notInstalledError{name: name}
+}
+
+// isNotInstalled reports whether err says a plugin has no record
+func isNotInstalled(err error) bool {
+	var e *notInstalledError
+	return errors.As(err, &e)
+}
+
+// get returns a copy of a plugin's record
+func (r *registry) get(name string) (*PluginMeta, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openLocked(); err != nil {
+		return nil, err
+	}
+	raw, ok := r.records[name]
+	if !ok {
+		return nil, errNotInstalled(name)
+	}
+	return decodeRecord(raw)
+}
+
+// list returns copies of every record, sorted by name
+func (r *registry) list() ([]*PluginMeta, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openLocked(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(r.records))
+	for name := range r.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metas := make([]*PluginMeta, 0, len(names))
+	for _, name := range names {
+		meta, err := decodeRecord(r.records[name])
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+// put creates or replaces a plugin's record
+func (r *registry) put(meta *PluginMeta) error {
+	if meta.Name == "" {
+		return fmt.Errorf("plugin record without a name")
+	}
+	raw, err := encodeRecord(meta)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openLocked(); err != nil {
+		return err
+	}
+	return r.commitLocked(func(records map[string]json.RawMessage) {
+		records[meta.Name] = raw
+	})
+}
+
+// update changes a plugin's record with fn and returns the result. Nothing
+// is written if fn fails or leaves the record as it was.
+func (r *registry) update(name string, fn func(meta *PluginMeta) error) (*PluginMeta, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openLocked(); err != nil {
+		return nil, err
+	}
+	prev, ok := r.records[name]
+	if !ok {
+		return nil, errNotInstalled(name)
+	}
+	meta, err := decodeRecord(prev)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(meta); err != nil {
+		return nil, err
+	}
+	meta.Name = name
+
+	raw, err := encodeRecord(meta)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(raw, prev) {
+		return meta, nil
+	}
+	if err := r.commitLocked(func(records map[string]json.RawMessage) {
+		records[name] = raw
+	}); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// remove deletes a plugin's record and returns it, nil if there was none
+func (r *registry) remove(name string) (*PluginMeta, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.openLocked(); err != nil {
+		return nil, err
+	}
+	raw, ok := r.records[name]
+	if !ok {
+		return nil, nil
+	}
+	meta, err := decodeRecord(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.commitLocked(func(records map[string]json.RawMessage) {
+		delete(records, name)
+	}); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// encodeRecord encodes a record in the form update compares with
+func encodeRecord(meta *PluginMeta) (json.RawMessage, error) {
+	return json.Marshal(meta)
+}
+
+func decodeRecord(raw json.RawMessage) (*PluginMeta, error) {
+	var meta PluginMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("invalid plugin meta: %w", err)
+	}
+	return &meta, nil
+}
+
+// commitLocked applies change to a copy of the records and writes them.
+// The records are only replaced once the file is on disk.
+func (r *registry) commitLocked(change func(records map[string]json.RawMessage)) error {
+	records := make(map[string]json.RawMessage, len(r.records)+1)
+	for name, raw := range r.records {
+		records[name] = raw
+	}
+	change(records)
+
+	data, err := json.MarshalIndent(&registryData{Schema: registrySchema, Plugins: records}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	// The previous contents are kept to fall back on if the file is damaged
+	if len(r.data) > 0 {
+		if err := writeFileAtomic(r.path+".bak", r.data, 0644); err != nil {
+			return fmt.Errorf("failed to back up plugin registry: %w", err)
+		}
+	}
+	if err := writeFileAtomic(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plugin registry: %w", err)
+	}
+	r.records = records
+	r.data = data
+	return nil
+}
+
+// openLocked loads the registry the first time it is used. A damaged file
+// is replaced by its backup or, failing that, rebuilt from the version
+// store. A registry that cannot be read at all stays closed, so that it is
+// never overwritten with less than it holds.
+func (r *registry) openLocked() error {
+	if r.loaded {
+		return r.err
+	}
+	r.loaded = true
+	r.err = r.load()
+	if r.err != nil {
+		log.Printf("[PluginMgr] Plugin registry unavailable: %v", r.err)
+	}
+	return r.err
+}
+
+func (r *registry) load() error {
+	data, err := os.ReadFile(r.path)
+	if err == nil {
+		var reg *registryData
+		if reg, err = parseRegistry(data); err == nil {
+			r.records, r.data = reg.Plugins, data
+			return nil
+		}
+		if errors.Is(err, errNewerRegistry) {
+			return err
+		}
+		log.Printf("[PluginMgr] Plugin registry %s is damaged: %v", r.path, err)
+		r.quarantine(r.path)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read plugin registry: %w", err)
+	}
+
+	// A crash may have left only the backup behind
+	if data, err := os.ReadFile(r.path + ".bak"); err == nil {
+		reg, err := parseRegistry(data)
+		if err == nil {
+			log.Printf("[PluginMgr] Restoring plugin registry from %s.bak", r.path)
+			r.records = reg.Plugins
+			return r.commitLocked(func(map[string]json.RawMessage) {})
+		}
+		if errors.Is(err, errNewerRegistry) {
+			return err
+		}
+		log.Printf("[PluginMgr] Plugin registry backup is damaged: %v", err)
+	}
+
+	r.records = make(map[string]json.RawMessage)
+	legacy, err := r.migrateLegacy()
+	if err != nil {
+		return err
+	}
+	// Plugins whose records were lost are brought back from the version
+	// store
+	r.rebuild()
+	if len(r.records) == 0 {
+		return nil
+	}
+	if err := r.commitLocked(func(map[string]json.RawMessage) {}); err != nil {
+		return err
+	}
+
+	// The old files are kept under another name in case the core is
+	// downgraded again
+	for _, f := range legacy {
+		if err := os.Rename(f, f+".migrated"); err != nil {
+			log.Printf("[PluginMgr] Failed to retire %s: %v", f, err)
+		}
+	}
+	return nil
+}
+
+// errNewerRegistry is returned for a registry written by a newer core
+var errNewerRegistry = errors.New("written by a newer version of the core")
+
+// parseRegistry decodes a registry file. Schema 1 is the only one there
+// has been; the per-plugin files before it are handled by migrateLegacy.
+func parseRegistry(data []byte) (*registryData, error) {
+	var reg registryData
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, err
+	}
+	if reg.Schema < 1 {
+		return nil, fmt.Errorf("missing schema version")
+	}
+	if reg.Schema > registrySchema {
+		return nil, fmt.Errorf("plugin registry schema %d is %w", reg.Schema, errNewerRegistry)
+	}
+	if reg.Plugins == nil {
+		reg.Plugins = make(map[string]json.RawMessage)
+	}
+	for name, raw := range reg.Plugins {
+		meta, err := decodeRecord(raw)
+		if err == nil && meta.Name != name {
+			err = fmt.Errorf("record names plugin %q", meta.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record of %s: %w", name, err)
+		}
+		if reg.Plugins[name], err = encodeRecord(meta); err != nil {
+			return nil, err
+		}
+	}
+	return &reg, nil
+}
+
+// quarantine moves a damaged file aside, keeping it for inspection
+func (r *registry) quarantine(path string) {
+	if err := os.Rename(path, path+".corrupt"); err != nil {
+		log.Printf("[PluginMgr] Failed to move damaged %s aside: %v", path, err)
+	}
+}
+
+// migrateLegacy reads the <name>.json files of older cores into the
+// records and returns the files it took in. Files that do not decode are
+// moved aside; their plugins are rebuilt from the version store.
+func (r *registry) migrateLegacy() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(r.path), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := make([]string, 0, len(files))
+	for _, f := range files {
+		if filepath.Base(f) == registryFile {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate %s: %w", f, err)
+		}
+		meta, err := decodeRecord(data)
+		if err == nil && meta.Name == "" {
+			err = fmt.Errorf("no plugin name")
+		}
+		if err != nil {
+			name := strings.TrimSuffix(filepath.Base(f), ".json")
+			log.Printf("[PluginMgr] Plugin meta %s is damaged: %v", f, err)
+			r.quarantine(f)
+			if meta := r.recoverRecord(name); meta != nil {
+				r.records[name], _ = encodeRecord(meta)
+			}
+			continue
+		}
+		if r.records[meta.Name], err = encodeRecord(meta); err != nil {
+			return nil, err
+		}
+		migrated = append(migrated, f)
+	}
+	if len(migrated) > 0 {
+		log.Printf("[PluginMgr] Migrated %d plugin meta file(s) into %s", len(migrated), r.path)
+	}
+	return migrated, nil
+}
+
+// rebuild recovers the record of every plugin in the version store
+func (r *registry) rebuild() {
+	entries, err := os.ReadDir(r.storeDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !validStoreName.MatchString(e.Name()) {
+			continue
+		}
+		if _, exists := r.records[e.Name()]; exists {
+			continue
+		}
+		if meta := r.recoverRecord(e.Name()); meta != nil {
+			r.records[meta.Name], _ = encodeRecord(meta)
+		}
+	}
+}
+
+// recoverRecord rebuilds a plugin's record from the newest version in the
+// version store that still has its binary. What belonged to the
+// installation, such as approvals and holds, is lost: the plugin has to be
+// approved and started again.
+func (r *registry) recoverRecord(name string) *PluginMeta {
+	entries, err := os.ReadDir(filepath.Join(r.storeDir, name))
+	if err != nil {
+		return nil
+	}
+	versions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	sortVersions(versions)
+
+	pluginDir := filepath.Dir(r.storeDir)
+	for _, version := range versions {
+		data, err := os.ReadFile(filepath.Join(r.storeDir, name, version, "meta.json"))
+		if err != nil {
+			continue
+		}
+		meta, err := decodeRecord(data)
+		if err != nil || meta.Name != name || meta.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pluginDir, meta.Path)); err != nil {
+			continue
+		}
+		meta.Approval = ApprovalPending
+		meta.Desired = DesiredDisabled
+		log.Printf("[PluginMgr] Recovered plugin %s v%s from the version store; approve and start it again", name, meta.Version)
+		return meta
+	}
+	log.Printf("[PluginMgr] Cannot recover plugin %s: no usable version in the version store", name)
+	return nil
+}
+
+// checkBinary compares a plugin's binary with the checksum recorded when it
+// was installed
+func checkBinary(meta *PluginMeta, path string) error {
+	if meta.SHA256 == "" {
+		return nil
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, meta.SHA256) {
+		return fmt.Errorf("binary of plugin %s was modified since it was installed (sha256 %s, recorded %s); reinstall it",
+			meta.Name, sum, meta.SHA256)
+	}
+	return nil
+}
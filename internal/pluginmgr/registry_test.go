@@ -0,0 +1,43 @@
+package pluginmgr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRegistrySchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		ok    bool
+		newer bool // rejected as written by a newer core
+	}{
+		{"current", `{"schema": 1, "plugins": {"echo": {"name": "echo", "version": "1.0.0"}}}`, true, false},
+		{"empty", `{"schema": 1}`, true, false},
+		{"missing schema", `{"plugins": {}}`, false, false},
+		{"negative schema", `{"schema": -1, "plugins": {}}`, false, false},
+		{"newer schema", `{"schema": 2, "plugins": {}}`, false, true},
+		{"misnamed record", `{"schema": 1, "plugins": {"echo": {"name": "other"}}}`, false, false},
+		{"not json", `schema: 1`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := parseRegistry([]byte(tt.data))
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("expected the registry to parse, got %v", err)
+				}
+				if reg.Plugins == nil {
+					t.Fatal("parsed registry has no plugin map")
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected the registry to be rejected")
+			}
+			if errors.Is(err, errNewerRegistry) != tt.newer {
+				t.Fatalf("got %v, newer registry expected: %v", err, tt.newer)
+			}
+		})
+	}
+}
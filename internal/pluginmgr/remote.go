@@ -0,0 +1,229 @@
+package pluginmgr
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// SetRemoteAuth configures which credentials remote plugins may register
+// with. token is accepted for any plugin name; keys maps a plugin name to a
+// key accepted for that plugin only. With neither set, registration is off.
+func (pm *PluginManager) SetRemoteAuth(token string, keys map[string]string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.remoteToken = token
+	pm.remoteKeys = make(map[string]string)
+	for name, key := range keys {
+		pm.remoteKeys[name] = key
+	}
+}
+
+// authorizeRemoteLocked checks a remote plugin's credential
+func (pm *PluginManager) authorizeRemoteLocked(name, token string) bool {
+	if token == "" {
+		return false
+	}
+	if key, ok := pm.remoteKeys[name]; ok && key != "" {
+		return subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1
+	}
+	if pm.remoteToken != "" {
+		return subtle.ConstantTimeCompare([]byte(pm.remoteToken), []byte(token)) == 1
+	}
+	return false
+}
+
+// registerRemote adds a self-registered plugin as running
+func (pm *PluginManager) registerRemote(hello *pb.Hello, session *streamSession) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// The name ends up in log, queue and settings file paths
+	if !validStoreName.MatchString(hello.PluginName) {
+		return fmt.Errorf("invalid plugin name %q", hello.PluginName)
+	}
+	if !pm.authorizeRemoteLocked(hello.PluginName, hello.Token) {
+		return fmt.Errorf("invalid registration token for plugin %s", hello.PluginName)
+	}
+	if hello.Info == nil || hello.Info.Name != hello.PluginName {
+		return fmt.Errorf("plugin info does not match plugin name %s", hello.PluginName)
+	}
+
+	if state, exists := pm.plugins[hello.PluginName]; exists {
+		if !state.Remote {
+			return fmt.Errorf("plugin %s is installed locally", hello.PluginName)
+		}
+		return fmt.Errorf("plugin %s is already connected", hello.PluginName)
+	}
+
+	meta := metaFromInfo(hello.Info)
+	if err := checkCompatible(meta); err != nil {
+		return err
+	}
+	matcher, err := compileMatcher(meta)
+	if err != nil {
+		return err
+	}
+	if err := pm.checkCommandConflictsLocked(meta); err != nil {
+		return err
+	}
+
+	// A remote plugin that declares no capabilities gets none
+	if meta.Capabilities == nil {
+		meta.Capabilities = &Capabilities{}
+	}
+	pm.remoteApprovalLocked(meta)
+
+	state := &PluginState{
+		Info:      meta,
+		Client:    session,
+		Protocol:  ProtocolVersion,
+		Status:    StatusStopped,
+		StartedAt: time.Now(),
+		Remote:    true,
+		matcher:   matcher,
+	}
+	pm.plugins[meta.Name] = state
+
+	// Until an admin approves what it declares, the plugin stays connected
+	// but gets no messages and may only log
+	if !meta.Approved() {
+		pm.grant(meta.Name, &Capabilities{})
+		log.Printf("[PluginMgr] Registered remote plugin: %s v%s, capabilities awaiting approval: %s",
+			meta.Name, meta.Version, meta.Capabilities)
+		return nil
+	}
+
+	pm.activateRemoteLocked(state)
+	log.Printf("[PluginMgr] Registered remote plugin: %s v%s", meta.Name, meta.Version)
+	return nil
+}
+
+// remoteApprovalLocked decides the approval state of a registering remote
+// plugin the way carryApproval does for an installed one. Approvals are
+// kept per plugin name while the core runs, so a plugin reconnecting with
+// nothing beyond what was approved is let in again.
+func (pm *PluginManager) remoteApprovalLocked(meta *PluginMeta) {
+	if prev, ok := pm.remoteApprovals[meta.Name]; ok && prev.covers(meta.Capabilities) {
+		meta.Approval = ApprovalApproved
+		meta.ApprovedCapabilities = prev
+		return
+	}
+	if meta.Capabilities.empty() {
+		meta.Approval = ApprovalApproved
+		meta.ApprovedCapabilities = meta.Capabilities
+		return
+	}
+	meta.Approval = ApprovalPending
+}
+
+// approveRemoteLocked grants a connected remote plugin what it declares
+// and starts dispatching to it
+func (pm *PluginManager) approveRemoteLocked(state *PluginState) (*PluginMeta, error) {
+	meta := state.Info
+	if meta.Approved() {
+		return meta, nil
+	}
+	if err := pm.checkCommandConflictsLocked(meta); err != nil {
+		return nil, err
+	}
+
+	pm.remoteApprovals[meta.Name] = meta.Capabilities
+	meta.Approval = ApprovalApproved
+	meta.ApprovedCapabilities = meta.Capabilities
+	pm.activateRemoteLocked(state)
+
+	log.Printf("[PluginMgr] Approved capabilities of remote plugin %s: %s", meta.Name, meta.Capabilities)
+	return meta, nil
+}
+
+// activateRemoteLocked puts an approved remote plugin into dispatch
+func (pm *PluginManager) activateRemoteLocked(state *PluginState) {
+	meta := state.Info
+	pm.grant(meta.Name, meta.Granted())
+	pm.claimCommandsLocked(meta)
+
+	if pm.bus != nil && len(meta.EventTopics) > 0 {
+		pm.bus.Attach(meta.Name, meta.EventTopics, pm.eventHandler(state))
+	}
+	state.Status = StatusRunning
+}
+
+// dropHeldRemote deregisters a remote plugin whose stream closed while it
+// was still awaiting approval
+func (pm *PluginManager) dropHeldRemote(session *streamSession) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	state, exists := pm.plugins[session.name]
+	if !exists || !state.Remote || state.Status == StatusRunning || state.Client != session {
+		return
+	}
+	pm.deregisterRemoteLocked(state)
+	log.Printf("[PluginMgr] Remote plugin %s disconnected before approval, deregistered", session.name)
+}
+
+// deregisterRemoteLocked forgets a remote plugin. Caller must hold pm.mu.
+func (pm *PluginManager) deregisterRemoteLocked(state *PluginState) {
+	pm.releaseCommandsLocked(state.Info)
+	if pm.bus != nil {
+		pm.bus.Detach(state.Info.Name)
+	}
+	delete(pm.plugins, state.Info.Name)
+	pm.ungrant(state.Info.Name)
+}
+
+// metaFromInfo converts the PluginInfo announced over gRPC
+func metaFromInfo(info *pb.PluginInfo) *PluginMeta {
+	meta := &PluginMeta{
+		Name:              info.Name,
+		Version:           info.Version,
+		Description:       info.Description,
+		Author:            info.Author,
+		Commands:          info.Commands,
+		HandleAllMessages: info.HandleAllMessages,
+		Priority:          int(info.Priority),
+		ObserveOnly:       info.ObserveOnly,
+		EventTopics:       info.EventTopics,
+		Methods:           info.Methods,
+		DependsOn:         info.DependsOn,
+		ProtocolVersion:   info.ProtocolVersion,
+		SDKVersion:        info.SdkVersion,
+		Features:          info.Features,
+		CoreVersion:       info.CoreVersion,
+	}
+	if c := info.Capabilities; c != nil {
+		meta.Capabilities = &Capabilities{
+			RPCs:        c.Rpcs,
+			Actions:     c.Actions,
+			Groups:      c.Groups,
+			UploadPaths: c.UploadPaths,
+		}
+	}
+	if f := info.MessageFilter; f != nil {
+		meta.MessageFilter = &MessageFilter{
+			Keywords:     f.Keywords,
+			Patterns:     f.Patterns,
+			GroupIDs:     f.GroupIds,
+			UserIDs:      f.UserIds,
+			SegmentTypes: f.SegmentTypes,
+			MessageTypes: f.MessageTypes,
+		}
+	}
+	for _, f := range info.ConfigSchema {
+		meta.ConfigSchema = append(meta.ConfigSchema, ConfigField{
+			Key:         f.Key,
+			Type:        f.Type,
+			Description: f.Description,
+			Default:     f.Default,
+			Required:    f.Required,
+			Secret:      f.Secret,
+			Options:     f.Options,
+		})
+	}
+	return meta
+}
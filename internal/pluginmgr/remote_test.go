@@ -0,0 +1,179 @@
+package pluginmgr
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// remotePlugin is the plugin side of a remote registration: it connects
+// over the PluginHost stream and acks what the core sends it
+type remotePlugin struct {
+	stream  pb.PluginHost_ConnectClient
+	healthy atomic.Bool // whether health checks are answered
+}
+
+// startHost serves the PluginHost service of pm on a loopback port
+func startHost(t *testing.T, pm *PluginManager) *grpc.ClientConn {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterPluginHostServer(srv, pm.HostServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// connectRemote registers a plugin named name with token and returns it
+// once the core welcomed it, or the error the core rejected it with
+func connectRemote(t *testing.T, conn *grpc.ClientConn, name, token string) (*remotePlugin, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream, err := pb.NewPluginHostClient(conn).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&pb.PluginFrame{Frame: &pb.PluginFrame_Hello{Hello: &pb.Hello{
+		PluginName:      name,
+		Token:           token,
+		ProtocolVersion: ProtocolVersion,
+		InitialCredits:  8,
+		Info:            &pb.PluginInfo{Name: name, Version: "1.0.0", Commands: []string{name + "-cmd"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if frame.GetWelcome() == nil {
+		t.Fatalf("expected welcome, got %v", frame)
+	}
+
+	p := &remotePlugin{stream: stream}
+	p.healthy.Store(true)
+	go p.serve()
+	return p, nil
+}
+
+func (p *remotePlugin) serve() {
+	for {
+		frame, err := p.stream.Recv()
+		if err != nil {
+			return
+		}
+		event := frame.GetEvent()
+		if event == nil {
+			continue
+		}
+
+		ack := &pb.Ack{Seq: event.Seq, Credits: 1}
+		if event.GetHealth() != nil {
+			if !p.healthy.Load() {
+				continue
+			}
+			ack.Result = &pb.Ack_Health{Health: &pb.HealthResponse{Healthy: true}}
+		} else {
+			ack.Result = &pb.Ack_Empty{Empty: &pb.Empty{}}
+		}
+		p.stream.Send(&pb.PluginFrame{Frame: &pb.PluginFrame_Ack{Ack: ack}})
+	}
+}
+
+// listed returns the plugin named name as ListPlugins reports it
+func listed(pm *PluginManager, name string) *PluginState {
+	for _, state := range pm.ListPlugins() {
+		if state.Info.Name == name {
+			return state
+		}
+	}
+	return nil
+}
+
+func TestRemoteRegistration(t *testing.T) {
+	pm := NewPluginManager(t.TempDir(), t.TempDir(), 0)
+	t.Cleanup(pm.Shutdown)
+	pm.SetRemoteAuth("shared-token", map[string]string{"keyed": "keyed-key", "locked": "locked-key"})
+	conn := startHost(t, pm)
+
+	tests := []struct {
+		name   string
+		plugin string
+		token  string
+		ok     bool
+	}{
+		{"shared token", "shared", "shared-token", true},
+		{"per-plugin key", "keyed", "keyed-key", true},
+		{"bad token", "intruder", "guess", false},
+		{"shared token for keyed plugin", "locked", "shared-token", false},
+		{"no token", "anonymous", "", false},
+		{"unsafe name", "../escape", "shared-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := connectRemote(t, conn, tt.plugin, tt.token)
+			if !tt.ok {
+				if status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("expected PermissionDenied, got %v", err)
+				}
+				if listed(pm, tt.plugin) != nil {
+					t.Fatalf("rejected plugin %s is listed", tt.plugin)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("registration failed: %v", err)
+			}
+			state := listed(pm, tt.plugin)
+			if state == nil || !state.Remote || state.Status != "running" {
+				t.Fatalf("expected %s listed as a running remote plugin, got %+v", tt.plugin, state)
+			}
+		})
+	}
+}
+
+func TestRemoteHealthCheck(t *testing.T) {
+	pm := NewPluginManager(t.TempDir(), t.TempDir(), 0)
+	t.Cleanup(pm.Shutdown)
+	pm.SetRemoteAuth("shared-token", nil)
+	conn := startHost(t, pm)
+
+	p, err := connectRemote(t, conn, "remote", "shared-token")
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+
+	pm.checkPluginHealth()
+	if listed(pm, "remote") == nil {
+		t.Fatal("healthy remote plugin was deregistered")
+	}
+
+	p.healthy.Store(false)
+	pm.checkPluginHealth()
+	if state := listed(pm, "remote"); state != nil {
+		t.Fatalf("remote plugin failing its health check is still listed: %+v", state)
+	}
+}
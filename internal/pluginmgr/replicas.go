@@ -0,0 +1,664 @@
+package pluginmgr
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// A plugin may run as several replicas, each a process of its own with its
+// own port or stream. The first replica is the plugin's PluginState and
+// carries its commands, subscriptions and status; the others hang off it
+// in PluginState.replicas. Every call goes to the running replica with the
+// fewest calls in progress, unless the plugin keeps state per group or
+// user and asks for sticky routing, which sends all calls about one group
+// or user to the same replica.
+//
+// Replicas are health-checked and restarted one by one under the restart
+// policy. When the first replica crashes, another running replica takes
+// over carrying the plugin and the crashed one is restarted as a replica;
+// the plugin only goes down when none of its replicas runs. Each replica
+// keeps its sticky slot through restarts and takeovers, so calls about a
+// group or user stay with the same process as long as it runs.
+
+// MaxReplicas bounds how many replicas a plugin may run
+const MaxReplicas = 16
+
+// Sticky routing modes kept in PluginMeta.Sticky
+const (
+	StickyNone  = ""      // every call goes to the least busy replica
+	StickyGroup = "group" // calls about one group, or one user in private, share a replica
+	StickyUser  = "user"  // calls about one user share a replica
+)
+
+// ParseSticky checks a sticky routing mode; "none" and "" disable it
+func ParseSticky(mode string) (string, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "", "none", "off":
+		return StickyNone, nil
+	case StickyGroup, StickyUser:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid sticky routing %q: expected none, group or user", mode)
+}
+
+// ReplicaCount returns how many replicas of the plugin should run
+func (meta *PluginMeta) ReplicaCount() int {
+	if meta.Replicas < 1 {
+		return 1
+	}
+	return meta.Replicas
+}
+
+// ReplicaStatus describes one replica of a plugin
+type ReplicaStatus struct {
+	Replica   int       `json:"replica"` // kept through restarts and takeovers
+	Status    string    `json:"status"`
+	PID       int       `json:"pid,omitempty"`
+	Port      int       `json:"port,omitempty"`
+	Inflight  int64     `json:"inflight"` // calls in progress
+	StartedAt time.Time `json:"started_at"`
+	Restarts  int       `json:"restarts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Replicas describes the replicas of a running plugin, nil if it is not
+// running
+func (pm *PluginManager) Replicas(state *PluginState) []ReplicaStatus {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if state.Status != StatusRunning {
+		return nil
+	}
+	result := make([]ReplicaStatus, 0, 1+len(state.replicas))
+	for _, replica := range state.slotsLocked() {
+		status := ReplicaStatus{
+			Replica:   replica.slot + 1,
+			Status:    replica.Status,
+			Port:      replica.Port,
+			Inflight:  replica.inflight.Load(),
+			StartedAt: replica.StartedAt,
+			Restarts:  replica.Restarts,
+			LastError: replica.LastError,
+		}
+		if replica.Status == StatusRunning && replica.Process != nil {
+			status.PID = replica.Process.Pid
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// freeInstancesLocked names count new replicas of a plugin, skipping the
+// names its replicas already run under: after a takeover the first replica
+// is not necessarily the one named after the plugin. Caller must hold
+// pm.mu.
+func (s *PluginState) freeInstancesLocked(count int) []string {
+	base := ""
+	if greenInstance(s.instance) {
+		base = "green"
+	}
+	used := map[string]bool{s.instance: true}
+	for _, replica := range s.replicas {
+		used[replica.instance] = true
+	}
+
+	names := make([]string, 0, count)
+	for n := 2; len(names) < count; n++ {
+		if name := replicaInstance(base, n); !used[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// replicaInstance names the instance of replica n of a plugin whose first
+// replica runs as instance base
+func replicaInstance(base string, n int) string {
+	if base == "" {
+		return strconv.Itoa(n)
+	}
+	return base + "-" + strconv.Itoa(n)
+}
+
+// slotsLocked returns the replicas of a running plugin, itself included,
+// ordered by sticky slot. Caller must hold pm.mu.
+func (s *PluginState) slotsLocked() []*PluginState {
+	slots := make([]*PluginState, 1+len(s.replicas))
+	for _, replica := range append([]*PluginState{s}, s.replicas...) {
+		slots[replica.slot] = replica
+	}
+	return slots
+}
+
+// renumberLocked gives the replicas of a running plugin consecutive sticky
+// slots again after some were removed, keeping their order. Caller must
+// hold pm.mu.
+func (s *PluginState) renumberLocked() {
+	slots := append([]*PluginState{s}, s.replicas...)
+	sort.Slice(slots, func(i, j int) bool { return slots[i].slot < slots[j].slot })
+	for i, replica := range slots {
+		replica.slot = i
+	}
+}
+
+// pickLocked chooses the replica of a running plugin that handles a call
+// about msg, which is nil for calls not about a message. Caller must hold
+// pm.mu.
+func (s *PluginState) pickLocked(msg *pb.MessageEvent) *PluginState {
+	if len(s.replicas) == 0 {
+		return s
+	}
+	slots := s.slotsLocked()
+
+	// A sticky replica that is down hands its calls to the others until
+	// it is back
+	if key, ok := stickyKey(s.Info.Sticky, msg); ok {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		if slot := slots[h.Sum32()%uint32(len(slots))]; slot.Status == StatusRunning {
+			return slot
+		}
+	}
+
+	// Least calls in progress; equally busy replicas take turns
+	start := int(s.rotation.Add(1) % uint32(len(slots)))
+	var best *PluginState
+	for i := range slots {
+		slot := slots[(start+i)%len(slots)]
+		if slot.Status != StatusRunning {
+			continue
+		}
+		if best == nil || slot.inflight.Load() < best.inflight.Load() {
+			best = slot
+		}
+	}
+	if best == nil {
+		return s
+	}
+	return best
+}
+
+// stickyKey returns what a call about msg is routed by in the given
+// sticky mode
+func stickyKey(mode string, msg *pb.MessageEvent) (string, bool) {
+	if msg == nil {
+		return "", false
+	}
+	switch {
+	case mode == StickyGroup && msg.GroupId != 0:
+		return "group:" + strconv.FormatInt(msg.GroupId, 10), true
+	case (mode == StickyGroup || mode == StickyUser) && msg.UserId != 0:
+		return "user:" + strconv.FormatInt(msg.UserId, 10), true
+	}
+	return "", false
+}
+
+// instancesLocked returns the running replicas of a running plugin,
+// itself first. Caller must hold pm.mu.
+func (s *PluginState) instancesLocked() []*PluginState {
+	instances := []*PluginState{s}
+	for _, replica := range s.replicas {
+		if replica.Status == StatusRunning {
+			instances = append(instances, replica)
+		}
+	}
+	return instances
+}
+
+// replicaNumberLocked returns the number of a replica of state, 0 if it is
+// not one of them. Caller must hold pm.mu.
+func (s *PluginState) replicaNumberLocked(replica *PluginState) int {
+	for _, r := range s.replicas {
+		if r == replica {
+			return r.slot + 1
+		}
+	}
+	return 0
+}
+
+// successorLocked returns a running replica that can take over from the
+// first one, nil if none runs. Caller must hold pm.mu.
+func (s *PluginState) successorLocked() *PluginState {
+	var best *PluginState
+	for _, replica := range s.replicas {
+		if replica.Status != StatusRunning {
+			continue
+		}
+		if best == nil || replica.inflight.Load() < best.inflight.Load() {
+			best = replica
+		}
+	}
+	return best
+}
+
+// takeOverLocked hands a plugin whose first replica crashed to successor,
+// another of its replicas that runs. The crashed replica takes the
+// successor's place among the replicas and is restarted like any of them;
+// every replica keeps its process and its sticky slot. Caller must hold
+// pm.mu.
+func (pm *PluginManager) takeOverLocked(name string, crashed, successor *PluginState, failed bool) {
+	replicas := make([]*PluginState, 0, len(crashed.replicas))
+	for _, replica := range crashed.replicas {
+		if replica == successor {
+			replica = crashed
+		}
+		replicas = append(replicas, replica)
+	}
+	crashed.replicas = nil
+	successor.replicas = replicas
+	pm.plugins[name] = successor
+
+	if pm.bus != nil && len(successor.Info.EventTopics) > 0 {
+		pm.bus.Attach(name, successor.Info.EventTopics, pm.eventHandler(successor))
+	}
+	go pm.supervise(name, successor)
+
+	log.Printf("[PluginMgr] Replica %d of plugin %s crashed: %s; replica %d took over",
+		crashed.slot+1, name, crashed.LastError, successor.slot+1)
+	pm.scheduleReplicaRestartLocked(name, crashed, failed)
+}
+
+// replicaByClientLocked finds the running replica of a plugin that is
+// reached through client. Caller must hold pm.mu.
+func (pm *PluginManager) replicaByClientLocked(name string, client pb.PluginServiceClient) *PluginState {
+	state, exists := pm.plugins[name]
+	if !exists {
+		return nil
+	}
+	for _, replica := range state.replicas {
+		if replica.Status == StatusRunning && replica.Client == client {
+			return replica
+		}
+	}
+	return nil
+}
+
+// launchReplicas starts replicas from to to of a plugin whose first
+// replica is state. A replica that fails to start is returned in error and
+// retried under the restart policy once the replicas are attached; the
+// plugin runs with fewer meanwhile.
+func (pm *PluginManager) launchReplicas(ctx context.Context, state *PluginState, binaryPath string, from, to int) []*PluginState {
+	pm.mu.RLock()
+	instances := state.freeInstancesLocked(to - from + 1)
+	pm.mu.RUnlock()
+
+	launched := make([]*PluginState, 0)
+	for n := from; n <= to; n++ {
+		replica := &PluginState{
+			Info:     state.Info,
+			Status:   StatusStarting,
+			matcher:  state.matcher,
+			instance: instances[n-from],
+			slot:     n - 1,
+		}
+		if err := pm.launch(ctx, replica, binaryPath); err != nil {
+			log.Printf("[PluginMgr] Failed to start replica %d of plugin %s: %v", n, state.Info.Name, err)
+			replica.Status = StatusError
+			replica.LastError = err.Error()
+		}
+		launched = append(launched, replica)
+	}
+	return launched
+}
+
+// attachReplicasLocked adds launched replicas to a running plugin, which
+// makes them take calls. Caller must hold pm.mu.
+func (pm *PluginManager) attachReplicasLocked(state *PluginState, replicas []*PluginState) {
+	name := state.Info.Name
+	for _, replica := range replicas {
+		state.replicas = append(state.replicas, replica)
+		if replica.Status != StatusStarting {
+			pm.scheduleReplicaRestartLocked(name, replica, true)
+			continue
+		}
+		replica.Status = StatusRunning
+		replica.StartedAt = time.Now()
+		go pm.superviseReplica(name, replica)
+	}
+}
+
+// killReplicasLocked kills replicas that are starting or running and
+// frees what they took. Caller must hold pm.mu.
+func (pm *PluginManager) killReplicasLocked(replicas []*PluginState) {
+	for _, replica := range replicas {
+		if replica.Status == StatusStarting || replica.Status == StatusRunning {
+			replica.proc.kill()
+			pm.releaseInstance(replica)
+		}
+		replica.Status = StatusStopped
+	}
+}
+
+// stopReplicas shuts down instances that were taken out of dispatch and
+// marked stopping, all at once. With drain they first finish the calls
+// they are handling. It returns how many were stopped.
+func (pm *PluginManager) stopReplicas(ctx context.Context, replicas []*PluginState, drain bool) int {
+	pm.mu.RLock()
+	stopping := make([]*PluginState, 0, len(replicas))
+	for _, replica := range replicas {
+		if replica.Status == StatusStopping {
+			stopping = append(stopping, replica)
+		}
+	}
+	pm.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, replica := range stopping {
+		wg.Add(1)
+		go func(r *PluginState) {
+			defer wg.Done()
+			if drain {
+				pm.drain(ctx, r)
+			} else {
+				pm.shutdownInstance(ctx, r)
+			}
+		}(replica)
+	}
+	wg.Wait()
+
+	pm.mu.Lock()
+	for _, replica := range stopping {
+		replica.Status = StatusStopped
+	}
+	pm.mu.Unlock()
+	return len(stopping)
+}
+
+// carrierLocked returns the running plugin replica belongs to, nil if it
+// is not a replica of the current instance of the plugin. The plugin is
+// looked up afresh since another replica may have taken it over. Caller
+// must hold pm.mu.
+func (pm *PluginManager) carrierLocked(name string, replica *PluginState) *PluginState {
+	state, exists := pm.plugins[name]
+	if !exists || state.Status != StatusRunning || state.replicaNumberLocked(replica) == 0 {
+		return nil
+	}
+	return state
+}
+
+// liveReplicaLocked reports whether replica still runs as part of the
+// current instance of a plugin. Caller must hold pm.mu.
+func (pm *PluginManager) liveReplicaLocked(name string, replica *PluginState) bool {
+	return replica.Status == StatusRunning && pm.carrierLocked(name, replica) != nil
+}
+
+// superviseReplica waits for the process of a replica to exit. An exit
+// the manager did not ask for is handled as a crash of that replica.
+func (pm *PluginManager) superviseReplica(name string, replica *PluginState) {
+	<-replica.proc.done
+
+	pm.mu.RLock()
+	live := pm.liveReplicaLocked(name, replica)
+	pm.mu.RUnlock()
+	if !live {
+		return
+	}
+
+	pm.handleReplicaCrash(name, replica, "process "+replica.proc.exitDescription())
+}
+
+// checkReplicaHealth checks one replica of a running plugin
+func (pm *PluginManager) checkReplicaHealth(state, replica *PluginState) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, err := replica.Client.Health(ctx, &pb.Empty{})
+	cancel()
+
+	if err != nil {
+		log.Printf("[PluginMgr] Replica of plugin %s health check failed: %v", state.Info.Name, err)
+		pm.handleReplicaCrash(state.Info.Name, replica, "health check failed: "+err.Error())
+		return
+	}
+	pm.updateViolations(replica)
+}
+
+// handleReplicaCrash cleans up after a replica that exited, lost its
+// stream or failed a health check, and restarts it according to the
+// restart policy. The plugin's other replicas keep serving.
+func (pm *PluginManager) handleReplicaCrash(name string, replica *PluginState, reason string) {
+	pm.mu.RLock()
+	live := pm.liveReplicaLocked(name, replica)
+	pm.mu.RUnlock()
+	if !live {
+		return
+	}
+
+	// Make sure the process is gone, giving it a moment to exit on its own
+	killed := false
+	select {
+	case <-replica.proc.done:
+	case <-time.After(exitGrace):
+		replica.proc.kill()
+		killed = true
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.liveReplicaLocked(name, replica) {
+		return
+	}
+
+	failed := recordExit(replica, reason, killed)
+	replica.Status = StatusError
+	pm.releaseInstance(replica)
+
+	log.Printf("[PluginMgr] Replica %d of plugin %s crashed: %s", replica.slot+1, name, replica.LastError)
+	pm.scheduleReplicaRestartLocked(name, replica, failed)
+}
+
+// scheduleReplicaRestartLocked applies the restart policy to a replica
+// that crashed or failed to start. Caller must hold pm.mu.
+func (pm *PluginManager) scheduleReplicaRestartLocked(name string, replica *PluginState, failed bool) {
+	n := replica.slot + 1
+	switch pm.policyFor(name) {
+	case RestartNever:
+		log.Printf("[PluginMgr] Replica %d of plugin %s will not be restarted (policy %s)", n, name, RestartNever)
+		return
+	case RestartOnFailure:
+		if !failed {
+			replica.Status = StatusStopped
+			log.Printf("[PluginMgr] Replica %d of plugin %s exited cleanly, not restarting", n, name)
+			return
+		}
+	}
+
+	delay, ok := pm.backoffLocked(replica)
+	if !ok {
+		replica.Status = StatusCrashloop
+		log.Printf("[PluginMgr] Replica %d of plugin %s crashed %d times within %s, giving up (%s)",
+			n, name, len(replica.restarts)+1, pm.restartPolicy.Window, replica.LastError)
+		return
+	}
+
+	log.Printf("[PluginMgr] Restarting replica %d of plugin %s in %s (restart %d)", n, name, delay, replica.Restarts)
+
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-pm.stopHealth:
+			return // manager is shutting down
+		}
+		pm.restartReplica(name, replica)
+	}()
+}
+
+// pendingReplicaLocked reports whether a crashed replica still waits to be
+// restarted: the plugin runs and nobody removed or replaced the replica.
+// Caller must hold pm.mu.
+func (pm *PluginManager) pendingReplicaLocked(name string, replica *PluginState) bool {
+	return replica.Status == StatusError && pm.carrierLocked(name, replica) != nil
+}
+
+// restartReplica starts a crashed replica again in place of the old one
+func (pm *PluginManager) restartReplica(name string, replica *PluginState) {
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		// Try again later unless the admin busy with the plugin stops it
+		pm.mu.Lock()
+		if pm.pendingReplicaLocked(name, replica) {
+			pm.scheduleReplicaRestartLocked(name, replica, true)
+		}
+		pm.mu.Unlock()
+		return
+	}
+	defer unlock()
+
+	pm.mu.RLock()
+	state := pm.carrierLocked(name, replica)
+	pending := pm.pendingReplicaLocked(name, replica)
+	pm.mu.RUnlock()
+	if !pending {
+		return
+	}
+
+	fresh := &PluginState{
+		Info:     state.Info,
+		Status:   StatusStarting,
+		matcher:  state.matcher,
+		instance: replica.instance,
+		slot:     replica.slot,
+		Restarts: replica.Restarts,
+		restarts: replica.restarts,
+	}
+	binaryPath := pm.binaryPath(state.Info)
+	err = checkBinary(state.Info, binaryPath)
+	if err == nil {
+		err = pm.launch(context.Background(), fresh, binaryPath)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[PluginMgr] Failed to restart replica of plugin %s: %v", name, err)
+		if pm.pendingReplicaLocked(name, replica) {
+			replica.LastError = "restart failed: " + err.Error()
+			pm.scheduleReplicaRestartLocked(name, replica, true)
+		}
+		return
+	}
+	if !pm.pendingReplicaLocked(name, replica) {
+		fresh.proc.kill()
+		pm.releaseInstance(fresh)
+		return
+	}
+
+	// Another replica may have taken over the plugin meanwhile
+	state = pm.carrierLocked(name, replica)
+
+	for i, r := range state.replicas {
+		if r == replica {
+			state.replicas[i] = fresh
+		}
+	}
+	fresh.Status = StatusRunning
+	fresh.StartedAt = time.Now()
+	go pm.superviseReplica(name, fresh)
+	log.Printf("[PluginMgr] Successfully restarted replica %d of plugin %s", fresh.slot+1, name)
+}
+
+// SetReplicas sets how many replicas of an installed plugin run and how
+// calls are routed between them. A running plugin is scaled right away:
+// new replicas take calls once started, removed ones finish the calls
+// they are handling first.
+func (pm *PluginManager) SetReplicas(ctx context.Context, name string, replicas int, sticky string) error {
+	if replicas < 1 || replicas > MaxReplicas {
+		return fmt.Errorf("invalid replica count %d: expected 1 to %d", replicas, MaxReplicas)
+	}
+	sticky, err := ParseSticky(sticky)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	pm.mu.Lock()
+	state, exists := pm.plugins[name]
+	if exists && state.Remote {
+		pm.mu.Unlock()
+		return fmt.Errorf("plugin %s is a connected remote plugin, it runs where it was started", name)
+	}
+
+	stored := replicas
+	if stored == 1 {
+		stored = 0
+	}
+	_, err = pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.Replicas = stored
+		meta.Sticky = sticky
+		return nil
+	})
+	if err != nil {
+		pm.mu.Unlock()
+		return err
+	}
+
+	running := exists && state.Status == StatusRunning
+	current := 0
+	var removed []*PluginState
+	if exists {
+		state.Info.Replicas = stored
+		state.Info.Sticky = sticky
+	}
+	if running {
+		current = 1 + len(state.replicas)
+		if replicas < current {
+			// Out of dispatch right away; they are drained below
+			removed = append(removed, state.replicas[replicas-1:]...)
+			state.replicas = state.replicas[:replicas-1]
+			for _, replica := range removed {
+				if replica.Status == StatusRunning {
+					replica.Status = StatusStopping
+				}
+			}
+			state.renumberLocked()
+		}
+	}
+	pm.mu.Unlock()
+
+	switch {
+	case replicas == 1:
+		log.Printf("[PluginMgr] Plugin %s set to run as a single instance", name)
+	case sticky == StickyNone:
+		log.Printf("[PluginMgr] Plugin %s set to %d replicas", name, replicas)
+	default:
+		log.Printf("[PluginMgr] Plugin %s set to %d replicas, sticky by %s", name, replicas, sticky)
+	}
+
+	switch {
+	case !running:
+		return nil
+	case replicas < current:
+		n := pm.stopReplicas(ctx, removed, true)
+		log.Printf("[PluginMgr] Stopped %d replicas of plugin %s", n, name)
+	case replicas > current:
+		launched := pm.launchReplicas(ctx, state, pm.binaryPath(state.Info), current+1, replicas)
+
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+
+		// Another replica may have taken over the plugin meanwhile
+		state, exists = pm.plugins[name]
+		if !exists || state.Status != StatusRunning {
+			// Crashed meanwhile; a restart brings all replicas up
+			pm.killReplicasLocked(launched)
+			return fmt.Errorf("plugin %s stopped while its replicas were starting", name)
+		}
+		pm.attachReplicasLocked(state, launched)
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+package pluginmgr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Source fetches plugin binaries for installation. Install tries local
+// files, catalog names, GitHub-compatible release APIs and plain http(s)
+// URLs, in that order. Binaries may be packed in a .tar.gz or .zip
+// archive. The sources verify downloads against the checksums and
+// signatures published next to them.
+type Source interface {
+	// Name identifies the source kind, e.g. "github"
+	Name() string
+	// Match reports whether the source handles ref. A source that has to
+	// look ref up honors ctx.
+	Match(ctx context.Context, ref string) bool
+	// Fetch writes the plugin binary for ref to dest
+	Fetch(ctx context.Context, ref, dest string) (*Artifact, error)
+}
+
+// VersionLister is implemented by sources that publish several releases.
+// Their refs accept a release tag as "ref@tag".
+type VersionLister interface {
+	// Versions lists the release tags available for ref, newest first
+	Versions(ctx context.Context, ref string) ([]string, error)
+}
+
+// Artifact describes a fetched plugin binary
+type Artifact struct {
+	BinaryName string // file name the binary is installed under
+	Release    string // release tag, "" if the source has no releases
+	RepoURL    string // repo the binary was released from, if known
+	Verified   string // VerifiedSignature, VerifiedChecksum or VerifiedLocal, "" if unverified
+}
+
+// splitRef splits "ref@tag" into the ref and the tag. An @ before the last
+// slash belongs to the URL, e.g. user info.
+func splitRef(ref string) (string, string) {
+	i := strings.LastIndex(ref, "@")
+	if i < 0 || i < strings.LastIndex(ref, "/") {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// ReleaseAPI is a GitHub-compatible release API, such as GitHub
+// Enterprise, Gitea or a local stand-in
+type ReleaseAPI struct {
+	BaseURL string // e.g. "https://git.example.com/api/v1"
+	Token   string // sent as "Authorization: token ...", optional
+}
+
+// defaultReleaseAPIs serves repos on github.com
+var defaultReleaseAPIs = map[string]ReleaseAPI{
+	"github.com": {BaseURL: "https://api.github.com"},
+}
+
+// SetReleaseAPIs configures GitHub-compatible release APIs by repo host.
+// github.com is always known and may be overridden, e.g. to add a token.
+func (pm *PluginManager) SetReleaseAPIs(apis map[string]ReleaseAPI) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.releaseAPIs = make(map[string]ReleaseAPI)
+	for host, api := range defaultReleaseAPIs {
+		pm.releaseAPIs[host] = api
+	}
+	for host, api := range apis {
+		api.BaseURL = strings.TrimSuffix(api.BaseURL, "/")
+		pm.releaseAPIs[strings.ToLower(host)] = api
+	}
+}
+
+// resolveSource picks the source that handles ref
+func (pm *PluginManager) resolveSource(ctx context.Context, ref string) (Source, error) {
+	pm.mu.RLock()
+	apis := pm.releaseAPIs
+	v := pm.verifier
+	pm.mu.RUnlock()
+
+	releases := &releaseSource{apis: apis, verify: v}
+	sources := []Source{&fileSource{verify: v}, &catalogSource{pm: pm, releases: releases, verify: v}, releases, &urlSource{verify: v}}
+	for _, src := range sources {
+		if src.Match(ctx, ref) {
+			return src, nil
+		}
+	}
+	return nil, fmt.Errorf("don't know how to install %q: expected a catalog name, a repo URL, an http(s) URL or a local file", ref)
+}
+
+// releaseSource installs a release asset of a repo hosted behind a
+// GitHub-compatible API, e.g. https://github.com/owner/repo or the GitHub
+// shorthand owner/repo. The latest release is used unless a tag is given
+// as owner/repo@v1.2.0.
+type releaseSource struct {
+	apis   map[string]ReleaseAPI
+	verify *verifier
+}
+
+func (s *releaseSource) Name() string { return "github" }
+
+func (s *releaseSource) Match(ctx context.Context, ref string) bool {
+	_, _, _, err := s.parse(ref)
+	return err == nil
+}
+
+// parse splits a repo URL into its API, owner and repo
+func (s *releaseSource) parse(ref string) (ReleaseAPI, string, string, error) {
+	ref, _ = splitRef(ref)
+	if !strings.Contains(ref, "://") && strings.Count(ref, "/") == 1 {
+		ref = "https://github.com/" + ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return ReleaseAPI{}, "", "", fmt.Errorf("not a repo URL: %s", ref)
+	}
+	api, ok := s.apis[strings.ToLower(u.Host)]
+	if !ok {
+		return ReleaseAPI{}, "", "", fmt.Errorf("no release API configured for %s", u.Host)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ReleaseAPI{}, "", "", fmt.Errorf("invalid repo URL: %s", ref)
+	}
+	return api, parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+}
+
+// release is a release as returned by a GitHub-compatible API
+type release struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// get calls the release API and decodes its JSON answer into v
+func (s *releaseSource) get(ctx context.Context, api ReleaseAPI, path string, v interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", api.BaseURL+path, nil)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if api.Token != "" {
+		req.Header.Set("Authorization", "token "+api.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return fmt.Errorf("release not found")
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("release API returned %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse release info: %w", err)
+	}
+	return nil
+}
+
+func (s *releaseSource) Versions(ctx context.Context, ref string) ([]string, error) {
+	api, owner, repo, err := s.parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []release
+	if err := s.get(ctx, api, fmt.Sprintf("/repos/%s/%s/releases?per_page=30", owner, repo), &releases); err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(releases))
+	for _, r := range releases {
+		if !r.Draft {
+			tags = append(tags, r.TagName)
+		}
+	}
+	return tags, nil
+}
+
+func (s *releaseSource) Fetch(ctx context.Context, ref, dest string) (*Artifact, error) {
+	api, owner, repo, err := s.parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the requested or latest release
+	path := fmt.Sprintf("/repos/%s/%s/releases/latest", owner, repo)
+	if _, tag := splitRef(ref); tag != "" {
+		path = fmt.Sprintf("/repos/%s/%s/releases/tags/%s", owner, repo, url.PathEscape(tag))
+	}
+	var release release
+	if err := s.get(ctx, api, path, &release); err != nil {
+		return nil, err
+	}
+
+	// Find the right binary for current OS/arch
+	var downloadURL, assetName string
+	assets := make(map[string]string, len(release.Assets))
+	names := make([]string, 0, len(release.Assets))
+	for _, asset := range release.Assets {
+		assets[asset.Name] = asset.BrowserDownloadURL
+		names = append(names, asset.Name)
+		if downloadURL == "" && matchAsset(asset.Name) {
+			downloadURL = asset.BrowserDownloadURL
+			assetName = asset.Name
+		}
+	}
+	if downloadURL == "" {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+
+	// Checksums and signatures are other assets of the release
+	check := func(file string) (string, error) {
+		return s.verify.verify(ctx, assetName, file, names, func(ctx context.Context, name string) ([]byte, error) {
+			return fetchSmall(ctx, assets[name], api.Token)
+		})
+	}
+
+	log.Printf("[PluginMgr] Downloading %s (%s)...", assetName, release.TagName)
+	artifact, err := fetchBinary(ctx, downloadURL, assetName, api.Token, dest, check)
+	if err != nil {
+		return nil, err
+	}
+	artifact.Release = release.TagName
+	artifact.RepoURL, _ = splitRef(ref)
+	return artifact, nil
+}
+
+// urlSource installs a binary or archive from a plain http(s) URL. Checksum
+// and signature files are looked for next to it, e.g. checksums.txt in the
+// same directory.
+type urlSource struct {
+	verify *verifier
+}
+
+func (s *urlSource) Name() string { return "url" }
+
+func (s *urlSource) Match(ctx context.Context, ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+}
+
+func (s *urlSource) Fetch(ctx context.Context, ref, dest string) (*Artifact, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(u.Path)
+	if name == "." || name == "/" {
+		return nil, fmt.Errorf("cannot tell a file name from %s", ref)
+	}
+
+	check := func(file string) (string, error) {
+		return s.verify.verify(ctx, name, file, sidecarNames(name), func(ctx context.Context, sidecar string) ([]byte, error) {
+			return fetchSmall(ctx, u.ResolveReference(&url.URL{Path: url.PathEscape(sidecar)}).String(), "")
+		})
+	}
+
+	log.Printf("[PluginMgr] Downloading %s...", ref)
+	return fetchBinary(ctx, ref, name, "", dest, check)
+}
+
+// fileSource installs a binary or archive from the core's file system. It
+// is trusted as the admin put it there, but checksums published next to
+// it are still checked.
+type fileSource struct {
+	verify *verifier
+}
+
+func (s *fileSource) Name() string { return "file" }
+
+func (s *fileSource) Match(ctx context.Context, ref string) bool {
+	info, err := os.Stat(strings.TrimPrefix(ref, "file://"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+func (s *fileSource) Fetch(ctx context.Context, ref, dest string) (*Artifact, error) {
+	path := strings.TrimPrefix(ref, "file://")
+	name := filepath.Base(path)
+	verified, err := s.verify.verify(ctx, name, path, sidecarNames(name), func(ctx context.Context, sidecar string) ([]byte, error) {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), sidecar))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verified == "" {
+		verified = VerifiedLocal
+	}
+
+	if format := archiveFormat(path); format != "" {
+		binaryName, err := extractBinary(path, format, dest)
+		if err != nil {
+			return nil, err
+		}
+		return &Artifact{BinaryName: binaryName, Verified: verified}, nil
+	}
+	if err := copyFile(path, dest); err != nil {
+		return nil, err
+	}
+	return &Artifact{BinaryName: name, Verified: verified}, nil
+}
+
+// fetchBinary downloads url, verifies the download with check and unpacks
+// it if it is an archive
+func fetchBinary(ctx context.Context, url, name, token, dest string, check func(file string) (string, error)) (*Artifact, error) {
+	format := archiveFormat(name)
+	file := dest
+	if format != "" {
+		file = dest + ".archive"
+		defer os.Remove(file)
+	}
+	if err := downloadFile(ctx, url, token, file); err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+
+	verified, err := check(file)
+	if err != nil {
+		return nil, err
+	}
+
+	binaryName := name
+	if format != "" {
+		if binaryName, err = extractBinary(file, format, dest); err != nil {
+			return nil, err
+		}
+	}
+	return &Artifact{BinaryName: binaryName, Verified: verified}, nil
+}
+
+// downloadFile downloads a file from URL. A download that ends early is an
+// error rather than a truncated file.
+func downloadFile(ctx context.Context, url, token, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	if resp.ContentLength > maxBinarySize {
+		return fmt.Errorf("%s is larger than %dMB", url, maxBinarySize>>20)
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return err
+	}
+	if n > maxBinarySize {
+		return fmt.Errorf("%s is larger than %dMB", url, maxBinarySize>>20)
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("download of %s ended after %d of %d bytes", url, n, resp.ContentLength)
+	}
+	return out.Close()
+}
+
+// fetchSmall downloads a checksum or signature file, nil if there is none
+func fetchSmall(ctx context.Context, url, token string) ([]byte, error) {
+	if url == "" {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSidecarSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSidecarSize {
+		return nil, fmt.Errorf("%s is too large", url)
+	}
+	return data, nil
+}
+
+// copyFile copies a local file
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// matchAsset reports whether a release asset is built for this OS and
+// architecture. The name must carry them as separate words, e.g.
+// "weather_linux_amd64", "weather-linux-amd64.tar.gz" or
+// "weather_windows_amd64.exe", so linux_arm does not match linux_arm64.
+func matchAsset(name string) bool {
+	base := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip", ".exe"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	for i := 0; i+1 < len(words); i++ {
+		if words[i] == runtime.GOOS && words[i+1] == runtime.GOARCH {
+			return i+2 == len(words)
+		}
+	}
+	return false
+}
@@ -0,0 +1,448 @@
+package pluginmgr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ProtocolVersion is the highest plugin protocol the core speaks.
+// Version 1 is per-event unary RPCs into the plugin's own gRPC server;
+// version 2 is a single bidi stream opened by the plugin.
+const ProtocolVersion = 2
+
+// Environment variables used to hand a v2 launch session to a plugin
+const (
+	EnvProtocol = "BOT_PLUGIN_PROTOCOL"
+	EnvSession  = "BOT_PLUGIN_SESSION"
+)
+
+// streamConnectTimeout bounds how long a spawned v2 plugin has to open its stream
+const streamConnectTimeout = 10 * time.Second
+
+var errSessionClosed = errors.New("plugin stream closed")
+
+// pendingLaunch is a spawned v2 plugin the core is waiting to hear from
+type pendingLaunch struct {
+	session  string
+	ready    chan *streamSession
+	rejected chan error // the plugin failed the version handshake
+}
+
+// streamSession is the core side of a v2 plugin stream. It implements
+// pb.PluginServiceClient so the rest of the manager does not care which
+// protocol a plugin speaks.
+type streamSession struct {
+	name   string
+	info   *pb.PluginInfo
+	stream pb.PluginHost_ConnectServer
+	pm     *PluginManager
+
+	sendMu sync.Mutex
+
+	mu       sync.Mutex
+	nextSeq  uint64
+	pending  map[uint64]chan *pb.Ack
+	credits  int
+	creditCh chan struct{}
+	done     chan struct{} // closed when the stream has ended
+
+	closing   chan struct{} // closed to end the stream from the core side
+	closeOnce sync.Once
+}
+
+// hostServer implements pb.PluginHostServer on behalf of the manager
+type hostServer struct {
+	pb.UnimplementedPluginHostServer
+	pm *PluginManager
+}
+
+// HostServer returns the PluginHost service to register on the core's gRPC server
+func (pm *PluginManager) HostServer() pb.PluginHostServer {
+	return &hostServer{pm: pm}
+}
+
+// expectStream registers a v2 launch and returns its session token
+func (pm *PluginManager) expectStream(name string) *pendingLaunch {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	launch := &pendingLaunch{
+		session:  hex.EncodeToString(buf),
+		ready:    make(chan *streamSession, 1),
+		rejected: make(chan error, 1),
+	}
+
+	pm.streamMu.Lock()
+	pm.pendingStreams[name] = launch
+	pm.streamMu.Unlock()
+	return launch
+}
+
+// cancelStream forgets a pending v2 launch
+func (pm *PluginManager) cancelStream(name string, launch *pendingLaunch) {
+	pm.streamMu.Lock()
+	if pm.pendingStreams[name] == launch {
+		delete(pm.pendingStreams, name)
+	}
+	pm.streamMu.Unlock()
+}
+
+// Connect accepts a v2 plugin stream
+func (h *hostServer) Connect(stream pb.PluginHost_ConnectServer) error {
+	pm := h.pm
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := first.GetHello()
+	if hello == nil {
+		return status.Error(codes.InvalidArgument, "first frame must be hello")
+	}
+
+	session := &streamSession{
+		name:     hello.PluginName,
+		info:     hello.Info,
+		stream:   stream,
+		pm:       pm,
+		pending:  make(map[uint64]chan *pb.Ack),
+		credits:  int(hello.InitialCredits),
+		creditCh: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+	if session.credits <= 0 {
+		session.credits = 1
+	}
+
+	version := hello.ProtocolVersion
+	if version > ProtocolVersion {
+		version = ProtocolVersion
+	}
+	welcome := &pb.CoreFrame{Frame: &pb.CoreFrame_Welcome{
+		Welcome: &pb.Welcome{
+			ProtocolVersion: version,
+			CoreVersion:     CoreVersion,
+			Features:        CoreFeatures,
+		},
+	}}
+
+	pm.streamMu.Lock()
+	launch, exists := pm.pendingStreams[hello.PluginName]
+	launched := exists && hello.Session != "" && launch.session == hello.Session
+	if launched {
+		delete(pm.pendingStreams, hello.PluginName)
+	}
+	pm.streamMu.Unlock()
+
+	if launched {
+		if err := pm.handshakeStream(hello); err != nil {
+			log.Printf("[PluginMgr] Refused plugin %s: %v", hello.PluginName, err)
+			launch.rejected <- err
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if err := session.send(welcome); err != nil {
+			return err
+		}
+		launch.ready <- session
+		log.Printf("[PluginMgr] Plugin %s connected over stream (protocol v%d)", session.name, version)
+	} else {
+		// Not spawned by us: the plugin must register with a token
+		if err := pm.registerRemote(hello, session); err != nil {
+			log.Printf("[PluginMgr] Rejected stream from %q: %v", hello.PluginName, err)
+			if errors.Is(err, errIncompatible) {
+				return status.Error(codes.FailedPrecondition, err.Error())
+			}
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+		if err := session.send(welcome); err != nil {
+			pm.mu.RLock()
+			state := pm.plugins[session.name]
+			pm.mu.RUnlock()
+			pm.handlePluginCrash(session.name, state, "plugin stream closed")
+			return err
+		}
+	}
+
+	recvErr := make(chan error, 1)
+	go func() {
+		recvErr <- session.recvLoop()
+	}()
+
+	select {
+	case err = <-recvErr:
+	case <-session.closing:
+		err = errSessionClosed
+	}
+	close(session.done)
+
+	// A stream that breaks while the plugin is still marked running
+	// means the plugin died; don't wait for the next health check
+	pm.mu.RLock()
+	state, exists := pm.plugins[session.name]
+	current := exists && state.Status == StatusRunning && state.Client == session
+	replica := pm.replicaByClientLocked(session.name, session)
+	pm.mu.RUnlock()
+	if current {
+		log.Printf("[PluginMgr] Stream of plugin %s closed: %v", session.name, err)
+		go pm.handlePluginCrash(session.name, state, fmt.Sprintf("plugin stream closed: %v", err))
+	} else if replica != nil {
+		log.Printf("[PluginMgr] Stream of a replica of plugin %s closed: %v", session.name, err)
+		go pm.handleReplicaCrash(session.name, replica, fmt.Sprintf("plugin stream closed: %v", err))
+	} else {
+		pm.dropHeldRemote(session)
+	}
+	return nil
+}
+
+// Close ends the stream from the core side
+func (s *streamSession) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+}
+
+// closeSession ends a plugin's stream, if it speaks the stream protocol
+func closeSession(state *PluginState) {
+	if session, ok := state.Client.(*streamSession); ok {
+		session.Close()
+	}
+}
+
+// recvLoop reads plugin frames until the stream ends
+func (s *streamSession) recvLoop() error {
+	for {
+		frame, err := s.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch f := frame.Frame.(type) {
+		case *pb.PluginFrame_Ack:
+			s.grant(int(f.Ack.Credits))
+			s.mu.Lock()
+			ch, exists := s.pending[f.Ack.Seq]
+			delete(s.pending, f.Ack.Seq)
+			s.mu.Unlock()
+			if exists {
+				ch <- f.Ack
+			}
+		case *pb.PluginFrame_Credit:
+			s.grant(int(f.Credit.Credits))
+		case *pb.PluginFrame_Action:
+			go s.handleAction(f.Action)
+		}
+	}
+}
+
+// handleAction runs a BotService call received over the stream by routing
+// it through the generated service descriptor
+func (s *streamSession) handleAction(action *pb.Action) {
+	result := &pb.ActionResult{Id: action.Id}
+
+	// The stream itself is authenticated, so its calls carry its identity
+	ctx := WithCaller(s.stream.Context(), s.name)
+	resp, err := s.pm.callBotService(ctx, action.Method, action.Request)
+	if err != nil {
+		result.Error = err.Error()
+	} else if resp != nil {
+		result.Response, err = anypb.New(resp)
+		if err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	if err := s.send(&pb.CoreFrame{Frame: &pb.CoreFrame_ActionResult{ActionResult: result}}); err != nil {
+		log.Printf("[PluginMgr] Failed to send action result to %s: %v", s.name, err)
+	}
+}
+
+// callBotService invokes a BotService method by name with an Any-encoded request
+func (pm *PluginManager) callBotService(ctx context.Context, method string, req *anypb.Any) (proto.Message, error) {
+	if pm.botService == nil {
+		return nil, fmt.Errorf("bot service is not available")
+	}
+
+	for _, desc := range pb.BotService_ServiceDesc.Methods {
+		if desc.MethodName != method {
+			continue
+		}
+		dec := func(v interface{}) error {
+			if req == nil {
+				return nil
+			}
+			return req.UnmarshalTo(v.(proto.Message))
+		}
+		resp, err := desc.Handler(pm.botService, ctx, dec, pm.capabilityInterceptor)
+		if err != nil {
+			return nil, err
+		}
+		return resp.(proto.Message), nil
+	}
+	return nil, fmt.Errorf("unknown method %s", method)
+}
+
+func (s *streamSession) send(frame *pb.CoreFrame) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return s.stream.Send(frame)
+}
+
+// grant adds event credits and wakes a waiting sender
+func (s *streamSession) grant(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	s.credits += n
+	s.mu.Unlock()
+
+	select {
+	case s.creditCh <- struct{}{}:
+	default:
+	}
+}
+
+// acquire takes one event credit, waiting for the plugin to grant more
+func (s *streamSession) acquire(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		if s.credits > 0 {
+			s.credits--
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.creditCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.done:
+			return errSessionClosed
+		}
+	}
+}
+
+// call sends an event and waits for its ack. Data events consume a credit;
+// control events (health, shutdown) bypass flow control.
+func (s *streamSession) call(ctx context.Context, event *pb.PluginEvent, needsCredit bool) (*pb.Ack, error) {
+	if needsCredit {
+		if err := s.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan *pb.Ack, 1)
+	s.mu.Lock()
+	s.nextSeq++
+	event.Seq = s.nextSeq
+	s.pending[event.Seq] = ch
+	s.mu.Unlock()
+
+	if err := s.send(&pb.CoreFrame{Frame: &pb.CoreFrame_Event{Event: event}}); err != nil {
+		s.forget(event.Seq)
+		return nil, err
+	}
+
+	select {
+	case ack := <-ch:
+		return ack, nil
+	case <-ctx.Done():
+		s.forget(event.Seq)
+		return nil, ctx.Err()
+	case <-s.done:
+		return nil, errSessionClosed
+	}
+}
+
+func (s *streamSession) forget(seq uint64) {
+	s.mu.Lock()
+	delete(s.pending, seq)
+	s.mu.Unlock()
+}
+
+func (s *streamSession) GetInfo(ctx context.Context, _ *pb.Empty, _ ...grpc.CallOption) (*pb.PluginInfo, error) {
+	return s.info, nil
+}
+
+func (s *streamSession) OnMessage(ctx context.Context, in *pb.MessageEvent, _ ...grpc.CallOption) (*pb.HandleResult, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Message{Message: in}}, true)
+	if err != nil {
+		return nil, err
+	}
+	return ackHandleResult(ack), nil
+}
+
+func (s *streamSession) OnCommand(ctx context.Context, in *pb.CommandEvent, _ ...grpc.CallOption) (*pb.HandleResult, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Command{Command: in}}, true)
+	if err != nil {
+		return nil, err
+	}
+	return ackHandleResult(ack), nil
+}
+
+func (s *streamSession) OnEvent(ctx context.Context, in *pb.BusEvent, _ ...grpc.CallOption) (*pb.HandleResult, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_BusEvent{BusEvent: in}}, true)
+	if err != nil {
+		return nil, err
+	}
+	return ackHandleResult(ack), nil
+}
+
+func (s *streamSession) OnInvoke(ctx context.Context, in *pb.InvokeRequest, _ ...grpc.CallOption) (*pb.InvokeResponse, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Invoke{Invoke: in}}, true)
+	if err != nil {
+		return nil, err
+	}
+	if resp := ack.GetInvoke(); resp != nil {
+		return resp, nil
+	}
+	return &pb.InvokeResponse{}, nil
+}
+
+func (s *streamSession) Health(ctx context.Context, _ *pb.Empty, _ ...grpc.CallOption) (*pb.HealthResponse, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Health{Health: &pb.Empty{}}}, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp := ack.GetHealth(); resp != nil {
+		return resp, nil
+	}
+	return &pb.HealthResponse{}, nil
+}
+
+func (s *streamSession) OnConfigChange(ctx context.Context, in *pb.PluginConfig, _ ...grpc.CallOption) (*pb.HandleResult, error) {
+	ack, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Config{Config: in}}, false)
+	if err != nil {
+		return nil, err
+	}
+	return ackHandleResult(ack), nil
+}
+
+func (s *streamSession) Shutdown(ctx context.Context, _ *pb.Empty, _ ...grpc.CallOption) (*pb.Empty, error) {
+	if _, err := s.call(ctx, &pb.PluginEvent{Payload: &pb.PluginEvent_Shutdown{Shutdown: &pb.Empty{}}}, false); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+func ackHandleResult(ack *pb.Ack) *pb.HandleResult {
+	if result := ack.GetHandleResult(); result != nil {
+		return result
+	}
+	return &pb.HandleResult{}
+}
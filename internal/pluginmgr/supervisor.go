@@ -0,0 +1,272 @@
+package pluginmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Restart policies for spawned plugins that exit on their own
+const (
+	RestartAlways    = "always"     // restart after any exit
+	RestartOnFailure = "on-failure" // restart unless the plugin exited with code 0
+	RestartNever     = "never"      // leave the plugin stopped
+)
+
+// RestartPolicy decides whether and how fast crashed plugins are restarted
+type RestartPolicy struct {
+	Policy      string            // RestartAlways, RestartOnFailure or RestartNever
+	Overrides   map[string]string // plugin name -> policy
+	Backoff     time.Duration     // delay before the first restart, doubled per restart
+	MaxBackoff  time.Duration     // upper bound of the delay
+	MaxRestarts int               // restarts allowed within Window before crashloop
+	Window      time.Duration
+}
+
+// DefaultRestartPolicy restarts failed plugins up to 5 times in 5 minutes
+var DefaultRestartPolicy = RestartPolicy{
+	Policy:      RestartOnFailure,
+	Backoff:     time.Second,
+	MaxBackoff:  time.Minute,
+	MaxRestarts: 5,
+	Window:      5 * time.Minute,
+}
+
+// SetRestartPolicy configures how crashed plugins are restarted. Zero
+// values keep the defaults.
+func (pm *PluginManager) SetRestartPolicy(policy RestartPolicy) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	p := DefaultRestartPolicy
+	if validRestartPolicy(policy.Policy) {
+		p.Policy = policy.Policy
+	} else if policy.Policy != "" {
+		log.Printf("[PluginMgr] Unknown restart policy %q, using %q", policy.Policy, p.Policy)
+	}
+	p.Overrides = make(map[string]string)
+	for name, value := range policy.Overrides {
+		if !validRestartPolicy(value) {
+			log.Printf("[PluginMgr] Unknown restart policy %q for plugin %s, ignored", value, name)
+			continue
+		}
+		p.Overrides[name] = value
+	}
+	if policy.Backoff > 0 {
+		p.Backoff = policy.Backoff
+	}
+	if policy.MaxBackoff > 0 {
+		p.MaxBackoff = policy.MaxBackoff
+	}
+	if policy.MaxRestarts > 0 {
+		p.MaxRestarts = policy.MaxRestarts
+	}
+	if policy.Window > 0 {
+		p.Window = policy.Window
+	}
+	pm.restartPolicy = p
+}
+
+func validRestartPolicy(policy string) bool {
+	return policy == RestartAlways || policy == RestartOnFailure || policy == RestartNever
+}
+
+// policyFor returns the restart policy of a plugin. Caller must hold pm.mu.
+func (pm *PluginManager) policyFor(name string) string {
+	if policy, ok := pm.restartPolicy.Overrides[name]; ok {
+		return policy
+	}
+	return pm.restartPolicy.Policy
+}
+
+// child is a spawned plugin process. Its exit is collected by exactly one
+// goroutine so crashed plugins never linger as zombies.
+type child struct {
+	cmd  *exec.Cmd
+	sb   *sandbox
+	done chan struct{} // closed once the process has exited
+	err  error         // result of cmd.Wait, valid after done
+}
+
+// startChild starts a plugin process inside its sandbox and begins
+// waiting on it. The process is tracked until it exits so Shutdown can
+// kill whatever is left.
+func (pm *PluginManager) startChild(cmd *exec.Cmd, sb *sandbox) (*child, error) {
+	if err := sb.configure(cmd); err != nil {
+		sb.exited()
+		return nil, err
+	}
+	// Don't let a grandchild holding the output pipes delay the exit
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		sb.exited()
+		return nil, err
+	}
+
+	c := &child{cmd: cmd, sb: sb, done: make(chan struct{})}
+	pm.childMu.Lock()
+	pm.children[c] = true
+	pm.childMu.Unlock()
+	go func() {
+		c.err = c.cmd.Wait()
+		c.sb.exited()
+		pm.childMu.Lock()
+		delete(pm.children, c)
+		pm.childMu.Unlock()
+		close(c.done)
+	}()
+
+	if err := sb.started(cmd.Process.Pid); err != nil {
+		c.kill()
+		return nil, err
+	}
+	return c, nil
+}
+
+// killChildren kills every plugin process still running and returns how
+// many there were
+func (pm *PluginManager) killChildren() int {
+	pm.childMu.Lock()
+	children := make([]*child, 0, len(pm.children))
+	for c := range pm.children {
+		children = append(children, c)
+	}
+	pm.childMu.Unlock()
+
+	for _, c := range children {
+		c.kill()
+	}
+	return len(children)
+}
+
+// kill terminates the process and waits until it is gone
+func (c *child) kill() {
+	c.cmd.Process.Kill()
+	<-c.done
+}
+
+// exitDescription describes how the process ended, e.g. "exited with code 2"
+func (c *child) exitDescription() string {
+	var exitErr *exec.ExitError
+	if c.err != nil && !errors.As(c.err, &exitErr) {
+		return c.err.Error()
+	}
+
+	ps := c.cmd.ProcessState
+	if ps == nil {
+		return "exited"
+	}
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return fmt.Sprintf("killed by signal %s", ws.Signal())
+	}
+	return fmt.Sprintf("exited with code %d", ps.ExitCode())
+}
+
+// failed reports whether the process ended any other way than exit code 0
+func (c *child) failed() bool {
+	ps := c.cmd.ProcessState
+	return ps == nil || !ps.Success()
+}
+
+// supervise waits for a spawned plugin to exit. An exit the manager did
+// not ask for is handled as a crash.
+func (pm *PluginManager) supervise(name string, state *PluginState) {
+	<-state.proc.done
+
+	pm.mu.RLock()
+	current := pm.plugins[name] == state && state.Status == StatusRunning
+	pm.mu.RUnlock()
+	if !current {
+		return
+	}
+
+	pm.handlePluginCrash(name, state, "process "+state.proc.exitDescription())
+}
+
+// scheduleRestartLocked applies the restart policy after a crash. Caller
+// must hold pm.mu.
+func (pm *PluginManager) scheduleRestartLocked(name string, state *PluginState, failed bool) {
+	switch pm.policyFor(name) {
+	case RestartNever:
+		log.Printf("[PluginMgr] Plugin %s will not be restarted (policy %s)", name, RestartNever)
+		return
+	case RestartOnFailure:
+		if !failed {
+			state.Status = StatusStopped
+			log.Printf("[PluginMgr] Plugin %s exited cleanly, not restarting", name)
+			return
+		}
+	}
+
+	delay, ok := pm.backoffLocked(state)
+	if !ok {
+		state.Status = StatusCrashloop
+		log.Printf("[PluginMgr] Plugin %s crashed %d times within %s, giving up (%s)",
+			name, len(state.restarts)+1, pm.restartPolicy.Window, state.LastError)
+		return
+	}
+
+	log.Printf("[PluginMgr] Restarting plugin %s in %s (restart %d)", name, delay, state.Restarts)
+
+	go func() {
+		select {
+		case <-time.After(delay):
+		case <-pm.stopHealth:
+			return // manager is shutting down
+		}
+
+		// An admin may have started, stopped or removed the plugin meanwhile
+		pm.mu.RLock()
+		pending := pm.plugins[name] == state && state.Status == StatusError
+		pm.mu.RUnlock()
+		if !pending {
+			return
+		}
+
+		if err := pm.startPlugin(context.Background(), name, true); err != nil {
+			log.Printf("[PluginMgr] Failed to restart plugin %s: %v", name, err)
+
+			// The failed attempt left the plugin in error unless an admin
+			// is busy with it, in which case the admin takes over
+			pm.mu.Lock()
+			if cur, exists := pm.plugins[name]; exists && cur.Status == StatusError {
+				cur.LastError = "restart failed: " + err.Error()
+				pm.scheduleRestartLocked(name, cur, true)
+			}
+			pm.mu.Unlock()
+		} else {
+			log.Printf("[PluginMgr] Successfully restarted plugin %s", name)
+		}
+	}()
+}
+
+// backoffLocked counts another restart of a crashed instance and returns
+// how long to wait before it. It returns false once the instance crashed
+// too often within the restart window. Caller must hold pm.mu.
+func (pm *PluginManager) backoffLocked(state *PluginState) (time.Duration, bool) {
+	// Forget restarts that fell out of the window
+	now := time.Now()
+	recent := state.restarts[:0]
+	for _, t := range state.restarts {
+		if now.Sub(t) < pm.restartPolicy.Window {
+			recent = append(recent, t)
+		}
+	}
+	state.restarts = recent
+
+	if len(state.restarts) >= pm.restartPolicy.MaxRestarts {
+		return 0, false
+	}
+
+	delay := pm.restartPolicy.Backoff << len(state.restarts)
+	if delay > pm.restartPolicy.MaxBackoff || delay <= 0 {
+		delay = pm.restartPolicy.MaxBackoff
+	}
+	state.restarts = append(state.restarts, now)
+	state.Restarts++
+	return delay, true
+}
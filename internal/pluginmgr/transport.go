@@ -0,0 +1,93 @@
+package pluginmgr
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Transports between the core and the plugins it spawns
+const (
+	TransportTCP  = "tcp"  // grpc_port plus a pooled loopback port per plugin
+	TransportUnix = "unix" // socket files in a private runtime directory
+)
+
+// SetTransport selects how spawned plugins are connected. In unix mode the
+// runtime directory is created (or tightened) so only the bot user can
+// reach the sockets in it, and the port pool is not used.
+func (pm *PluginManager) SetTransport(transport, runtimeDir string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if transport != TransportUnix {
+		pm.transport = TransportTCP
+		return nil
+	}
+
+	dir, err := filepath.Abs(runtimeDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create runtime dir: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("failed to restrict runtime dir: %w", err)
+	}
+
+	pm.transport = TransportUnix
+	pm.runtimeDir = dir
+	return nil
+}
+
+// Transport returns the configured transport
+func (pm *PluginManager) Transport() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.transport
+}
+
+// CoreSocket returns the socket path the core serves BotService on in unix mode
+func (pm *PluginManager) CoreSocket() string {
+	return filepath.Join(pm.runtimeDir, "core.sock")
+}
+
+// pluginSocket returns the socket path a v1 plugin instance listens on in
+// unix mode
+func (pm *PluginManager) pluginSocket(instance string) string {
+	return filepath.Join(pm.runtimeDir, instance+".sock")
+}
+
+// instanceName tells apart processes of one plugin that run side by side,
+// such as the old and new version during an upgrade. The first instance is
+// named after the plugin.
+func instanceName(name, instance string) string {
+	if instance == "" {
+		return name
+	}
+	return name + "@" + instance
+}
+
+// coreArgs returns the flags telling a plugin where to reach the core
+func (pm *PluginManager) coreArgs() []string {
+	if pm.transport == TransportUnix {
+		return []string{"--core-socket", pm.CoreSocket()}
+	}
+	return []string{"--core-addr", fmt.Sprintf("127.0.0.1:%d", pm.grpcPort)}
+}
+
+// ListenUnix listens on a socket file only the current user can connect to.
+// A stale socket left by a previous run is removed first.
+func ListenUnix(path string) (net.Listener, error) {
+	os.Remove(path)
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
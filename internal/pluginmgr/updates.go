@@ -0,0 +1,376 @@
+package pluginmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Auto-update policies kept in PluginMeta.AutoUpdate. A policy bounds how
+// far a plugin is upgraded on its own; anything beyond is only announced.
+const (
+	AutoUpdateOff   = ""      // announce new versions only
+	AutoUpdatePatch = "patch" // 1.2.x
+	AutoUpdateMinor = "minor" // 1.x
+	AutoUpdateAny   = "any"   // every new release
+)
+
+// updateCheckDelay is how long after startup the first update check runs
+const updateCheckDelay = time.Minute
+
+// UpdateStatus is the result of the last update check of a plugin
+type UpdateStatus struct {
+	Latest    string    `json:"latest,omitempty"` // newest release the source offers
+	Available bool      `json:"available"`        // Latest is newer than the installed version
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`    // why the check failed
+	Notified  string    `json:"notified,omitempty"` // newest version admins were told about
+	Skip      string    `json:"skip,omitempty"`     // version auto-update failed to or was rolled back from
+	Awaiting  string    `json:"awaiting,omitempty"` // stored version held back until its capabilities are approved
+}
+
+// forVersion returns the status as seen from another installed version
+func (u *UpdateStatus) forVersion(version string) *UpdateStatus {
+	if u == nil {
+		return nil
+	}
+	status := *u
+	cmp, err := compareVersions(status.Latest, version)
+	status.Available = err == nil && cmp > 0
+	if cmp, err := compareVersions(status.Awaiting, version); err != nil || cmp <= 0 {
+		status.Awaiting = ""
+	}
+	return &status
+}
+
+// Notifier delivers update notices to the bot's admins
+type Notifier interface {
+	SendPrivateText(userID int64, text string) error
+}
+
+// UpdateCheck configures the background update checker
+type UpdateCheck struct {
+	Interval time.Duration // 0 disables the checker
+	Admins   []int64       // users told about new versions
+	Notifier Notifier      // nil logs notices only
+}
+
+// ParseAutoUpdate checks an auto-update policy; "off" and "" disable it
+func ParseAutoUpdate(policy string) (string, error) {
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case "", "off", "none":
+		return AutoUpdateOff, nil
+	case AutoUpdatePatch, AutoUpdateMinor, AutoUpdateAny:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid auto-update policy %q: expected off, patch, minor or any", policy)
+}
+
+// SetUpdateCheck starts checking installed plugins for new releases
+// periodically. It should be called once, after the plugins are loaded.
+func (pm *PluginManager) SetUpdateCheck(check UpdateCheck) {
+	pm.mu.Lock()
+	pm.updateCheck = check
+	pm.mu.Unlock()
+
+	if check.Interval <= 0 {
+		return
+	}
+	log.Printf("[PluginMgr] Checking for plugin updates every %s", check.Interval)
+
+	go func() {
+		timer := time.NewTimer(updateCheckDelay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-pm.stopHealth:
+				return
+			case <-timer.C:
+				pm.CheckUpdates(context.Background())
+				timer.Reset(check.Interval)
+			}
+		}
+	}()
+}
+
+// SetAutoUpdate sets the auto-update policy of an installed plugin
+func (pm *PluginManager) SetAutoUpdate(name, policy string) (*PluginMeta, error) {
+	policy, err := ParseAutoUpdate(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.AutoUpdate = policy
+		if meta.Update != nil {
+			// A new policy gets another try at a version skipped before
+			meta.Update.Skip = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state, exists := pm.plugins[name]; exists {
+		state.Info.AutoUpdate = policy
+		state.Info.Update = meta.Update
+	}
+
+	if policy == AutoUpdateOff {
+		log.Printf("[PluginMgr] Disabled auto-update of plugin %s", name)
+	} else {
+		log.Printf("[PluginMgr] Auto-updating plugin %s (%s)", name, policy)
+	}
+	return meta, nil
+}
+
+// CheckUpdates compares every installed plugin with the releases its
+// source offers, records the result in its meta and tells the admins
+// about new versions. Plugins with an auto-update policy are upgraded to
+// the newest version the policy allows; a version that fails its health
+// check or is rolled back by an admin is not tried again.
+func (pm *PluginManager) CheckUpdates(ctx context.Context) []*PluginMeta {
+	names := make([]string, 0)
+	for _, state := range pm.ListPlugins() {
+		if !state.Remote && state.Info != nil && state.Info.Name != "" {
+			names = append(names, state.Info.Name)
+		}
+	}
+
+	checked := make([]*PluginMeta, 0, len(names))
+	for _, name := range names {
+		if meta := pm.checkUpdate(ctx, name); meta != nil {
+			checked = append(checked, meta)
+		}
+	}
+	return checked
+}
+
+// checkUpdate checks one plugin, returning nil if its source has no
+// releases to compare with
+func (pm *PluginManager) checkUpdate(ctx context.Context, name string) *PluginMeta {
+	meta, err := pm.registry.get(name)
+	if err != nil {
+		return nil
+	}
+	ref := meta.Source
+	if ref == "" {
+		ref = meta.RepoURL
+	}
+	if ref == "" {
+		return nil
+	}
+	src, err := pm.resolveSource(ctx, ref)
+	if err != nil {
+		return nil
+	}
+	lister, ok := src.(VersionLister)
+	if !ok {
+		return nil
+	}
+
+	// A failed check keeps what the last one found
+	status := &UpdateStatus{CheckedAt: time.Now()}
+	if meta.Update != nil {
+		status.Latest = meta.Update.Latest
+		status.Notified = meta.Update.Notified
+		status.Skip = meta.Update.Skip
+		status.Awaiting = meta.Update.Awaiting
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	tags, err := lister.Versions(checkCtx, ref)
+	cancel()
+	if err != nil {
+		status.Error = err.Error()
+		log.Printf("[PluginMgr] Update check of %s failed: %v", name, err)
+		return pm.recordUpdate(name, status)
+	}
+
+	releases := stableVersions(tags)
+	status.Latest = ""
+	if len(releases) > 0 {
+		status.Latest = releases[0]
+	}
+	status = status.forVersion(meta.Version)
+	if !status.Available {
+		return pm.recordUpdate(name, status)
+	}
+
+	// Upgrade within the policy, announce the rest
+	target := autoUpdateTarget(meta.AutoUpdate, meta.Version, releases)
+	if target != "" && !meta.Held && strings.TrimPrefix(target, "v") != status.Skip {
+		if meta = pm.recordUpdate(name, status); meta == nil {
+			return nil
+		}
+		pm.autoUpdate(ctx, meta, target)
+		if meta, err = pm.registry.get(name); err != nil {
+			return nil
+		}
+		status = meta.Update
+		if status == nil || !status.Available || sameVersion(status.Awaiting, status.Latest) {
+			return meta
+		}
+	}
+
+	if status.Notified != status.Latest {
+		note := ""
+		if meta.Held {
+			note = " It is held, unhold it first."
+		}
+		pm.notifyAdmins(fmt.Sprintf("🆕 Plugin %s v%s is available (installed: v%s).%s\nUpgrade with /plugin upgrade %s",
+			name, strings.TrimPrefix(status.Latest, "v"), meta.Version, note, name))
+		status.Notified = status.Latest
+	}
+	return pm.recordUpdate(name, status)
+}
+
+// autoUpdate upgrades a plugin to target and tells the admins how it went
+func (pm *PluginManager) autoUpdate(ctx context.Context, meta *PluginMeta, target string) {
+	from := meta.Version
+	log.Printf("[PluginMgr] Auto-updating plugin %s from v%s to %s", meta.Name, from, target)
+
+	next, err := pm.Upgrade(ctx, meta.Name, target, false)
+	if errors.Is(err, ErrAwaitingApproval) {
+		// Told once; the version waits in the store until approved
+		if meta.Update == nil || !sameVersion(meta.Update.Awaiting, next.Version) {
+			pm.notifyAdmins(fmt.Sprintf("🔐 Plugin %s v%s requests new capabilities, it stays on v%s until approved: %s\nApprove with /plugin approve %s",
+				meta.Name, next.Version, from, next.Capabilities, meta.Name))
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("[PluginMgr] Auto-update of %s to %s failed: %v", meta.Name, target, err)
+		pm.mu.Lock()
+		pm.updateStatusLocked(meta.Name, func(status *UpdateStatus) {
+			status.Skip = strings.TrimPrefix(target, "v")
+		})
+		pm.mu.Unlock()
+		pm.notifyAdmins(fmt.Sprintf("⚠️ Auto-update of plugin %s to %s failed, it stays on v%s:\n%v",
+			meta.Name, target, from, err))
+		return
+	}
+	pm.notifyAdmins(fmt.Sprintf("⬆️ Plugin %s was auto-updated from v%s to v%s (%s policy).\nUndo with /plugin rollback %s",
+		meta.Name, from, next.Version, meta.AutoUpdate, meta.Name))
+}
+
+// sameVersion reports whether a and b name the same version, "1.2.0" and
+// "v1.2.0" alike
+func sameVersion(a, b string) bool {
+	return a != "" && strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// recordUpdate saves the result of an update check in the plugin's meta
+func (pm *PluginManager) recordUpdate(name string, status *UpdateStatus) *PluginMeta {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.Update = status.forVersion(meta.Version)
+		return nil
+	})
+	if err != nil {
+		if !isNotInstalled(err) {
+			log.Printf("[PluginMgr] Failed to record update status of %s: %v", name, err)
+		}
+		return nil
+	}
+	pm.showUpdateLocked(meta)
+	return meta
+}
+
+// updateStatusLocked changes the recorded update status of a plugin, if
+// it has one. The caller must hold pm.mu.
+func (pm *PluginManager) updateStatusLocked(name string, fn func(status *UpdateStatus)) {
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		if meta.Update != nil {
+			fn(meta.Update)
+		}
+		return nil
+	})
+	if err != nil {
+		if !isNotInstalled(err) {
+			log.Printf("[PluginMgr] Failed to record update status of %s: %v", name, err)
+		}
+		return
+	}
+	pm.showUpdateLocked(meta)
+}
+
+// showUpdateLocked copies a recorded update status to the plugin's state.
+// The caller must hold pm.mu.
+func (pm *PluginManager) showUpdateLocked(meta *PluginMeta) {
+	if state, exists := pm.plugins[meta.Name]; exists && state.Info != nil {
+		state.Info.Update = meta.Update
+	}
+}
+
+// notifyAdmins sends a notice to every configured admin
+func (pm *PluginManager) notifyAdmins(text string) {
+	pm.mu.RLock()
+	check := pm.updateCheck
+	pm.mu.RUnlock()
+
+	log.Printf("[PluginMgr] %s", strings.ReplaceAll(text, "\n", " "))
+	if check.Notifier == nil {
+		return
+	}
+	for _, admin := range check.Admins {
+		if err := check.Notifier.SendPrivateText(admin, text); err != nil {
+			log.Printf("[PluginMgr] Failed to notify admin %d: %v", admin, err)
+		}
+	}
+}
+
+// stableVersions returns the release tags that parse as versions, newest
+// first. Pre-releases such as v1.3.0-rc1 are left out.
+func stableVersions(tags []string) []string {
+	releases := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if strings.ContainsAny(tag, "-+") {
+			continue
+		}
+		if _, err := parseVersion(tag); err == nil {
+			releases = append(releases, tag)
+		}
+	}
+	sortVersions(releases)
+	return releases
+}
+
+// autoUpdateTarget picks the newest release the policy allows upgrading
+// current to, "" if there is none
+func autoUpdateTarget(policy, current string, releases []string) string {
+	if policy == AutoUpdateOff {
+		return ""
+	}
+	cur, err := parseVersion(current)
+	if err != nil {
+		return ""
+	}
+	for _, tag := range releases {
+		v, _ := parseVersion(tag)
+		if compareParsed(v, cur) <= 0 {
+			return ""
+		}
+		switch policy {
+		case AutoUpdatePatch:
+			if v[0] == cur[0] && v[1] == cur[1] {
+				return tag
+			}
+		case AutoUpdateMinor:
+			if v[0] == cur[0] {
+				return tag
+			}
+		case AutoUpdateAny:
+			return tag
+		}
+	}
+	return ""
+}
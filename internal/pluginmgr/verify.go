@@ -0,0 +1,330 @@
+package pluginmgr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Verification levels recorded in PluginMeta.Verified
+const (
+	VerifiedSignature = "signature" // checksums or binary signed by a trusted key
+	VerifiedChecksum  = "checksum"  // matches the published SHA-256 checksum
+	VerifiedLocal     = "local"     // installed from a file on the core's host
+	VerifiedNone      = "none"      // installed unverified on an admin's override
+)
+
+// ErrUnverified is returned when a download comes without the checksum or
+// signature the core requires. An admin may override it per install.
+var ErrUnverified = errors.New("plugin could not be verified")
+
+// maxSidecarSize bounds checksum and signature files
+const maxSidecarSize = 1 << 20
+
+// Verification configures how downloaded plugins are checked
+type Verification struct {
+	// Publisher keys signatures are checked against: minisign public keys
+	// ("RW..."), or base64 encoded ed25519 public keys for raw .sig files
+	TrustedKeys []string
+	// RequireSignature refuses downloads that are only checksummed
+	RequireSignature bool
+}
+
+// verifier checks downloads against the checksums and signatures published
+// next to them. The zero value checks checksums only.
+type verifier struct {
+	keys             []publicKey
+	requireSignature bool
+}
+
+type publicKey struct {
+	id  []byte // minisign key ID, nil for raw ed25519 keys
+	key ed25519.PublicKey
+}
+
+// SetVerification configures checksum and signature checks of downloads
+func (pm *PluginManager) SetVerification(v Verification) error {
+	keys := make([]publicKey, 0, len(v.TrustedKeys))
+	for _, s := range v.TrustedKeys {
+		key, err := parsePublicKey(s)
+		if err != nil {
+			return fmt.Errorf("invalid trusted key %q: %w", s, err)
+		}
+		keys = append(keys, key)
+	}
+	if v.RequireSignature && len(keys) == 0 {
+		return fmt.Errorf("require_signature needs at least one trusted key")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.verifier = &verifier{keys: keys, requireSignature: v.RequireSignature}
+	return nil
+}
+
+// parsePublicKey reads a minisign public key or a raw ed25519 key
+func parsePublicKey(s string) (publicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return publicKey{}, err
+	}
+	switch {
+	case len(raw) == 42 && string(raw[:2]) == "Ed":
+		return publicKey{id: raw[2:10], key: ed25519.PublicKey(raw[10:])}, nil
+	case len(raw) == ed25519.PublicKeySize:
+		return publicKey{key: ed25519.PublicKey(raw)}, nil
+	}
+	return publicKey{}, fmt.Errorf("not a minisign or ed25519 public key")
+}
+
+// fetchSidecar returns a file published next to a download, nil if there
+// is none
+type fetchSidecar func(ctx context.Context, name string) ([]byte, error)
+
+// verify checks a downloaded asset and returns how it was verified, "" if
+// nothing was published to verify it with. A checksum or signature that
+// does not match is always an error.
+func (v *verifier) verify(ctx context.Context, asset, file string, candidates []string, fetch fetchSidecar) (string, error) {
+	sum, err := fileSHA256(file)
+	if err != nil {
+		return "", err
+	}
+
+	available := make(map[string]bool, len(candidates))
+	for _, name := range candidates {
+		available[name] = true
+	}
+
+	level := ""
+	for _, name := range candidates {
+		if !isChecksumFile(name, asset) {
+			continue
+		}
+		sums, err := fetch(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		expected, listed := lookupChecksum(sums, asset, name)
+		if !listed {
+			continue
+		}
+		if !strings.EqualFold(expected, sum) {
+			return "", fmt.Errorf("checksum mismatch for %s: %s lists %s, download is %s", asset, name, expected, sum)
+		}
+		if level == "" {
+			level = VerifiedChecksum
+		}
+
+		signed, err := v.checkSignatures(ctx, name, sums, available, fetch)
+		if err != nil {
+			return "", err
+		}
+		if signed {
+			level = VerifiedSignature
+		}
+	}
+
+	// A signature over the asset itself needs no checksum file
+	if level != VerifiedSignature && len(v.keys) > 0 && (available[asset+".minisig"] || available[asset+".sig"]) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		signed, err := v.checkSignatures(ctx, asset, data, available, fetch)
+		if err != nil {
+			return "", err
+		}
+		if signed {
+			level = VerifiedSignature
+		}
+	}
+	return level, nil
+}
+
+// checkSignatures verifies the .minisig and .sig files published for
+// name. It reports false if there are none or no keys to check them with.
+func (v *verifier) checkSignatures(ctx context.Context, name string, msg []byte, available map[string]bool, fetch fetchSidecar) (bool, error) {
+	if len(v.keys) == 0 {
+		return false, nil
+	}
+
+	signed := false
+	for _, ext := range []string{".minisig", ".sig"} {
+		if !available[name+ext] {
+			continue
+		}
+		sig, err := fetch(ctx, name+ext)
+		if err != nil {
+			return false, err
+		}
+		if sig == nil {
+			continue
+		}
+		if ext == ".minisig" {
+			err = v.checkMinisign(sig, msg)
+		} else {
+			err = v.checkRaw(sig, msg)
+		}
+		if err != nil {
+			return false, fmt.Errorf("signature %s%s: %w", name, ext, err)
+		}
+		signed = true
+	}
+	return signed, nil
+}
+
+// checkMinisign verifies a minisign signature file, including its trusted
+// comment. Both legacy and prehashed signatures are accepted.
+func (v *verifier) checkMinisign(file, msg []byte) error {
+	lines := make([]string, 0, 4)
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment:") {
+		return fmt.Errorf("not a minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 74 {
+		return fmt.Errorf("not a minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("not a minisign signature")
+	}
+
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		hash := blake2b.Sum512(msg)
+		msg = hash[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig[:2])
+	}
+
+	id, signature := sig[2:10], sig[10:]
+	comment := []byte(strings.TrimPrefix(lines[2], "trusted comment: "))
+	for _, key := range v.keys {
+		if key.id == nil || !bytes.Equal(key.id, id) {
+			continue
+		}
+		if !ed25519.Verify(key.key, msg, signature) {
+			return fmt.Errorf("does not match the trusted key %X", id)
+		}
+		if !ed25519.Verify(key.key, bytes.Join([][]byte{signature, comment}, nil), global) {
+			return fmt.Errorf("trusted comment was tampered with")
+		}
+		return nil
+	}
+	return fmt.Errorf("signed by key %X, which is not trusted", id)
+}
+
+// checkRaw verifies a bare ed25519 signature, binary or base64 encoded
+func (v *verifier) checkRaw(sig, msg []byte) error {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return fmt.Errorf("not an ed25519 signature")
+		}
+		sig = decoded
+	}
+	for _, key := range v.keys {
+		if ed25519.Verify(key.key, msg, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("does not match any trusted key")
+}
+
+// accept applies the verification policy to a fetched artifact
+func (v *verifier) accept(artifact *Artifact, allowUnverified bool) error {
+	switch {
+	case artifact.Verified == VerifiedSignature, artifact.Verified == VerifiedLocal:
+		return nil
+	case allowUnverified:
+		if artifact.Verified == "" {
+			artifact.Verified = VerifiedNone
+		}
+		return nil
+	case artifact.Verified == "":
+		return fmt.Errorf("%w: no checksum or signature is published for %s", ErrUnverified, artifact.BinaryName)
+	case v.requireSignature:
+		return fmt.Errorf("%w: %s is only checksummed, but a signature by a trusted key is required", ErrUnverified, artifact.BinaryName)
+	}
+	return nil
+}
+
+// isChecksumFile reports whether a published file may list the checksum of
+// asset, e.g. checksums.txt, SHA256SUMS or weather_linux_amd64.sha256
+func isChecksumFile(name, asset string) bool {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == strings.ToLower(asset)+".sha256", lower == strings.ToLower(asset)+".sha256sum":
+		return true
+	case lower == "sha256sums", lower == "sha256sums.txt":
+		return true
+	}
+	return strings.HasSuffix(lower, "checksums.txt")
+}
+
+// lookupChecksum finds the SHA-256 of asset in a checksum file in the
+// format of sha256sum. A per-asset file may hold the bare checksum.
+func lookupChecksum(sums []byte, asset, file string) (string, bool) {
+	perAsset := strings.HasPrefix(strings.ToLower(file), strings.ToLower(asset)+".")
+	for _, line := range strings.Split(string(sums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil {
+			continue
+		}
+		if len(fields) == 1 && perAsset {
+			return fields[0], true
+		}
+		if len(fields) >= 2 && path.Base(strings.TrimPrefix(fields[1], "*")) == asset {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
+
+func fileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sidecarNames guesses the verification files a plain URL or directory
+// may publish next to asset
+func sidecarNames(asset string) []string {
+	return []string{
+		asset + ".sha256", "checksums.txt", "SHA256SUMS",
+		asset + ".minisig", asset + ".sig",
+		"checksums.txt.minisig", "checksums.txt.sig",
+		"SHA256SUMS.minisig", "SHA256SUMS.sig",
+	}
+}
@@ -0,0 +1,629 @@
+package pluginmgr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Installed versions are kept side by side under the plugin directory:
+//
+//	<plugin_dir>/versions/<name>/<version>/<binary>
+//	<plugin_dir>/versions/<name>/<version>/meta.json
+//
+// The plugin's record in the registry selects the current version. Upgrades
+// and rollbacks switch between stored versions.
+const versionsDir = "versions"
+
+// defaultKeepVersions is how many versions of a plugin are kept on disk
+const defaultKeepVersions = 3
+
+// upgradeSettle is how long an upgraded plugin has to keep running before
+// its health check decides whether the upgrade sticks
+const upgradeSettle = 2 * time.Second
+
+// ErrUpToDate is returned by Upgrade when there is nothing newer to switch to
+var ErrUpToDate = errors.New("already up to date")
+
+// ErrAwaitingApproval is returned by Upgrade when the new version requests
+// capabilities beyond those approved. The plugin stays on its version
+// until an admin approves the new one.
+var ErrAwaitingApproval = errors.New("upgrade awaits capability approval")
+
+// validStoreName restricts plugin names and versions used as directories
+var validStoreName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// VersionList describes the versions of a plugin
+type VersionList struct {
+	Current        string   `json:"current"`
+	Previous       string   `json:"previous,omitempty"`
+	Held           bool     `json:"held"`
+	Installed      []string `json:"installed"`                 // versions on disk, newest first
+	Available      []string `json:"available,omitempty"`       // release tags the source offers
+	AvailableError string   `json:"available_error,omitempty"` // why Available could not be listed
+}
+
+// SetKeepVersions sets how many versions of each plugin are kept on disk.
+// The current and previous versions are always kept.
+func (pm *PluginManager) SetKeepVersions(n int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if n < 2 {
+		n = 2
+	}
+	pm.keepVersions = n
+}
+
+// binaryPath returns where a plugin's binary is installed. Plugins
+// installed before the version store live directly in the plugin directory.
+func (pm *PluginManager) binaryPath(meta *PluginMeta) string {
+	if meta.Path != "" {
+		return filepath.Join(pm.pluginDir, meta.Path)
+	}
+	return filepath.Join(pm.pluginDir, meta.BinaryName)
+}
+
+// versionDir returns the store directory of one version of a plugin
+func (pm *PluginManager) versionDir(name, version string) string {
+	return filepath.Join(pm.pluginDir, versionsDir, name, version)
+}
+
+// stage fetches and verifies ref into a temporary file next to the store
+// and reads the plugin's manifest from it. The caller removes the returned
+// file.
+func (pm *PluginManager) stage(ctx context.Context, ref string, allowUnverified bool) (*PluginMeta, string, error) {
+	src, err := pm.resolveSource(ctx, ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch next to the installed binaries so moving it in is atomic
+	tmp, err := os.CreateTemp(pm.pluginDir, ".install-*")
+	if err != nil {
+		return nil, "", err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+
+	meta, err := pm.inspect(ctx, src, ref, tmpPath, allowUnverified)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, "", err
+	}
+	return meta, tmpPath, nil
+}
+
+func (pm *PluginManager) inspect(ctx context.Context, src Source, ref, tmpPath string, allowUnverified bool) (*PluginMeta, error) {
+	artifact, err := src.Fetch(ctx, ref, tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	binaryName := artifact.BinaryName
+	if binaryName == "" || binaryName != filepath.Base(binaryName) || strings.HasPrefix(binaryName, ".") {
+		return nil, fmt.Errorf("invalid binary name %q", binaryName)
+	}
+
+	// Nothing is run before the download is verified, not even --info
+	pm.mu.RLock()
+	v := pm.verifier
+	pm.mu.RUnlock()
+	if err := v.accept(artifact, allowUnverified); err != nil {
+		return nil, err
+	}
+	if artifact.Verified == VerifiedNone {
+		log.Printf("[PluginMgr] Warning: installing unverified %s on admin override", binaryName)
+	}
+
+	// Make executable
+	if runtime.GOOS != "windows" {
+		os.Chmod(tmpPath, 0755)
+	}
+
+	// Get plugin info by running with --info flag
+	meta, err := pm.getPluginInfo(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugin info: %w", err)
+	}
+	if !validStoreName.MatchString(meta.Name) {
+		return nil, fmt.Errorf("invalid plugin name %q", meta.Name)
+	}
+	if meta.Version == "" {
+		meta.Version = strings.TrimPrefix(artifact.Release, "v")
+	}
+	if !validStoreName.MatchString(meta.Version) {
+		return nil, fmt.Errorf("plugin %s reports an invalid version %q", meta.Name, meta.Version)
+	}
+
+	// Upgrades follow the source, not the tag that was installed
+	if _, ok := src.(VersionLister); ok {
+		ref, _ = splitRef(ref)
+	}
+	meta.Source = ref
+	meta.RepoURL = artifact.RepoURL
+	meta.BinaryName = binaryName
+	meta.Release = artifact.Release
+	meta.Verified = artifact.Verified
+
+	if err := checkCompatible(meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// storeVersion moves a staged binary into the version store and records
+// the version's manifest next to it. Reinstalling a version replaces it.
+func (pm *PluginManager) storeVersion(meta *PluginMeta, tmpPath string) error {
+	sum, err := fileSHA256(tmpPath)
+	if err != nil {
+		return err
+	}
+	meta.SHA256 = sum
+
+	dir := pm.versionDir(meta.Name, meta.Version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, meta.BinaryName)); err != nil {
+		return err
+	}
+	meta.Path = filepath.Join(versionsDir, meta.Name, meta.Version, meta.BinaryName)
+	return writeSnapshot(dir, meta)
+}
+
+// writeSnapshot records what a version is, leaving out the state that
+// belongs to the installation such as approvals and holds
+func writeSnapshot(dir string, meta *PluginMeta) error {
+	snapshot := *meta
+	snapshot.Approval = ""
+	snapshot.ApprovedCapabilities = nil
+	snapshot.PreviousVersion = ""
+	snapshot.Held = false
+	snapshot.Desired = ""
+	snapshot.Replicas = 0
+	snapshot.Sticky = ""
+	snapshot.AutoUpdate = ""
+	snapshot.Update = nil
+
+	data, err := json.MarshalIndent(&snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "meta.json"), data, 0644)
+}
+
+// loadVersion reads the manifest of a stored version
+func (pm *PluginManager) loadVersion(name, version string) (*PluginMeta, error) {
+	if !validStoreName.MatchString(version) {
+		return nil, fmt.Errorf("invalid version %q", version)
+	}
+	data, err := os.ReadFile(filepath.Join(pm.versionDir(name, version), "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("version %s of plugin %s is not installed", version, name)
+	}
+	var meta PluginMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid meta of %s v%s: %w", name, version, err)
+	}
+	if _, err := os.Stat(pm.binaryPath(&meta)); err != nil {
+		return nil, fmt.Errorf("binary of %s v%s is missing", name, version)
+	}
+	return &meta, nil
+}
+
+// installedVersions lists the stored versions of a plugin, newest first
+func (pm *PluginManager) installedVersions(name string) []string {
+	entries, err := os.ReadDir(filepath.Join(pm.pluginDir, versionsDir, name))
+	if err != nil {
+		return nil
+	}
+	versions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pm.pluginDir, versionsDir, name, e.Name(), "meta.json")); err == nil {
+			versions = append(versions, e.Name())
+		}
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// sortVersions sorts versions newest first. Versions that do not parse
+// sort after the others, by name.
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		cmp, err := compareVersions(versions[i], versions[j])
+		if err != nil {
+			_, erri := parseVersion(versions[i])
+			_, errj := parseVersion(versions[j])
+			if (erri == nil) != (errj == nil) {
+				return erri == nil
+			}
+			return versions[i] > versions[j]
+		}
+		return cmp > 0
+	})
+}
+
+// adoptLegacy moves the binary of a plugin installed before the version
+// store into it, so that it can be rolled back to
+func (pm *PluginManager) adoptLegacy(meta *PluginMeta) {
+	if meta.Path != "" || meta.BinaryName == "" {
+		return
+	}
+	if !validStoreName.MatchString(meta.Name) || !validStoreName.MatchString(meta.Version) {
+		log.Printf("[PluginMgr] Cannot keep %s v%s for rollback: invalid version", meta.Name, meta.Version)
+		return
+	}
+
+	legacy := filepath.Join(pm.pluginDir, meta.BinaryName)
+	dir := pm.versionDir(meta.Name, meta.Version)
+	sum, err := fileSHA256(legacy)
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err == nil {
+		err = os.Rename(legacy, filepath.Join(dir, meta.BinaryName))
+	}
+	if err != nil {
+		log.Printf("[PluginMgr] Cannot keep %s v%s for rollback: %v", meta.Name, meta.Version, err)
+		return
+	}
+	meta.Path = filepath.Join(versionsDir, meta.Name, meta.Version, meta.BinaryName)
+	meta.SHA256 = sum
+	if err := writeSnapshot(dir, meta); err != nil {
+		log.Printf("[PluginMgr] Cannot keep %s v%s for rollback: %v", meta.Name, meta.Version, err)
+	}
+
+	// The binary is gone from where the record points, whether or not
+	// the plugin switches versions now
+	_, err = pm.registry.update(meta.Name, func(rec *PluginMeta) error {
+		rec.Path = meta.Path
+		rec.SHA256 = meta.SHA256
+		return nil
+	})
+	if err != nil {
+		log.Printf("[PluginMgr] Failed to record the new location of %s v%s: %v", meta.Name, meta.Version, err)
+	}
+}
+
+// activate makes next the current version of a plugin whose lifecycle lock
+// is held. next must carry its approval, see carryApproval. With restart,
+// a running plugin is switched over blue/green: the new version is started
+// next to the old one and takes over once it is healthy, see bluegreen.go.
+func (pm *PluginManager) activate(ctx context.Context, next *PluginMeta, restart bool) error {
+	name := next.Name
+	cur, err := pm.registry.get(name)
+	if err != nil {
+		cur = nil
+	}
+	if cur != nil {
+		pm.adoptLegacy(cur)
+		next.PreviousVersion = cur.PreviousVersion
+		if cur.Version != next.Version {
+			next.PreviousVersion = cur.Version
+		}
+		next.Held = cur.Held
+		next.Desired = cur.Desired
+		next.Replicas = cur.Replicas
+		next.Sticky = cur.Sticky
+		next.AutoUpdate = cur.AutoUpdate
+		next.Update = cur.Update.forVersion(next.Version)
+	}
+
+	pm.mu.Lock()
+	state, exists := pm.plugins[name]
+	running := cur != nil && exists && state.Status == StatusRunning && !state.Remote
+	if exists && !running && !state.Transitional() {
+		state.Info = next
+	}
+	pm.mu.Unlock()
+
+	// A fresh install awaiting approval is recorded but not started
+	if !running || !restart || !next.Approved() {
+		if err := pm.registry.put(next); err != nil {
+			return err
+		}
+		pm.pruneVersions(next)
+		return nil
+	}
+
+	if err := pm.switchOver(ctx, state, next); err != nil {
+		return err
+	}
+	pm.pruneVersions(next)
+	return nil
+}
+
+// pruneVersions removes stored versions beyond the configured number,
+// keeping the current and previous ones
+func (pm *PluginManager) pruneVersions(meta *PluginMeta) {
+	pm.mu.RLock()
+	keep := pm.keepVersions
+	pm.mu.RUnlock()
+
+	kept := 0
+	for _, version := range pm.installedVersions(meta.Name) {
+		if version == meta.Version || version == meta.PreviousVersion {
+			continue
+		}
+		// Room is left for the current and previous versions
+		if kept < keep-2 {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(pm.versionDir(meta.Name, version)); err != nil {
+			log.Printf("[PluginMgr] Failed to remove %s v%s: %v", meta.Name, version, err)
+			continue
+		}
+		log.Printf("[PluginMgr] Removed old version %s v%s", meta.Name, version)
+	}
+}
+
+// Upgrade installs a newer version of a plugin from the source it was
+// installed from and switches to it. version selects a release tag or an
+// installed version; empty means the latest release. Downloads are
+// verified as by Install. A running plugin is restarted on the new version
+// and health-checked, and put back on the old version if it fails. A
+// version requesting more capabilities than approved is only stored and
+// ErrAwaitingApproval returned; ApproveCapabilities switches to it.
+func (pm *PluginManager) Upgrade(ctx context.Context, name, version string, allowUnverified bool) (*PluginMeta, error) {
+	cur, err := pm.registry.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if cur.Held {
+		return nil, fmt.Errorf("plugin %s is held at v%s, unhold it to upgrade", name, cur.Version)
+	}
+
+	// Switching to a version on disk needs no download
+	var next *PluginMeta
+	if version != "" {
+		if stored, err := pm.loadVersion(name, strings.TrimPrefix(version, "v")); err == nil {
+			next = stored
+		} else if stored, err := pm.loadVersion(name, version); err == nil {
+			next = stored
+		}
+	}
+
+	if next == nil {
+		ref := cur.Source
+		if ref == "" {
+			ref = cur.RepoURL
+		}
+		if ref == "" {
+			return nil, fmt.Errorf("plugin %s does not record where it was installed from, reinstall it", name)
+		}
+		if version != "" {
+			src, err := pm.resolveSource(ctx, ref)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := src.(VersionLister); !ok {
+				return nil, fmt.Errorf("plugin %s was installed from a %s source, which has no releases to choose from", name, src.Name())
+			}
+			ref += "@" + version
+		}
+
+		staged, tmpPath, err := pm.stage(ctx, ref, allowUnverified)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+		}
+		defer os.Remove(tmpPath)
+		if staged.Name != name {
+			return nil, fmt.Errorf("%s provides plugin %s, not %s", ref, staged.Name, name)
+		}
+		if staged.Version == cur.Version {
+			return cur, fmt.Errorf("%w: plugin %s is at v%s", ErrUpToDate, name, cur.Version)
+		}
+		if err := pm.storeVersion(staged, tmpPath); err != nil {
+			return nil, fmt.Errorf("failed to install plugin: %w", err)
+		}
+		next = staged
+	} else if next.Version == cur.Version {
+		return cur, fmt.Errorf("%w: plugin %s is at v%s", ErrUpToDate, name, cur.Version)
+	}
+
+	if err := checkCompatible(next); err != nil {
+		return nil, err
+	}
+
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// An admin may have held the plugin while the new version downloaded
+	if cur, err := pm.registry.get(name); err == nil && cur.Held {
+		return nil, fmt.Errorf("plugin %s is held at v%s, unhold it to upgrade", name, cur.Version)
+	}
+
+	// Upgrades keep their approval unless they ask for more
+	pm.carryApproval(next)
+	if !next.Approved() {
+		pm.awaitApproval(name, next.Version)
+		log.Printf("[PluginMgr] Plugin %s v%s requests new capabilities, awaiting approval: %s", name, next.Version, next.Capabilities)
+		return next, fmt.Errorf("%w: plugin %s v%s requests %s and stays on v%s until approved",
+			ErrAwaitingApproval, name, next.Version, next.Capabilities, cur.Version)
+	}
+
+	if err := pm.activate(ctx, next, true); err != nil {
+		return nil, err
+	}
+	log.Printf("[PluginMgr] Upgraded plugin %s to v%s", name, next.Version)
+	return next, nil
+}
+
+// awaitApproval records that version of a plugin is stored and waits for
+// its capabilities to be approved
+func (pm *PluginManager) awaitApproval(name, version string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		if meta.Update == nil {
+			meta.Update = &UpdateStatus{}
+		}
+		meta.Update.Awaiting = version
+		return nil
+	})
+	if err != nil {
+		log.Printf("[PluginMgr] Failed to record update status of %s: %v", name, err)
+		return
+	}
+	pm.showUpdateLocked(meta)
+}
+
+// approveUpgrade approves the capabilities of the version an upgrade of
+// name awaits and switches to it as Upgrade would have. It reports false
+// if no upgrade is waiting.
+func (pm *PluginManager) approveUpgrade(name string) (*PluginMeta, bool, error) {
+	if cur, err := pm.registry.get(name); err != nil || cur.Update == nil || cur.Update.Awaiting == "" {
+		return nil, false, nil
+	}
+
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return nil, true, err
+	}
+	defer unlock()
+
+	cur, err := pm.registry.get(name)
+	if err != nil || cur.Update == nil || cur.Update.Awaiting == "" {
+		return nil, false, nil
+	}
+	if cur.Held {
+		return nil, true, fmt.Errorf("plugin %s is held at v%s, unhold it to upgrade", name, cur.Version)
+	}
+	next, err := pm.loadVersion(name, cur.Update.Awaiting)
+	if err != nil {
+		return nil, true, err
+	}
+	if err := checkCompatible(next); err != nil {
+		return nil, true, err
+	}
+
+	next.Approval = ApprovalApproved
+	next.ApprovedCapabilities = next.Capabilities
+	if err := pm.activate(context.Background(), next, true); err != nil {
+		return nil, true, err
+	}
+	log.Printf("[PluginMgr] Approved capabilities of plugin %s v%s and switched to it: %s", name, next.Version, next.Capabilities)
+	return next, true, nil
+}
+
+// Rollback switches a plugin back to the version it was on before its
+// last upgrade. Rolling back twice returns to the upgraded version.
+func (pm *PluginManager) Rollback(ctx context.Context, name string) (*PluginMeta, error) {
+	unlock, err := pm.lockLifecycle(name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cur, err := pm.registry.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if cur.PreviousVersion == "" {
+		return nil, fmt.Errorf("plugin %s has no previous version to roll back to", name)
+	}
+	prev, err := pm.loadVersion(name, cur.PreviousVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCompatible(prev); err != nil {
+		return nil, err
+	}
+
+	pm.carryApproval(prev)
+	if err := pm.activate(ctx, prev, true); err != nil {
+		return nil, err
+	}
+
+	// Auto-update must not bring back what an admin rolled back
+	if prev.AutoUpdate != AutoUpdateOff && prev.Update != nil {
+		pm.mu.Lock()
+		pm.updateStatusLocked(name, func(status *UpdateStatus) {
+			status.Skip = cur.Version
+		})
+		pm.mu.Unlock()
+	}
+	log.Printf("[PluginMgr] Rolled back plugin %s from v%s to v%s", name, cur.Version, prev.Version)
+	return prev, nil
+}
+
+// Hold stops a plugin from being upgraded, or allows it again. A held
+// plugin can still be rolled back.
+func (pm *PluginManager) Hold(name string, held bool) (*PluginMeta, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	meta, err := pm.registry.update(name, func(meta *PluginMeta) error {
+		meta.Held = held
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if state, exists := pm.plugins[name]; exists {
+		state.Info.Held = held
+	}
+
+	if held {
+		log.Printf("[PluginMgr] Holding plugin %s at v%s", name, meta.Version)
+	} else {
+		log.Printf("[PluginMgr] Released hold on plugin %s", name)
+	}
+	return meta, nil
+}
+
+// Versions lists the installed versions of a plugin and the releases its
+// source offers
+func (pm *PluginManager) Versions(ctx context.Context, name string) (*VersionList, error) {
+	meta, err := pm.registry.get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &VersionList{
+		Current:   meta.Version,
+		Previous:  meta.PreviousVersion,
+		Held:      meta.Held,
+		Installed: pm.installedVersions(name),
+	}
+	if len(list.Installed) == 0 {
+		list.Installed = []string{meta.Version}
+	}
+
+	ref := meta.Source
+	if ref == "" {
+		ref = meta.RepoURL
+	}
+	if ref == "" {
+		return list, nil
+	}
+	src, err := pm.resolveSource(ctx, ref)
+	if err != nil {
+		list.AvailableError = err.Error()
+		return list, nil
+	}
+	if lister, ok := src.(VersionLister); ok {
+		list.Available, err = lister.Versions(ctx, ref)
+		if err != nil {
+			list.AvailableError = err.Error()
+		}
+	}
+	return list, nil
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/DaikonSushi/bot-platform/internal/pluginmgr"
 )
@@ -31,6 +33,22 @@ func (s *AdminServer) Start() error {
 	mux.HandleFunc("/api/plugins/start", s.handleStart)
 	mux.HandleFunc("/api/plugins/stop", s.handleStop)
 	mux.HandleFunc("/api/plugins/uninstall", s.handleUninstall)
+	mux.HandleFunc("/api/plugins/approve", s.handleApprove)
+	mux.HandleFunc("/api/plugins/upgrade", s.handleUpgrade)
+	mux.HandleFunc("/api/plugins/rollback", s.handleRollback)
+	mux.HandleFunc("/api/plugins/hold", s.handleHold)
+	mux.HandleFunc("/api/plugins/unhold", s.handleUnhold)
+	mux.HandleFunc("/api/plugins/autoupdate", s.handleAutoUpdate)
+	mux.HandleFunc("/api/plugins/replicas", s.handleReplicas)
+	mux.HandleFunc("/api/plugins/check-updates", s.handleCheckUpdates)
+	mux.HandleFunc("GET /api/plugins/{name}/logs", s.handleLogs)
+	mux.HandleFunc("GET /api/plugins/{name}/versions", s.handleVersions)
+	mux.HandleFunc("GET /api/plugins/{name}/config", s.handleGetConfig)
+	mux.HandleFunc("PUT /api/plugins/{name}/config", s.handlePutConfig)
+	mux.HandleFunc("GET /api/catalog", s.handleCatalog)
+	mux.HandleFunc("/api/commands", s.handleCommands)
+	mux.HandleFunc("/api/commands/pin", s.handlePin)
+	mux.HandleFunc("/api/commands/unpin", s.handleUnpin)
 	mux.HandleFunc("/api/health", s.handleHealth)
 
 	return http.ListenAndServe(s.addr, mux)
@@ -52,11 +70,38 @@ func (s *AdminServer) handlePlugins(w http.ResponseWriter, r *http.Request) {
 		Author      string   `json:"author"`
 		Commands    []string `json:"commands"`
 		Status      string   `json:"status"`
+		Desired     string   `json:"desired,omitempty"` // enabled or disabled, what the core restores on boot
+		Drift       bool     `json:"drift"`             // Status does not match Desired
 		RepoURL     string   `json:"repo_url,omitempty"`
+		Release     string   `json:"release,omitempty"`
+		Previous    string   `json:"previous_version,omitempty"` // Version a rollback returns to
+		Held        bool     `json:"held"`
+		Verified    string   `json:"verified,omitempty"` // How the installed download was verified
+		Priority    int      `json:"priority"`
+		Role        string   `json:"role"`
+		Protocol    uint32   `json:"protocol,omitempty"`
+		SDKVersion  string   `json:"sdk_version,omitempty"`
+		CoreVersion string   `json:"core_version,omitempty"` // Compatible core range from the manifest
+		Remote      bool     `json:"remote"`
+
+		AutoUpdate string                  `json:"auto_update,omitempty"`
+		Update     *pluginmgr.UpdateStatus `json:"update,omitempty"` // Result of the last update check
+
+		Replicas      int                       `json:"replicas"`                 // Replicas that should run
+		Sticky        string                    `json:"sticky,omitempty"`         // group or user, empty routes to the least busy replica
+		ReplicaStatus []pluginmgr.ReplicaStatus `json:"replica_status,omitempty"` // Replicas of a running plugin
+
+		Capabilities *pluginmgr.Capabilities `json:"capabilities,omitempty"`
+		Approval     string                  `json:"approval,omitempty"`
+		LastError    string                  `json:"last_error,omitempty"`
+		Restarts     int                     `json:"restarts,omitempty"`
+		Limits       pluginmgr.Limits        `json:"limits"`
+		Violations   []string                `json:"violations,omitempty"`
 	}
 
 	result := make([]pluginResponse, 0)
 	for _, p := range plugins {
+		desired, drift := s.pm.DesiredState(p)
 		result = append(result, pluginResponse{
 			Name:        p.Info.Name,
 			Version:     p.Info.Version,
@@ -64,19 +109,47 @@ func (s *AdminServer) handlePlugins(w http.ResponseWriter, r *http.Request) {
 			Author:      p.Info.Author,
 			Commands:    p.Info.Commands,
 			Status:      p.Status,
+			Desired:     desired,
+			Drift:       drift,
 			RepoURL:     p.Info.RepoURL,
+			Release:     p.Info.Release,
+			Previous:    p.Info.PreviousVersion,
+			Held:        p.Info.Held,
+			Verified:    p.Info.Verified,
+			Priority:    s.pm.Priority(p.Info),
+			Role:        p.Info.Role(),
+			Protocol:    p.Protocol,
+			SDKVersion:  p.Info.SDKVersion,
+			CoreVersion: p.Info.CoreVersion,
+			Remote:      p.Remote,
+
+			AutoUpdate: p.Info.AutoUpdate,
+			Update:     p.Info.Update,
+
+			Replicas:      p.Info.ReplicaCount(),
+			Sticky:        p.Info.Sticky,
+			ReplicaStatus: s.pm.Replicas(p),
+
+			Capabilities: p.Info.Capabilities,
+			Approval:     p.Info.Approval,
+			LastError:    p.LastError,
+			Restarts:     p.Restarts,
+			Limits:       s.pm.LimitsFor(p.Info.Name),
+			Violations:   p.Violations,
 		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"code":    0,
-		"message": "success",
-		"data":    result,
+		"code":          0,
+		"message":       "success",
+		"data":          result,
+		"dispatch_mode": s.pm.DispatchMode(),
+		"core_version":  pluginmgr.CoreVersion,
 	})
 }
 
-// handleInstall installs a plugin from GitHub
+// handleInstall installs a plugin from a repo URL, URL or local path
 func (s *AdminServer) handleInstall(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -84,22 +157,28 @@ func (s *AdminServer) handleInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		RepoURL   string `json:"repo_url"`
+		Source    string `json:"source"`   // Repo URL, http(s) URL or path on the core's host
+		RepoURL   string `json:"repo_url"` // Older name of source
 		AutoStart bool   `json:"auto_start"`
+
+		AllowUnverified bool `json:"allow_unverified"` // Install without a checksum or signature
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		jsonError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.RepoURL == "" {
-		jsonError(w, "repo_url is required", http.StatusBadRequest)
+	if req.Source == "" {
+		req.Source = req.RepoURL
+	}
+	if req.Source == "" {
+		jsonError(w, "source is required", http.StatusBadRequest)
 		return
 	}
 
-	meta, err := s.pm.InstallFromGitHub(r.Context(), req.RepoURL)
+	meta, err := s.pm.Install(r.Context(), req.Source, req.AllowUnverified)
 	if err != nil {
-		jsonError(w, err.Error(), http.StatusInternalServerError)
+		installError(w, err)
 		return
 	}
 
@@ -111,9 +190,10 @@ func (s *AdminServer) handleInstall(w http.ResponseWriter, r *http.Request) {
 				"code":    0,
 				"message": "Plugin installed but failed to start: " + err.Error(),
 				"data": map[string]interface{}{
-					"name":    meta.Name,
-					"version": meta.Version,
-					"started": false,
+					"name":     meta.Name,
+					"version":  meta.Version,
+					"started":  false,
+					"approval": meta.Approval,
 				},
 			})
 			return
@@ -125,9 +205,14 @@ func (s *AdminServer) handleInstall(w http.ResponseWriter, r *http.Request) {
 		"code":    0,
 		"message": "Plugin installed successfully",
 		"data": map[string]interface{}{
-			"name":    meta.Name,
-			"version": meta.Version,
-			"started": req.AutoStart,
+			"name":         meta.Name,
+			"version":      meta.Version,
+			"source":       meta.Source,
+			"release":      meta.Release,
+			"verified":     meta.Verified,
+			"started":      req.AutoStart,
+			"capabilities": meta.Capabilities,
+			"approval":     meta.Approval,
 		},
 	})
 }
@@ -216,6 +301,481 @@ func (s *AdminServer) handleUninstall(w http.ResponseWriter, r *http.Request) {
 	jsonSuccess(w, "Plugin uninstalled successfully")
 }
 
+// handleApprove grants a plugin the capabilities it requests
+func (s *AdminServer) handleApprove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.pm.ApproveCapabilities(req.Name); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, "Plugin capabilities approved")
+}
+
+// handleUpgrade switches a plugin to a newer or given version
+func (s *AdminServer) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name    string `json:"name"`
+		Version string `json:"version"` // Release tag or installed version, empty for the latest
+
+		AllowUnverified bool `json:"allow_unverified"` // Install without a checksum or signature
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	meta, err := s.pm.Upgrade(r.Context(), req.Name, req.Version, req.AllowUnverified)
+	if errors.Is(err, pluginmgr.ErrUpToDate) {
+		jsonSuccess(w, "Plugin is already up to date")
+		return
+	}
+	if errors.Is(err, pluginmgr.ErrAwaitingApproval) {
+		jsonError(w, err.Error()+"; approve the plugin to switch", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		installError(w, err)
+		return
+	}
+	versionResponse(w, "Plugin upgraded successfully", meta)
+}
+
+// handleRollback switches a plugin back to its previous version
+func (s *AdminServer) handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	meta, err := s.pm.Rollback(r.Context(), req.Name)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	versionResponse(w, "Plugin rolled back successfully", meta)
+}
+
+// handleHold stops a plugin from being upgraded
+func (s *AdminServer) handleHold(w http.ResponseWriter, r *http.Request) {
+	s.setHold(w, r, true)
+}
+
+// handleUnhold allows a held plugin to be upgraded again
+func (s *AdminServer) handleUnhold(w http.ResponseWriter, r *http.Request) {
+	s.setHold(w, r, false)
+}
+
+func (s *AdminServer) setHold(w http.ResponseWriter, r *http.Request, held bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.pm.Hold(req.Name, held); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if held {
+		jsonSuccess(w, "Plugin held at its current version")
+	} else {
+		jsonSuccess(w, "Plugin hold released")
+	}
+}
+
+// handleAutoUpdate sets a plugin's auto-update policy
+func (s *AdminServer) handleAutoUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name   string `json:"name"`
+		Policy string `json:"policy"` // off, patch, minor or any
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := pluginmgr.ParseAutoUpdate(req.Policy); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.pm.SetAutoUpdate(req.Name, req.Policy); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, "Auto-update policy set")
+}
+
+// handleReplicas sets how many replicas of a plugin run and how calls are
+// routed between them
+func (s *AdminServer) handleReplicas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name     string `json:"name"`
+		Replicas int    `json:"replicas"`
+		Sticky   string `json:"sticky"` // none, group or user
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		jsonError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if req.Replicas < 1 || req.Replicas > pluginmgr.MaxReplicas {
+		jsonError(w, "replicas must be between 1 and "+strconv.Itoa(pluginmgr.MaxReplicas), http.StatusBadRequest)
+		return
+	}
+	if _, err := pluginmgr.ParseSticky(req.Sticky); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pm.SetReplicas(r.Context(), req.Name, req.Replicas, req.Sticky); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, "Plugin replicas set")
+}
+
+// handleCheckUpdates checks every plugin for new releases right away and
+// returns the plugins that were checked with their update status
+func (s *AdminServer) handleCheckUpdates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type updateResponse struct {
+		Name       string                  `json:"name"`
+		Version    string                  `json:"version"`
+		Held       bool                    `json:"held"`
+		AutoUpdate string                  `json:"auto_update,omitempty"`
+		Update     *pluginmgr.UpdateStatus `json:"update"`
+	}
+
+	result := make([]updateResponse, 0)
+	for _, meta := range s.pm.CheckUpdates(r.Context()) {
+		result = append(result, updateResponse{
+			Name:       meta.Name,
+			Version:    meta.Version,
+			Held:       meta.Held,
+			AutoUpdate: meta.AutoUpdate,
+			Update:     meta.Update,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// handleVersions lists the installed and available versions of a plugin
+func (s *AdminServer) handleVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := s.pm.Versions(r.Context(), r.PathValue("name"))
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": "success",
+		"data":    versions,
+	})
+}
+
+// handleGetConfig returns a plugin's settings with secrets masked
+func (s *AdminServer) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.pm.GetPluginConfig(r.PathValue("name"))
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	configResponse(w, "success", cfg)
+}
+
+// handlePutConfig changes a plugin's settings. Only the keys in values are
+// touched; a null value resets the key to its default.
+func (s *AdminServer) handlePutConfig(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Values map[string]*string `json:"values"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Values) == 0 {
+		jsonError(w, "values is required", http.StatusBadRequest)
+		return
+	}
+
+	set := make(map[string]string)
+	unset := make([]string, 0)
+	for key, value := range req.Values {
+		if value == nil {
+			unset = append(unset, key)
+		} else {
+			set[key] = *value
+		}
+	}
+
+	cfg, err := s.pm.UpdatePluginConfig(r.Context(), r.PathValue("name"), set, unset)
+	if errors.Is(err, pluginmgr.ErrInvalidConfig) {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	configResponse(w, "Config updated", cfg)
+}
+
+// handleCatalog searches the plugin catalogs. q filters by keyword and
+// refresh=true fetches the catalogs again instead of using the cache.
+func (s *AdminServer) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	entries, err := s.pm.SearchCatalog(r.Context(), r.URL.Query().Get("q"), refresh)
+	if errors.Is(err, pluginmgr.ErrNoCatalogs) {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": "success",
+		"data":    entries,
+	})
+}
+
+// handleLogs returns a plugin's recent output. With follow=true the
+// response stays open and streams new lines as JSON, one per line.
+func (s *AdminServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	lines := 100
+	if v := r.URL.Query().Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonError(w, "lines must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		lines = n
+	}
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+
+	if !follow {
+		entries, err := s.pm.Logs(name, lines)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    0,
+			"message": "success",
+			"data":    entries,
+		})
+		return
+	}
+
+	if _, err := s.pm.Logs(name, 0); err != nil {
+		jsonError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	recent, live, stop := s.pm.FollowLogs(name, lines)
+	defer stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, entry := range recent {
+		enc.Encode(entry)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case entry := <-live:
+			if err := enc.Encode(entry); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleCommands returns command ownership, conflicts and pins
+func (s *AdminServer) handleCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	owners := make(map[string]string)
+	for cmd, meta := range s.pm.GetAllCommands() {
+		owners[cmd] = meta.Name
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": "success",
+		"data": map[string]interface{}{
+			"policy":    s.pm.CommandPolicy(),
+			"owners":    owners,
+			"conflicts": s.pm.CommandConflicts(),
+			"pins":      s.pm.CommandPins(),
+		},
+	})
+}
+
+// handlePin pins a bare command to a plugin
+func (s *AdminServer) handlePin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Command string `json:"command"`
+		Plugin  string `json:"plugin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Command == "" || req.Plugin == "" {
+		jsonError(w, "command and plugin are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pm.PinCommand(req.Command, req.Plugin); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, "Command pinned successfully")
+}
+
+// handleUnpin removes a command pin
+func (s *AdminServer) handleUnpin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Command string `json:"command"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Command == "" {
+		jsonError(w, "command is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pm.UnpinCommand(req.Command); err != nil {
+		jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, "Command unpinned successfully")
+}
+
 // handleHealth returns health status
 func (s *AdminServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	plugins := s.pm.GetRunningPlugins()
@@ -240,6 +800,16 @@ func jsonError(w http.ResponseWriter, message string, status int) {
 	})
 }
 
+// installError reports a failed install or upgrade. Refused unverified
+// downloads are told apart so that clients can offer the override.
+func installError(w http.ResponseWriter, err error) {
+	if errors.Is(err, pluginmgr.ErrUnverified) {
+		jsonError(w, err.Error()+"; set allow_unverified to install it anyway", http.StatusForbidden)
+		return
+	}
+	jsonError(w, err.Error(), http.StatusInternalServerError)
+}
+
 func jsonSuccess(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -247,3 +817,30 @@ func jsonSuccess(w http.ResponseWriter, message string) {
 		"message": message,
 	})
 }
+
+// versionResponse reports the version a plugin was switched to
+func versionResponse(w http.ResponseWriter, message string, meta *pluginmgr.PluginMeta) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": message,
+		"data": map[string]interface{}{
+			"name":             meta.Name,
+			"version":          meta.Version,
+			"release":          meta.Release,
+			"previous_version": meta.PreviousVersion,
+			"approval":         meta.Approval,
+			"verified":         meta.Verified,
+		},
+	})
+}
+
+// configResponse writes a plugin's config after a config call
+func configResponse(w http.ResponseWriter, message string, cfg *pluginmgr.PluginConfig) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    0,
+		"message": message,
+		"data":    cfg,
+	})
+}
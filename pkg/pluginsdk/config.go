@@ -0,0 +1,130 @@
+package pluginsdk
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+)
+
+// Config field types for ConfigField.Type
+const (
+	ConfigString   = "string"
+	ConfigInt      = "int"
+	ConfigFloat    = "float"
+	ConfigBool     = "bool"
+	ConfigDuration = "duration" // Go duration, e.g. "1m30s"
+)
+
+// ConfigField declares a setting admins may change with /plugin config.
+// The core checks values against it and keeps them for the plugin.
+type ConfigField struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type,omitempty"` // ConfigString if empty
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	Required    bool     `json:"required,omitempty"` // the core does not start the plugin without it
+	Secret      bool     `json:"secret,omitempty"`   // masked when shown to admins, e.g. API keys
+	Options     []string `json:"options,omitempty"`  // allowed values, empty means any
+}
+
+// ConfigListener is implemented by plugins that apply config changes
+// themselves. Without it the SDK just updates what BotClient.Config returns.
+type ConfigListener interface {
+	// OnConfigChange is called after an admin changed the plugin's
+	// settings. Returning an error keeps the previous config.
+	OnConfigChange(ctx context.Context, bot *BotClient, cfg *Config) error
+}
+
+// Config is the plugin's configuration: every field of its schema that has
+// a value or a default. Values are in the canonical form of their type.
+type Config struct {
+	Values   map[string]string
+	Revision uint64   // increases with every change
+	Changed  []string // keys changed by the last update, empty from GetConfig
+}
+
+func convertConfig(cfg *pb.PluginConfig) *Config {
+	values := cfg.Values
+	if values == nil {
+		values = make(map[string]string)
+	}
+	return &Config{Values: values, Revision: cfg.Revision, Changed: cfg.Changed}
+}
+
+// String returns a setting, "" if it has no value
+func (c *Config) String(key string) string {
+	return c.Values[key]
+}
+
+// Int returns an int setting, 0 if it has no value
+func (c *Config) Int(key string) int64 {
+	n, _ := strconv.ParseInt(c.Values[key], 10, 64)
+	return n
+}
+
+// Float returns a float setting, 0 if it has no value
+func (c *Config) Float(key string) float64 {
+	n, _ := strconv.ParseFloat(c.Values[key], 64)
+	return n
+}
+
+// Bool returns a bool setting, false if it has no value
+func (c *Config) Bool(key string) bool {
+	b, _ := strconv.ParseBool(c.Values[key])
+	return b
+}
+
+// Duration returns a duration setting, 0 if it has no value
+func (c *Config) Duration(key string) time.Duration {
+	d, _ := time.ParseDuration(c.Values[key])
+	return d
+}
+
+// Config returns the plugin's current configuration. It is fetched from the
+// core once and kept up to date as admins change it.
+func (b *BotClient) Config() (*Config, error) {
+	b.configMu.Lock()
+	cfg := b.config
+	b.configMu.Unlock()
+	if cfg != nil {
+		return cfg, nil
+	}
+
+	if err := b.require(FeatureConfig); err != nil {
+		return nil, err
+	}
+	resp, err := b.client.GetConfig(context.Background(), &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	cfg = convertConfig(resp)
+
+	b.configMu.Lock()
+	defer b.configMu.Unlock()
+	// A change pushed while we were fetching is newer
+	if b.config != nil && b.config.Revision > cfg.Revision {
+		return b.config, nil
+	}
+	b.config = cfg
+	return cfg, nil
+}
+
+// setConfig records a config pushed by the core
+func (b *BotClient) setConfig(cfg *Config) {
+	b.configMu.Lock()
+	b.config = cfg
+	b.configMu.Unlock()
+}
+
+func (s *pluginServer) OnConfigChange(ctx context.Context, in *pb.PluginConfig) (*pb.HandleResult, error) {
+	cfg := convertConfig(in)
+	if listener, ok := s.plugin.(ConfigListener); ok {
+		if err := listener.OnConfigChange(ctx, s.bot, cfg); err != nil {
+			return &pb.HandleResult{Error: err.Error()}, nil
+		}
+	}
+	s.bot.setConfig(cfg)
+	return &pb.HandleResult{Handled: true}, nil
+}
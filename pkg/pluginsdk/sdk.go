@@ -11,12 +11,20 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
 	pb "github.com/DaikonSushi/bot-platform/api/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // Plugin is the interface that all plugins must implement
@@ -45,6 +53,118 @@ type PluginInfo struct {
 	Author            string   `json:"author"`
 	Commands          []string `json:"commands"`
 	HandleAllMessages bool     `json:"handle_all_messages"`
+
+	// MessageFilter subscribes to a subset of non-command messages.
+	// The core evaluates it before calling OnMessage, so OnMessage is only
+	// invoked for messages that match. Without HandleAllMessages or a
+	// filter, OnMessage is never called.
+	MessageFilter *MessageFilter `json:"message_filter,omitempty"`
+
+	// Priority orders plugins when the core runs in ordered dispatch mode;
+	// higher values see messages first. Admins may override it in config.
+	Priority int `json:"priority,omitempty"`
+
+	// ObserveOnly marks a plugin that watches messages without answering
+	// them. Observers run in parallel and never stop propagation.
+	ObserveOnly bool `json:"observe_only,omitempty"`
+
+	// EventTopics subscribes the plugin to event bus topics. "prefix.*"
+	// matches every topic under prefix and "*" matches all topics. The
+	// plugin must implement EventHandler to receive them.
+	EventTopics []string `json:"event_topics,omitempty"`
+
+	// Methods lists the methods other plugins may call through
+	// BotClient.Invoke. The plugin must implement MethodHandler.
+	Methods []string `json:"methods,omitempty"`
+
+	// DependsOn lists plugins that must be started before this one
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Capabilities declares what the plugin needs from the core. An admin
+	// approves them at install time and the core rejects calls outside
+	// them. Without Capabilities the plugin asks for unrestricted access.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+
+	// CoreVersion is the range of core versions the plugin works with,
+	// e.g. ">=1.0.0, <2.0.0". The core refuses to install or start the
+	// plugin outside it. Empty means any core.
+	CoreVersion string `json:"core_version,omitempty"`
+
+	// ConfigSchema declares the plugin's settings. Admins set them with
+	// /plugin config and the plugin reads them with BotClient.Config.
+	ConfigSchema []ConfigField `json:"config_schema,omitempty"`
+}
+
+// Capabilities lists the access a plugin requests. Log and GetConfig are
+// always allowed.
+type Capabilities struct {
+	RPCs        []string `json:"rpcs,omitempty"`         // BotService methods, e.g. "SendMessage", "CallAPI"
+	Actions     []string `json:"actions,omitempty"`      // NapCat actions for CallAPI, "get_*" matches a prefix
+	Groups      []int64  `json:"groups,omitempty"`       // Groups the plugin may act in, empty means any
+	UploadPaths []string `json:"upload_paths,omitempty"` // Directories files may be uploaded from
+}
+
+// MethodHandler is implemented by plugins that expose methods to other plugins
+type MethodHandler interface {
+	// OnInvoke handles a call to one of the methods declared in Info().Methods.
+	// payload and the returned result are JSON encoded.
+	OnInvoke(ctx context.Context, bot *BotClient, caller, method string, payload []byte) ([]byte, error)
+}
+
+// InvokeError is returned by BotClient.Invoke when the call fails
+type InvokeError struct {
+	Code    string // not_found, unavailable, unimplemented, deadline_exceeded, internal
+	Message string
+}
+
+func (e *InvokeError) Error() string {
+	return e.Message
+}
+
+// EventHandler is implemented by plugins that subscribe to event bus topics
+type EventHandler interface {
+	// OnEvent handles an event from another plugin. Returning an error
+	// leaves a durable event queued for redelivery.
+	OnEvent(ctx context.Context, bot *BotClient, event *Event) error
+}
+
+// Event is an event published on the plugin event bus
+type Event struct {
+	ID        string
+	Topic     string
+	Source    string     // Publishing plugin name
+	JSON      []byte     // JSON payload, nil if Any is set
+	Any       *anypb.Any // Protobuf payload, nil if JSON is set
+	Durable   bool
+	Timestamp int64 // Unix milliseconds
+}
+
+// Decode unmarshals the JSON payload into v
+func (e *Event) Decode(v interface{}) error {
+	if e.JSON == nil {
+		return fmt.Errorf("event %s has no JSON payload", e.ID)
+	}
+	return json.Unmarshal(e.JSON, v)
+}
+
+// DecodeProto unmarshals the protobuf payload into m
+func (e *Event) DecodeProto(m proto.Message) error {
+	if e.Any == nil {
+		return fmt.Errorf("event %s has no protobuf payload", e.ID)
+	}
+	return e.Any.UnmarshalTo(m)
+}
+
+// MessageFilter declares which non-command messages a plugin receives.
+// Every non-empty scope field (groups, users, segment types, message types)
+// must match; if Keywords or Patterns are set, at least one must match.
+type MessageFilter struct {
+	Keywords     []string `json:"keywords,omitempty"`      // Substring triggers
+	Patterns     []string `json:"patterns,omitempty"`      // Regular expression triggers
+	GroupIDs     []int64  `json:"group_ids,omitempty"`     // Only these groups
+	UserIDs      []int64  `json:"user_ids,omitempty"`      // Only these senders
+	SegmentTypes []string `json:"segment_types,omitempty"` // Required segment types, e.g. "image"
+	MessageTypes []string `json:"message_types,omitempty"` // "private" and/or "group"
 }
 
 // Message represents an incoming message
@@ -76,6 +196,11 @@ type UserInfo struct {
 // BotClient provides methods to interact with the bot
 type BotClient struct {
 	client pb.BotServiceClient
+	name   string   // plugin name, used as event source
+	core   coreInfo // what the core reported in the version handshake
+
+	configMu sync.Mutex
+	config   *Config // latest config, nil until first fetched or pushed
 }
 
 // SendPrivateMessage sends a message to a user
@@ -211,6 +336,105 @@ func (b *BotClient) CallAPI(action string, params map[string]string) ([]byte, er
 	return resp.Data, nil
 }
 
+// Publish publishes a JSON-encoded event. Delivery is at-most-once:
+// only subscribers running at the time receive it.
+func (b *BotClient) Publish(topic string, payload interface{}) error {
+	return b.publishJSON(topic, payload, false)
+}
+
+// PublishDurable publishes a JSON-encoded event that is queued and
+// redelivered until every subscriber acknowledges it
+func (b *BotClient) PublishDurable(topic string, payload interface{}) error {
+	return b.publishJSON(topic, payload, true)
+}
+
+// PublishProto publishes a protobuf payload wrapped in google.protobuf.Any
+func (b *BotClient) PublishProto(topic string, m proto.Message, durable bool) error {
+	payload, err := anypb.New(m)
+	if err != nil {
+		return err
+	}
+	return b.publish(&pb.PublishRequest{
+		Topic:   topic,
+		Payload: &pb.PublishRequest_Any{Any: payload},
+		Durable: durable,
+	})
+}
+
+func (b *BotClient) publishJSON(topic string, payload interface{}, durable bool) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return b.publish(&pb.PublishRequest{
+		Topic:   topic,
+		Payload: &pb.PublishRequest_Json{Json: data},
+		Durable: durable,
+	})
+}
+
+func (b *BotClient) publish(req *pb.PublishRequest) error {
+	if err := b.require(FeatureEvents); err != nil {
+		return err
+	}
+	req.Source = b.name
+	resp, err := b.client.Publish(context.Background(), req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("publish failed: %s", resp.Error)
+	}
+	return nil
+}
+
+// Invoke calls a method exposed by another plugin. args is JSON encoded and
+// the result is decoded into result if it is non-nil. The call deadline is
+// taken from ctx; without one the core default applies.
+func (b *BotClient) Invoke(ctx context.Context, target, method string, args, result interface{}) error {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	data, err := b.InvokeRaw(ctx, target, method, payload)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// InvokeRaw calls a method with an already encoded payload
+func (b *BotClient) InvokeRaw(ctx context.Context, target, method string, payload []byte) ([]byte, error) {
+	if err := b.require(FeatureInvoke); err != nil {
+		return nil, err
+	}
+	req := &pb.InvokeRequest{
+		Target:  target,
+		Method:  method,
+		Payload: payload,
+		Source:  b.name,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutMs = time.Until(deadline).Milliseconds()
+		if req.TimeoutMs <= 0 {
+			return nil, &InvokeError{Code: "deadline_exceeded", Message: context.DeadlineExceeded.Error()}
+		}
+	}
+
+	resp, err := b.client.InvokePlugin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &InvokeError{Code: resp.Code, Message: resp.Error}
+	}
+	return resp.Payload, nil
+}
+
 // Text creates a text message segment
 func Text(content string) MessageSegment {
 	return MessageSegment{
@@ -268,14 +492,53 @@ type pluginServer struct {
 
 func (s *pluginServer) GetInfo(ctx context.Context, _ *pb.Empty) (*pb.PluginInfo, error) {
 	info := s.plugin.Info()
-	return &pb.PluginInfo{
+	pbInfo := &pb.PluginInfo{
 		Name:              info.Name,
 		Version:           info.Version,
 		Description:       info.Description,
 		Author:            info.Author,
 		Commands:          info.Commands,
 		HandleAllMessages: info.HandleAllMessages,
-	}, nil
+		Priority:          int32(info.Priority),
+		ObserveOnly:       info.ObserveOnly,
+		EventTopics:       info.EventTopics,
+		Methods:           info.Methods,
+		DependsOn:         info.DependsOn,
+		ProtocolVersion:   ProtocolVersion,
+		SdkVersion:        SDKVersion,
+		Features:          sdkFeatures,
+		CoreVersion:       info.CoreVersion,
+	}
+	if c := info.Capabilities; c != nil {
+		pbInfo.Capabilities = &pb.Capabilities{
+			Rpcs:        c.RPCs,
+			Actions:     c.Actions,
+			Groups:      c.Groups,
+			UploadPaths: c.UploadPaths,
+		}
+	}
+	if f := info.MessageFilter; f != nil {
+		pbInfo.MessageFilter = &pb.MessageFilter{
+			Keywords:     f.Keywords,
+			Patterns:     f.Patterns,
+			GroupIds:     f.GroupIDs,
+			UserIds:      f.UserIDs,
+			SegmentTypes: f.SegmentTypes,
+			MessageTypes: f.MessageTypes,
+		}
+	}
+	for _, f := range info.ConfigSchema {
+		pbInfo.ConfigSchema = append(pbInfo.ConfigSchema, &pb.ConfigField{
+			Key:         f.Key,
+			Type:        f.Type,
+			Description: f.Description,
+			Default:     f.Default,
+			Required:    f.Required,
+			Secret:      f.Secret,
+			Options:     f.Options,
+		})
+	}
+	return pbInfo, nil
 }
 
 func (s *pluginServer) OnMessage(ctx context.Context, event *pb.MessageEvent) (*pb.HandleResult, error) {
@@ -290,6 +553,43 @@ func (s *pluginServer) OnCommand(ctx context.Context, event *pb.CommandEvent) (*
 	return &pb.HandleResult{Handled: handled}, nil
 }
 
+func (s *pluginServer) OnEvent(ctx context.Context, event *pb.BusEvent) (*pb.HandleResult, error) {
+	handler, ok := s.plugin.(EventHandler)
+	if !ok {
+		return &pb.HandleResult{Error: "plugin does not handle events"}, nil
+	}
+
+	if err := handler.OnEvent(ctx, s.bot, &Event{
+		ID:        event.Id,
+		Topic:     event.Topic,
+		Source:    event.Source,
+		JSON:      event.GetJson(),
+		Any:       event.GetAny(),
+		Durable:   event.Durable,
+		Timestamp: event.Timestamp,
+	}); err != nil {
+		return &pb.HandleResult{Error: err.Error()}, nil
+	}
+	return &pb.HandleResult{Handled: true}, nil
+}
+
+func (s *pluginServer) OnInvoke(ctx context.Context, req *pb.InvokeRequest) (*pb.InvokeResponse, error) {
+	handler, ok := s.plugin.(MethodHandler)
+	if !ok {
+		return &pb.InvokeResponse{Error: "plugin does not expose methods", Code: "unimplemented"}, nil
+	}
+
+	result, err := handler.OnInvoke(ctx, s.bot, req.Source, req.Method, req.Payload)
+	if err != nil {
+		code := "internal"
+		if invokeErr, ok := err.(*InvokeError); ok && invokeErr.Code != "" {
+			code = invokeErr.Code
+		}
+		return &pb.InvokeResponse{Error: err.Error(), Code: code}, nil
+	}
+	return &pb.InvokeResponse{Payload: result}, nil
+}
+
 func (s *pluginServer) Health(ctx context.Context, _ *pb.Empty) (*pb.HealthResponse, error) {
 	return &pb.HealthResponse{
 		Healthy: true,
@@ -342,30 +642,69 @@ func Run(plugin Plugin) {
 		port     int
 		coreAddr string
 		showInfo bool
+		token    string
+
+		coreSocket   string
+		listenSocket string
 	)
 
 	flag.IntVar(&port, "port", 50100, "Port to listen on")
 	flag.StringVar(&coreAddr, "core-addr", "127.0.0.1:50051", "Bot core gRPC address")
 	flag.BoolVar(&showInfo, "info", false, "Print plugin info as JSON and exit")
+	flag.StringVar(&token, "token", os.Getenv(envToken), "Register as a remote plugin with this token")
+	flag.StringVar(&coreSocket, "core-socket", "", "Bot core unix socket (overrides --core-addr)")
+	flag.StringVar(&listenSocket, "listen-socket", "", "Unix socket to listen on (overrides --port)")
 	flag.Parse()
 
 	// If --info flag is set, print plugin info and exit
 	if showInfo {
-		info := plugin.Info()
-		data, _ := json.Marshal(info)
+		data, _ := json.Marshal(struct {
+			PluginInfo
+			ProtocolVersion uint32   `json:"protocol_version"`
+			SDKVersion      string   `json:"sdk_version"`
+			Features        []string `json:"features"`
+		}{plugin.Info(), ProtocolVersion, SDKVersion, sdkFeatures})
 		fmt.Println(string(data))
 		os.Exit(0)
 	}
 
 	// Connect to bot core
-	conn, err := grpc.Dial(coreAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	target := coreAddr
+	if coreSocket != "" {
+		target = "unix:" + coreSocket
+	}
+	conn, err := grpc.Dial(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(withSecret(os.Getenv(envSecret))),
+	)
 	if err != nil {
 		log.Fatalf("Failed to connect to bot core: %v", err)
 	}
 	defer conn.Close()
 
+	// The core asks for the stream protocol by handing us a launch session
+	version, _ := strconv.Atoi(os.Getenv(envProtocol))
+	if session := os.Getenv(envSession); session != "" && version >= 2 {
+		err := runStream(plugin, conn, session, "")
+		if err == nil {
+			return
+		}
+		if status.Code(err) == codes.FailedPrecondition {
+			log.Fatalf("Bot core refused plugin: %s", status.Convert(err).Message())
+		}
+		log.Printf("[Plugin:%s] Stream protocol unavailable, falling back to v1: %v", plugin.Info().Name, err)
+	} else if token != "" {
+		// Started outside the core: register as a remote plugin
+		if err := runStream(plugin, conn, "", token); err != nil {
+			log.Fatalf("Failed to register with bot core: %v", err)
+		}
+		return
+	}
+
 	botClient := &BotClient{
 		client: pb.NewBotServiceClient(conn),
+		name:   plugin.Info().Name,
+		core:   coreFromEnv(),
 	}
 
 	// Initialize plugin
@@ -374,7 +713,7 @@ func Run(plugin Plugin) {
 	}
 
 	// Start gRPC server
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	lis, addr, err := listen(port, listenSocket)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
@@ -385,7 +724,7 @@ func Run(plugin Plugin) {
 		bot:    botClient,
 	})
 
-	log.Printf("[Plugin:%s] Started on port %d", plugin.Info().Name, port)
+	log.Printf("[Plugin:%s] Started on %s", plugin.Info().Name, addr)
 
 	// Handle graceful shutdown
 	go func() {
@@ -401,3 +740,34 @@ func Run(plugin Plugin) {
 		log.Fatalf("gRPC server error: %v", err)
 	}
 }
+
+// listen opens the plugin's gRPC listener. A unix socket is created with
+// owner-only permissions, replacing any stale socket file.
+func listen(port int, socket string) (net.Listener, string, error) {
+	if socket == "" {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		return lis, fmt.Sprintf("port %d", port), err
+	}
+
+	os.Remove(socket)
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		lis.Close()
+		return nil, "", err
+	}
+	return lis, socket, nil
+}
+
+// withSecret attaches the per-launch secret the core issued to this plugin
+// to every call, so the core knows who is calling
+func withSecret(secret string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if secret != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, secretMetadataKey, secret)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,316 @@
+package pluginsdk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	pb "github.com/DaikonSushi/bot-platform/api/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ProtocolVersion is the highest plugin protocol this SDK speaks
+const ProtocolVersion = 2
+
+// streamCredits is how many events the plugin lets the core have in flight
+const streamCredits = 16
+
+// Environment variables read by Run; all but the token are set by the core
+const (
+	envProtocol = "BOT_PLUGIN_PROTOCOL"
+	envSession  = "BOT_PLUGIN_SESSION"
+	envToken    = "BOT_PLUGIN_TOKEN"
+	envSecret   = "BOT_PLUGIN_SECRET"
+)
+
+// secretMetadataKey carries the per-launch secret on BotService calls
+const secretMetadataKey = "x-plugin-secret"
+
+// streamConn is the plugin side of a v2 stream. It implements
+// pb.BotServiceClient by multiplexing calls as actions on the stream.
+type streamConn struct {
+	stream pb.PluginHost_ConnectClient
+	server *pluginServer
+	core   coreInfo // from the welcome frame
+
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *pb.ActionResult
+	started chan struct{} // closed once OnStart returns; events wait for it
+	closed  chan struct{}
+}
+
+// dialStream opens the v2 stream and completes the hello/welcome exchange.
+// A plugin launched by the core presents its session; a remote plugin
+// presents its registration token.
+func dialStream(conn *grpc.ClientConn, session, token string, server *pluginServer) (*streamConn, error) {
+	stream, err := pb.NewPluginHostClient(conn).Connect(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	info, _ := server.GetInfo(context.Background(), &pb.Empty{})
+	if err := stream.Send(&pb.PluginFrame{Frame: &pb.PluginFrame_Hello{Hello: &pb.Hello{
+		PluginName:      info.Name,
+		Session:         session,
+		ProtocolVersion: ProtocolVersion,
+		InitialCredits:  streamCredits,
+		Info:            info,
+		Token:           token,
+	}}}); err != nil {
+		return nil, err
+	}
+
+	frame, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	welcome := frame.GetWelcome()
+	if welcome == nil {
+		return nil, fmt.Errorf("expected welcome from core")
+	}
+
+	// A core that predates the handshake leaves the welcome empty; fall
+	// back to what it put in the environment, if anything
+	core := coreFromEnv()
+	if welcome.CoreVersion != "" {
+		core = coreInfo{version: welcome.CoreVersion, features: welcome.Features, known: true}
+	}
+
+	return &streamConn{
+		stream:  stream,
+		server:  server,
+		core:    core,
+		pending: make(map[uint64]chan *pb.ActionResult),
+		started: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+func (c *streamConn) send(frame *pb.PluginFrame) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.stream.Send(frame)
+}
+
+// serve reads core frames until the stream ends or the core asks the
+// plugin to shut down
+func (c *streamConn) serve() error {
+	defer close(c.closed)
+
+	for {
+		frame, err := c.stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch f := frame.Frame.(type) {
+		case *pb.CoreFrame_ActionResult:
+			c.mu.Lock()
+			ch, exists := c.pending[f.ActionResult.Id]
+			delete(c.pending, f.ActionResult.Id)
+			c.mu.Unlock()
+			if exists {
+				ch <- f.ActionResult
+			}
+		case *pb.CoreFrame_Event:
+			if _, ok := f.Event.Payload.(*pb.PluginEvent_Shutdown); ok {
+				c.send(&pb.PluginFrame{Frame: &pb.PluginFrame_Ack{Ack: &pb.Ack{
+					Seq:    f.Event.Seq,
+					Result: &pb.Ack_Empty{Empty: &pb.Empty{}},
+				}}})
+				return nil
+			}
+			go c.handleEvent(f.Event)
+		}
+	}
+}
+
+// handleEvent runs one event and acks it, returning its credit
+func (c *streamConn) handleEvent(event *pb.PluginEvent) {
+	<-c.started
+
+	ctx := context.Background()
+	ack := &pb.Ack{Seq: event.Seq}
+
+	switch p := event.Payload.(type) {
+	case *pb.PluginEvent_Message:
+		result, _ := c.server.OnMessage(ctx, p.Message)
+		ack.Result = &pb.Ack_HandleResult{HandleResult: result}
+		ack.Credits = 1
+	case *pb.PluginEvent_Command:
+		result, _ := c.server.OnCommand(ctx, p.Command)
+		ack.Result = &pb.Ack_HandleResult{HandleResult: result}
+		ack.Credits = 1
+	case *pb.PluginEvent_BusEvent:
+		result, _ := c.server.OnEvent(ctx, p.BusEvent)
+		ack.Result = &pb.Ack_HandleResult{HandleResult: result}
+		ack.Credits = 1
+	case *pb.PluginEvent_Invoke:
+		result, _ := c.server.OnInvoke(ctx, p.Invoke)
+		ack.Result = &pb.Ack_Invoke{Invoke: result}
+		ack.Credits = 1
+	case *pb.PluginEvent_Health:
+		result, _ := c.server.Health(ctx, p.Health)
+		ack.Result = &pb.Ack_Health{Health: result}
+	case *pb.PluginEvent_Config:
+		result, _ := c.server.OnConfigChange(ctx, p.Config)
+		ack.Result = &pb.Ack_HandleResult{HandleResult: result}
+	default:
+		ack.Result = &pb.Ack_Empty{Empty: &pb.Empty{}}
+	}
+
+	if err := c.send(&pb.PluginFrame{Frame: &pb.PluginFrame_Ack{Ack: ack}}); err != nil {
+		log.Printf("[Plugin] Failed to ack event %d: %v", event.Seq, err)
+	}
+}
+
+// do sends a BotService call as an action and waits for its result
+func (c *streamConn) do(ctx context.Context, method string, in, out proto.Message) error {
+	req, err := anypb.New(in)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *pb.ActionResult, 1)
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+
+	if err := c.send(&pb.PluginFrame{Frame: &pb.PluginFrame_Action{Action: &pb.Action{
+		Id:      id,
+		Method:  method,
+		Request: req,
+	}}}); err != nil {
+		forget()
+		return err
+	}
+
+	select {
+	case result := <-ch:
+		if result.Error != "" {
+			return fmt.Errorf("%s", result.Error)
+		}
+		if result.Response == nil {
+			return nil
+		}
+		return result.Response.UnmarshalTo(out)
+	case <-ctx.Done():
+		forget()
+		return ctx.Err()
+	case <-c.closed:
+		return fmt.Errorf("connection to bot core closed")
+	}
+}
+
+func (c *streamConn) SendMessage(ctx context.Context, in *pb.SendMessageRequest, _ ...grpc.CallOption) (*pb.SendMessageResponse, error) {
+	out := &pb.SendMessageResponse{}
+	return out, c.do(ctx, "SendMessage", in, out)
+}
+
+func (c *streamConn) GetUserInfo(ctx context.Context, in *pb.GetUserInfoRequest, _ ...grpc.CallOption) (*pb.UserInfo, error) {
+	out := &pb.UserInfo{}
+	return out, c.do(ctx, "GetUserInfo", in, out)
+}
+
+func (c *streamConn) GetGroupInfo(ctx context.Context, in *pb.GetGroupInfoRequest, _ ...grpc.CallOption) (*pb.GroupInfo, error) {
+	out := &pb.GroupInfo{}
+	return out, c.do(ctx, "GetGroupInfo", in, out)
+}
+
+func (c *streamConn) Log(ctx context.Context, in *pb.LogRequest, _ ...grpc.CallOption) (*pb.Empty, error) {
+	out := &pb.Empty{}
+	return out, c.do(ctx, "Log", in, out)
+}
+
+func (c *streamConn) UploadGroupFile(ctx context.Context, in *pb.UploadGroupFileRequest, _ ...grpc.CallOption) (*pb.UploadFileResponse, error) {
+	out := &pb.UploadFileResponse{}
+	return out, c.do(ctx, "UploadGroupFile", in, out)
+}
+
+func (c *streamConn) UploadPrivateFile(ctx context.Context, in *pb.UploadPrivateFileRequest, _ ...grpc.CallOption) (*pb.UploadFileResponse, error) {
+	out := &pb.UploadFileResponse{}
+	return out, c.do(ctx, "UploadPrivateFile", in, out)
+}
+
+func (c *streamConn) CallAPI(ctx context.Context, in *pb.CallAPIRequest, _ ...grpc.CallOption) (*pb.CallAPIResponse, error) {
+	out := &pb.CallAPIResponse{}
+	return out, c.do(ctx, "CallAPI", in, out)
+}
+
+func (c *streamConn) Publish(ctx context.Context, in *pb.PublishRequest, _ ...grpc.CallOption) (*pb.PublishResponse, error) {
+	out := &pb.PublishResponse{}
+	return out, c.do(ctx, "Publish", in, out)
+}
+
+func (c *streamConn) InvokePlugin(ctx context.Context, in *pb.InvokeRequest, _ ...grpc.CallOption) (*pb.InvokeResponse, error) {
+	out := &pb.InvokeResponse{}
+	return out, c.do(ctx, "InvokePlugin", in, out)
+}
+
+func (c *streamConn) GetConfig(ctx context.Context, in *pb.Empty, _ ...grpc.CallOption) (*pb.PluginConfig, error) {
+	out := &pb.PluginConfig{}
+	return out, c.do(ctx, "GetConfig", in, out)
+}
+
+// runStream runs the plugin over a v2 stream. It returns an error without
+// starting the plugin if the stream could not be established.
+func runStream(plugin Plugin, conn *grpc.ClientConn, session, token string) error {
+	server := &pluginServer{plugin: plugin}
+	sc, err := dialStream(conn, session, token, server)
+	if err != nil {
+		return err
+	}
+
+	server.bot = &BotClient{
+		client: sc,
+		name:   plugin.Info().Name,
+		core:   sc.core,
+	}
+
+	// Serve before OnStart so calls made from it get their results
+	served := make(chan error, 1)
+	go func() {
+		served <- sc.serve()
+	}()
+
+	// Initialize plugin
+	if err := plugin.OnStart(server.bot); err != nil {
+		log.Fatalf("Plugin start failed: %v", err)
+	}
+	close(sc.started)
+
+	log.Printf("[Plugin:%s] Connected to core over stream", plugin.Info().Name)
+
+	// Handle graceful shutdown
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		log.Printf("[Plugin:%s] Shutting down...", plugin.Info().Name)
+		sc.stream.CloseSend()
+	}()
+
+	if err := <-served; err != nil {
+		log.Printf("[Plugin:%s] Stream closed: %v", plugin.Info().Name, err)
+	}
+	plugin.OnStop()
+	return nil
+}
@@ -0,0 +1,91 @@
+package pluginsdk
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// SDKVersion is the version of this SDK, reported to the core in the
+// version handshake
+const SDKVersion = "1.0.0"
+
+// Protocol features announced in the version handshake. Check them with
+// BotClient.HasFeature before relying on them.
+const (
+	FeatureStream       = "stream"       // protocol v2 single stream
+	FeatureEvents       = "events"       // event bus Publish and OnEvent
+	FeatureInvoke       = "invoke"       // plugin-to-plugin Invoke
+	FeatureCapabilities = "capabilities" // capability manifests and enforcement
+	FeatureConfig       = "config"       // config schema, GetConfig and OnConfigChange
+	FeatureFilters      = "filters"      // handle_all_messages and message filters
+)
+
+// sdkFeatures lists the features this SDK supports
+var sdkFeatures = []string{FeatureStream, FeatureEvents, FeatureInvoke, FeatureCapabilities, FeatureConfig, FeatureFilters}
+
+// Environment variables describing the core to a spawned plugin
+const (
+	envCoreVersion  = "BOT_CORE_VERSION"
+	envCoreFeatures = "BOT_CORE_FEATURES"
+)
+
+// ErrUnsupported is returned by calls the connected core does not support
+var ErrUnsupported = errors.New("not supported by the connected core")
+
+// coreInfo is what the core reported about itself. A core that predates
+// the handshake reports nothing.
+type coreInfo struct {
+	version  string
+	features []string
+	known    bool
+}
+
+// coreFromEnv reads the core description handed to a spawned plugin
+func coreFromEnv() coreInfo {
+	version, ok := os.LookupEnv(envCoreVersion)
+	if !ok {
+		return coreInfo{}
+	}
+	features := make([]string, 0)
+	for _, f := range strings.Split(os.Getenv(envCoreFeatures), ",") {
+		if f != "" {
+			features = append(features, f)
+		}
+	}
+	return coreInfo{version: version, features: features, known: true}
+}
+
+// CoreVersion returns the version of the connected core, or "" if the core
+// predates the version handshake
+func (b *BotClient) CoreVersion() string {
+	return b.core.version
+}
+
+// HasFeature reports whether both this SDK and the connected core support
+// a protocol feature. It is false for every feature when the core predates
+// the version handshake, so plugins can fall back to basic behavior.
+func (b *BotClient) HasFeature(feature string) bool {
+	if !b.core.known {
+		return false
+	}
+	return containsFeature(sdkFeatures, feature) && containsFeature(b.core.features, feature)
+}
+
+// require fails a call the core said it does not support. Cores that
+// predate the handshake are not second-guessed.
+func (b *BotClient) require(feature string) error {
+	if b.core.known && !containsFeature(b.core.features, feature) {
+		return ErrUnsupported
+	}
+	return nil
+}
+
+func containsFeature(features []string, feature string) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,9 @@ package pluginctl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +13,12 @@ import (
 	"github.com/DaikonSushi/bot-platform/internal/pluginmgr"
 )
 
+// Lines shown by /plugin logs, capped to keep the reply readable in chat
+const (
+	defaultLogLines = 20
+	maxLogLines     = 100
+)
+
 // PluginCtlPlugin provides plugin management through bot commands
 type PluginCtlPlugin struct {
 	plugin.BasePlugin
@@ -67,6 +75,34 @@ func (p *PluginCtlPlugin) OnCommand(ctx *plugin.Context, cmd string, args []stri
 		return p.handleList(ctx, subArgs)
 	case "info":
 		return p.handleInfo(ctx, subArgs)
+	case "pin":
+		return p.handlePin(ctx, subArgs)
+	case "unpin":
+		return p.handleUnpin(ctx, subArgs)
+	case "approve":
+		return p.handleApprove(ctx, subArgs)
+	case "logs", "log":
+		return p.handleLogs(ctx, subArgs)
+	case "upgrade", "update":
+		return p.handleUpgrade(ctx, subArgs)
+	case "rollback":
+		return p.handleRollback(ctx, subArgs)
+	case "hold":
+		return p.handleHold(ctx, subArgs, true)
+	case "unhold":
+		return p.handleHold(ctx, subArgs, false)
+	case "versions":
+		return p.handleVersions(ctx, subArgs)
+	case "search":
+		return p.handleSearch(ctx, subArgs)
+	case "autoupdate":
+		return p.handleAutoUpdate(ctx, subArgs)
+	case "replicas", "scale":
+		return p.handleReplicas(ctx, subArgs)
+	case "updates", "outdated":
+		return p.handleUpdates(ctx, subArgs)
+	case "config":
+		return p.handleConfig(ctx, subArgs)
 	default:
 		p.showHelp(ctx)
 		return true
@@ -81,8 +117,36 @@ Usage: /plugin <command> [args]
 Alias: /pm <command> [args]
 
 Commands:
-  install <repo_url>    Install plugin from GitHub
+  search [keyword]      Search the plugin catalogs
+                        Example: /pm search weather
+  
+  install <source>      Install plugin by catalog name, repo URL, URL or path
+                        Example: /pm install showmejm
                         Example: /pm install https://github.com/user/plugin-weather
+                        Example: /pm install /opt/plugins/weather.tar.gz
+                        Example: /pm install user/plugin-weather@v1.2.0
+                        Add --unverified to install a download that has
+                        no checksum or signature
+  
+  upgrade <name> [ver]  Upgrade to the latest or a given release, rolling
+                        back if the new version fails its health check
+                        Example: /pm upgrade weather v1.3.0
+  
+  rollback <name>       Go back to the version before the last upgrade
+                        Example: /pm rollback weather
+  
+  hold <name>           Keep a plugin at its current version (unhold to undo)
+                        Example: /pm hold weather
+  
+  versions <name>       Show installed and available versions
+                        Example: /pm versions weather
+  
+  updates               Check all plugins for new releases now
+                        Example: /pm updates
+  
+  autoupdate <name> <policy>
+                        Upgrade on its own: off, patch, minor or any
+                        Example: /pm autoupdate weather patch
   
   start <name>          Start an installed plugin
                         Example: /pm start weather
@@ -93,6 +157,11 @@ Commands:
   restart <name>        Restart a plugin
                         Example: /pm restart weather
   
+  replicas <name> <n> [sticky]
+                        Run n copies and spread calls over them; sticky
+                        group or user keeps a chat on one copy
+                        Example: /pm replicas weather 3 group
+  
   uninstall <name>      Uninstall a plugin
                         Example: /pm uninstall weather
   
@@ -101,6 +170,29 @@ Commands:
   
   info <name>           Show detailed info about a plugin
                         Example: /pm info weather
+  
+  config <name>         Show a plugin's settings
+                        Example: /pm config weather
+  config <name> set <key> <value>
+                        Change a setting; the plugin picks it up live
+                        Example: /pm config weather set units metric
+  config <name> unset <key>
+                        Reset a setting to its default
+                        Example: /pm config weather unset units
+  
+  logs <name> [n]       Show the last n lines a plugin logged (default 20)
+                        Example: /pm logs weather 50
+  
+  pin <cmd> <name>      Route a conflicting /cmd to a plugin
+                        Example: /pm pin forecast weather
+  
+  unpin <cmd>           Remove a command pin
+                        Example: /pm unpin forecast
+  
+  approve <name>        Grant a plugin the capabilities it requests
+                        Example: /pm approve weather
+
+Tip: /<name>:<cmd> always reaches a specific plugin, e.g. /weather:forecast
 
 Note: Only administrators can use these commands.`
 
@@ -108,41 +200,54 @@ Note: Only administrators can use these commands.`
 	ctx.Bot.Reply(ctx, msg)
 }
 
-// handleInstall installs a plugin from GitHub
+// handleInstall installs a plugin from a repo URL, URL or local path
 func (p *PluginCtlPlugin) handleInstall(ctx *plugin.Context, args []string) bool {
+	args, allowUnverified := takeFlag(args, "--unverified")
 	if len(args) == 0 {
-		msg := message.NewMessage().Text("❌ Usage: /plugin install <repo_url>\nExample: /plugin install https://github.com/user/plugin-weather")
+		msg := message.NewMessage().Text("❌ Usage: /plugin install [--unverified] <source>\n" +
+			"Source: catalog name (see /plugin search), GitHub/Gitea repo URL, URL of a binary or .tar.gz/.zip, or a path on the bot host\n" +
+			"Add @tag for a specific release\n" +
+			"Example: /plugin install https://github.com/user/plugin-weather")
 		ctx.Bot.Reply(ctx, msg)
 		return true
 	}
 
-	repoURL := args[0]
+	source := args[0]
 
 	// Send initial message
-	msg := message.NewMessage().Text(fmt.Sprintf("⏳ Installing plugin from %s...", repoURL))
+	msg := message.NewMessage().Text(fmt.Sprintf("⏳ Installing plugin from %s...", source))
 	ctx.Bot.Reply(ctx, msg)
 
 	// Install with timeout
 	installCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	meta, err := p.extManager.InstallFromGitHub(installCtx, repoURL)
+	meta, err := p.extManager.Install(installCtx, source, allowUnverified)
 	if err != nil {
-		msg := message.NewMessage().Text(fmt.Sprintf("❌ Installation failed: %v", err))
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Installation failed: %v%s", err, unverifiedHint(err)))
 		ctx.Bot.Reply(ctx, msg)
 		return true
 	}
 
 	// Success message
+	next := fmt.Sprintf("Use '/plugin start %s' to start it.", meta.Name)
+	if !meta.Approved() {
+		next = fmt.Sprintf("⚠️ Review the capabilities, then use '/plugin approve %s' to grant them.", meta.Name)
+	}
+
 	successMsg := fmt.Sprintf("✅ Plugin installed successfully!\n\n"+
 		"Name: %s\n"+
 		"Version: %s\n"+
 		"Description: %s\n"+
-		"Commands: /%s\n\n"+
-		"Use '/plugin start %s' to start it.",
+		"Commands: /%s\n"+
+		"Capabilities: %s\n"+
+		"Verified: %s\n\n"+
+		"%s",
 		meta.Name, meta.Version, meta.Description,
 		strings.Join(meta.Commands, ", /"),
-		meta.Name)
+		meta.Capabilities,
+		meta.Verified,
+		next)
 
 	msg = message.NewMessage().Text(successMsg)
 	ctx.Bot.Reply(ctx, msg)
@@ -304,10 +409,29 @@ func (p *PluginCtlPlugin) handleList(ctx *plugin.Context, args []string) bool {
 		} else if state.Status == "error" {
 			statusIcon = "🟡"
 			statusText = "error"
+		} else if state.Status == pluginmgr.StatusCrashloop {
+			statusIcon = "🟠"
+			statusText = "crashloop"
+		} else if state.Transitional() {
+			statusIcon = "⏳"
+			statusText = state.Status
 		} else {
 			stoppedCount++
 		}
 
+		if state.Remote {
+			statusText += ", remote"
+		}
+
+		// Where the plugin differs from what the core restores on boot
+		desired, drift := p.extManager.DesiredState(state)
+		switch {
+		case drift && desired == pluginmgr.DesiredEnabled:
+			statusText += ", ⚠️ should be running"
+		case drift:
+			statusText += ", ⚠️ should be stopped"
+		}
+
 		sb.WriteString(fmt.Sprintf("%s %s (v%s) - %s\n", statusIcon, state.Info.Name, state.Info.Version, statusText))
 		sb.WriteString(fmt.Sprintf("   %s\n", state.Info.Description))
 
@@ -324,16 +448,585 @@ func (p *PluginCtlPlugin) handleList(ctx *plugin.Context, args []string) bool {
 			sb.WriteString(fmt.Sprintf("   Error: %s\n", state.LastError))
 		}
 
+		if state.Restarts > 0 {
+			sb.WriteString(fmt.Sprintf("   Restarts: %d\n", state.Restarts))
+		}
+
+		if n := state.Info.ReplicaCount(); n > 1 {
+			if replicas := p.extManager.Replicas(state); replicas != nil {
+				sb.WriteString(fmt.Sprintf("   Replicas: %d/%d running\n", runningReplicas(replicas), n))
+			} else {
+				sb.WriteString(fmt.Sprintf("   Replicas: %d\n", n))
+			}
+		}
+
+		if len(state.Violations) > 0 {
+			sb.WriteString(fmt.Sprintf("   ⚠️ Limits hit: %s\n", strings.Join(state.Violations, ", ")))
+		}
+
+		if state.Info.Held {
+			sb.WriteString("   📌 Held at this version\n")
+		}
+
+		if update := state.Info.Update; update != nil && update.Available {
+			sb.WriteString(fmt.Sprintf("   🆕 %s available\n", update.Latest))
+		}
+
 		sb.WriteString("\n")
 	}
 
 	sb.WriteString(fmt.Sprintf("Total: %d plugins (%d running, %d stopped)\n", len(plugins), runningCount, stoppedCount))
 
+	if conflicts := p.extManager.CommandConflicts(); len(conflicts) > 0 {
+		sb.WriteString(fmt.Sprintf("\n⚠️ Command conflicts (policy: %s)\n", p.extManager.CommandPolicy()))
+		for _, c := range conflicts {
+			pinned := ""
+			if c.PinnedTo != "" {
+				pinned = ", pinned"
+			}
+			sb.WriteString(fmt.Sprintf("   /%s: %s → %s%s\n", c.Command, strings.Join(c.Plugins, ", "), c.Owner, pinned))
+		}
+	}
+
 	msg := message.NewMessage().Text(sb.String())
 	ctx.Bot.Reply(ctx, msg)
 	return true
 }
 
+// handlePin pins a bare command to a plugin
+func (p *PluginCtlPlugin) handlePin(ctx *plugin.Context, args []string) bool {
+	if len(args) < 2 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin pin <command> <name>\nExample: /plugin pin forecast weather")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	cmd := strings.TrimPrefix(args[0], "/")
+	name := args[1]
+
+	if err := p.extManager.PinCommand(cmd, name); err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to pin command: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	msg := message.NewMessage().Text(fmt.Sprintf("📌 /%s is now pinned to plugin '%s'", cmd, name))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleUnpin removes a command pin
+func (p *PluginCtlPlugin) handleUnpin(ctx *plugin.Context, args []string) bool {
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin unpin <command>\nExample: /plugin unpin forecast")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	cmd := strings.TrimPrefix(args[0], "/")
+
+	if err := p.extManager.UnpinCommand(cmd); err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to unpin command: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	msg := message.NewMessage().Text(fmt.Sprintf("✅ /%s is no longer pinned", cmd))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleApprove grants a plugin the capabilities it requests
+func (p *PluginCtlPlugin) handleApprove(ctx *plugin.Context, args []string) bool {
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin approve <name>\nExample: /plugin approve weather")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	// An upgrade waiting on the approval is switched to right away
+	before := ""
+	for _, state := range p.extManager.ListPlugins() {
+		if state.Info != nil && state.Info.Name == name {
+			before = state.Info.Version
+		}
+	}
+
+	meta, err := p.extManager.ApproveCapabilities(name)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to approve plugin: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	if before != "" && meta.Version != before {
+		msg := message.NewMessage().Text(fmt.Sprintf("✅ Approved capabilities of '%s' v%s: %s\n\n"+
+			"Switched from v%s. Use '/plugin rollback %s' to go back.", name, meta.Version, meta.Capabilities, before, name))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	msg := message.NewMessage().Text(fmt.Sprintf("✅ Approved capabilities of '%s': %s\n\n"+
+		"Use '/plugin restart %s' if it is running.", name, meta.Capabilities, name))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleUpgrade switches a plugin to the latest or a given version
+func (p *PluginCtlPlugin) handleUpgrade(ctx *plugin.Context, args []string) bool {
+	args, allowUnverified := takeFlag(args, "--unverified")
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin upgrade [--unverified] <name> [version]\nExample: /plugin upgrade weather v1.3.0")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+	version := ""
+	if len(args) > 1 {
+		version = args[1]
+	}
+
+	msg := message.NewMessage().Text(fmt.Sprintf("⏳ Upgrading plugin '%s'...", name))
+	ctx.Bot.Reply(ctx, msg)
+
+	upgradeCtx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	meta, err := p.extManager.Upgrade(upgradeCtx, name, version, allowUnverified)
+	if errors.Is(err, pluginmgr.ErrUpToDate) {
+		msg := message.NewMessage().Text(fmt.Sprintf("✅ Plugin '%s' is already up to date (v%s).", name, meta.Version))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+	if errors.Is(err, pluginmgr.ErrAwaitingApproval) {
+		msg := message.NewMessage().Text(fmt.Sprintf("⏸️ Switch to '%s' v%s is pending: it requests new capabilities: %s\n"+
+			"The plugin stays on its current version. Use '/plugin approve %s' to approve and switch over.",
+			name, meta.Version, meta.Capabilities, name))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Upgrade failed: %v%s", err, unverifiedHint(err)))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	msg = message.NewMessage().Text(fmt.Sprintf("✅ Plugin '%s' upgraded from v%s to v%s.\nUse '/plugin rollback %s' to go back.",
+		name, meta.PreviousVersion, meta.Version, name))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleRollback switches a plugin back to its previous version
+func (p *PluginCtlPlugin) handleRollback(ctx *plugin.Context, args []string) bool {
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin rollback <name>\nExample: /plugin rollback weather")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	msg := message.NewMessage().Text(fmt.Sprintf("⏳ Rolling back plugin '%s'...", name))
+	ctx.Bot.Reply(ctx, msg)
+
+	rollbackCtx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	meta, err := p.extManager.Rollback(rollbackCtx, name)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Rollback failed: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	msg = message.NewMessage().Text(fmt.Sprintf("✅ Plugin '%s' rolled back from v%s to v%s.", name, meta.PreviousVersion, meta.Version))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleAutoUpdate sets how far a plugin is upgraded by the update checker
+func (p *PluginCtlPlugin) handleAutoUpdate(ctx *plugin.Context, args []string) bool {
+	if len(args) < 2 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin autoupdate <name> <off|patch|minor|any>\n" +
+			"patch: 1.2.x, minor: 1.x, any: every release\n" +
+			"Example: /plugin autoupdate weather patch")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	meta, err := p.extManager.SetAutoUpdate(name, args[1])
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to set auto-update: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	text := fmt.Sprintf("✅ Plugin '%s' is auto-updated within %s releases. A new version that fails its health check is rolled back.", name, meta.AutoUpdate)
+	if meta.AutoUpdate == pluginmgr.AutoUpdateOff {
+		text = fmt.Sprintf("✅ Plugin '%s' is no longer auto-updated. New versions are still announced.", name)
+	}
+	msg := message.NewMessage().Text(text)
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleReplicas sets how many replicas of a plugin run
+func (p *PluginCtlPlugin) handleReplicas(ctx *plugin.Context, args []string) bool {
+	if len(args) < 2 {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Usage: /plugin replicas <name> <1-%d> [none|group|user]\n", pluginmgr.MaxReplicas) +
+			"group: a group chat always reaches the same replica, user: a user does\n" +
+			"Example: /plugin replicas weather 3 group")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Invalid replica count: %s", args[1]))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+	sticky := ""
+	if len(args) > 2 {
+		sticky = args[2]
+	}
+
+	scaleCtx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	if err := p.extManager.SetReplicas(scaleCtx, name, n, sticky); err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to set replicas: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	text := fmt.Sprintf("✅ Plugin '%s' runs as a single instance.", name)
+	if n > 1 {
+		text = fmt.Sprintf("✅ Plugin '%s' runs %d replicas; calls go to the least busy one.", name, n)
+		if sticky, _ := pluginmgr.ParseSticky(sticky); sticky != pluginmgr.StickyNone {
+			text = fmt.Sprintf("✅ Plugin '%s' runs %d replicas; each %s sticks to one of them.", name, n, sticky)
+		}
+	}
+	msg := message.NewMessage().Text(text)
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// runningReplicas counts the replicas that take calls
+func runningReplicas(replicas []pluginmgr.ReplicaStatus) int {
+	n := 0
+	for _, r := range replicas {
+		if r.Status == pluginmgr.StatusRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// handleUpdates checks every plugin for new releases right away
+func (p *PluginCtlPlugin) handleUpdates(ctx *plugin.Context, args []string) bool {
+	msg := message.NewMessage().Text("⏳ Checking plugins for updates...")
+	ctx.Bot.Reply(ctx, msg)
+
+	checkCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	checked := p.extManager.CheckUpdates(checkCtx)
+	if len(checked) == 0 {
+		msg := message.NewMessage().Text("📦 No installed plugin comes from a source with releases to check.")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔄 Plugin Updates\n")
+	sb.WriteString("========================\n")
+	outdated := 0
+	for _, meta := range checked {
+		switch update := meta.Update; {
+		case update == nil:
+			continue
+		case update.Available:
+			outdated++
+			sb.WriteString(fmt.Sprintf("🆕 %s v%s → %s\n", meta.Name, meta.Version, update.Latest))
+		case update.Error != "":
+			sb.WriteString(fmt.Sprintf("⚠️ %s: %s\n", meta.Name, update.Error))
+		default:
+			sb.WriteString(fmt.Sprintf("✅ %s v%s\n", meta.Name, meta.Version))
+		}
+	}
+	if outdated > 0 {
+		sb.WriteString("\nUpgrade with: /plugin upgrade <name>")
+	}
+
+	msg = message.NewMessage().Text(strings.TrimRight(sb.String(), "\n"))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleConfig shows or changes a plugin's settings
+func (p *PluginCtlPlugin) handleConfig(ctx *plugin.Context, args []string) bool {
+	usage := "❌ Usage: /plugin config <name> [set <key> <value> | unset <key>]\n" +
+		"Example: /plugin config weather set units metric"
+	if len(args) == 0 {
+		msg := message.NewMessage().Text(usage)
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	var cfg *pluginmgr.PluginConfig
+	var err error
+	switch {
+	case len(args) == 1:
+		cfg, err = p.extManager.GetPluginConfig(name)
+	case args[1] == "set" && len(args) >= 4:
+		set := map[string]string{args[2]: strings.Join(args[3:], " ")}
+		cfg, err = p.extManager.UpdatePluginConfig(context.Background(), name, set, nil)
+	case args[1] == "unset" && len(args) == 3:
+		cfg, err = p.extManager.UpdatePluginConfig(context.Background(), name, nil, []string{args[2]})
+	default:
+		msg := message.NewMessage().Text(usage)
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	var sb strings.Builder
+	if len(args) > 1 {
+		switch {
+		case cfg.Applied:
+			sb.WriteString("✅ Config updated and applied.\n\n")
+		case cfg.ApplyError != "":
+			sb.WriteString(fmt.Sprintf("⚠️ Config saved, but the plugin did not apply it: %s\n\n", cfg.ApplyError))
+		default:
+			sb.WriteString("✅ Config saved.\n\n")
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("⚙️ Plugin Config: %s\n", cfg.Name))
+	sb.WriteString("========================\n")
+	if len(cfg.Settings) == 0 {
+		sb.WriteString("This plugin has no settings.\n")
+	}
+	for _, s := range cfg.Settings {
+		value := s.Value
+		switch {
+		case value == "":
+			value = "(not set)"
+		case !s.Set:
+			value += " (default)"
+		}
+		sb.WriteString(fmt.Sprintf("• %s = %s\n", s.Key, value))
+		if s.Description != "" {
+			sb.WriteString(fmt.Sprintf("  %s\n", s.Description))
+		}
+		if len(s.Options) > 0 {
+			sb.WriteString(fmt.Sprintf("  Options: %s\n", strings.Join(s.Options, ", ")))
+		}
+	}
+	if len(cfg.Missing) > 0 {
+		sb.WriteString(fmt.Sprintf("\n⚠️ Required before the plugin can start: %s", strings.Join(cfg.Missing, ", ")))
+	}
+
+	msg := message.NewMessage().Text(strings.TrimRight(sb.String(), "\n"))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleHold keeps a plugin at its current version or releases it
+func (p *PluginCtlPlugin) handleHold(ctx *plugin.Context, args []string, held bool) bool {
+	sub := "hold"
+	if !held {
+		sub = "unhold"
+	}
+	if len(args) == 0 {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Usage: /plugin %s <name>\nExample: /plugin %s weather", sub, sub))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	meta, err := p.extManager.Hold(name, held)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to %s plugin: %v", sub, err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	text := fmt.Sprintf("📌 Plugin '%s' is held at v%s and will not be upgraded.", name, meta.Version)
+	if !held {
+		text = fmt.Sprintf("✅ Plugin '%s' can be upgraded again.", name)
+	}
+	msg := message.NewMessage().Text(text)
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleVersions lists the installed and available versions of a plugin
+func (p *PluginCtlPlugin) handleVersions(ctx *plugin.Context, args []string) bool {
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin versions <name>\nExample: /plugin versions weather")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+
+	versionsCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	list, err := p.extManager.Versions(versionsCtx, name)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to list versions: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🏷️ Versions of %s\n", name))
+	sb.WriteString("========================\n")
+	for _, v := range list.Installed {
+		mark := "  "
+		note := ""
+		if v == list.Current {
+			mark = "▶ "
+			note = " (current)"
+			if list.Held {
+				note = " (current, held)"
+			}
+		} else if v == list.Previous {
+			note = " (previous)"
+		}
+		sb.WriteString(fmt.Sprintf("%s%s%s\n", mark, v, note))
+	}
+
+	if len(list.Available) > 0 {
+		sb.WriteString(fmt.Sprintf("\nAvailable: %s\n", strings.Join(list.Available, ", ")))
+	} else if list.AvailableError != "" {
+		sb.WriteString(fmt.Sprintf("\n⚠️ Could not list releases: %s\n", list.AvailableError))
+	}
+
+	msg := message.NewMessage().Text(strings.TrimRight(sb.String(), "\n"))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// maxSearchResults bounds the catalog entries listed in one reply
+const maxSearchResults = 20
+
+// handleSearch lists catalog plugins matching a keyword
+func (p *PluginCtlPlugin) handleSearch(ctx *plugin.Context, args []string) bool {
+	query := strings.Join(args, " ")
+
+	searchCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	entries, err := p.extManager.SearchCatalog(searchCtx, query, false)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Search failed: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+	if len(entries) == 0 {
+		msg := message.NewMessage().Text(fmt.Sprintf("🔍 No plugins found for \"%s\"", query))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	var sb strings.Builder
+	if query == "" {
+		sb.WriteString(fmt.Sprintf("🔍 Catalog (%d)\n", len(entries)))
+	} else {
+		sb.WriteString(fmt.Sprintf("🔍 Results for \"%s\" (%d)\n", query, len(entries)))
+	}
+	sb.WriteString("========================\n")
+	for i, entry := range entries {
+		if i == maxSearchResults {
+			sb.WriteString(fmt.Sprintf("... and %d more, narrow the search\n", len(entries)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("📦 %s", entry.Name))
+		if latest := entry.Latest(); latest != "" {
+			sb.WriteString(" " + latest)
+		}
+		if entry.Installed != "" {
+			sb.WriteString(fmt.Sprintf(" ✅ installed v%s", entry.Installed))
+		}
+		sb.WriteString("\n")
+		if entry.Description != "" {
+			sb.WriteString(fmt.Sprintf("   %s\n", entry.Description))
+		}
+	}
+	sb.WriteString("\nInstall with: /plugin install <name>")
+
+	msg := message.NewMessage().Text(sb.String())
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
+// handleLogs shows the recent output of a plugin
+func (p *PluginCtlPlugin) handleLogs(ctx *plugin.Context, args []string) bool {
+	if len(args) == 0 {
+		msg := message.NewMessage().Text("❌ Usage: /plugin logs <name> [lines]\nExample: /plugin logs weather 50")
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	name := args[0]
+	lines := defaultLogLines
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			msg := message.NewMessage().Text(fmt.Sprintf("❌ Invalid line count: %s", args[1]))
+			ctx.Bot.Reply(ctx, msg)
+			return true
+		}
+		lines = min(n, maxLogLines)
+	}
+
+	entries, err := p.extManager.Logs(name, lines)
+	if err != nil {
+		msg := message.NewMessage().Text(fmt.Sprintf("❌ Failed to get logs: %v", err))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	if len(entries) == 0 {
+		msg := message.NewMessage().Text(fmt.Sprintf("📜 Plugin '%s' has not logged anything yet.", name))
+		ctx.Bot.Reply(ctx, msg)
+		return true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📜 Last %d lines of %s\n", len(entries), name))
+	sb.WriteString("========================\n")
+	for _, e := range entries {
+		tag := e.Stream
+		if e.Level != "" {
+			tag = e.Level
+		}
+		sb.WriteString(fmt.Sprintf("%s [%s] %s\n", e.Time.Format("15:04:05"), tag, e.Line))
+	}
+
+	msg := message.NewMessage().Text(strings.TrimRight(sb.String(), "\n"))
+	ctx.Bot.Reply(ctx, msg)
+	return true
+}
+
 // handleInfo shows detailed information about a plugin
 func (p *PluginCtlPlugin) handleInfo(ctx *plugin.Context, args []string) bool {
 	if len(args) == 0 {
@@ -374,21 +1067,122 @@ func (p *PluginCtlPlugin) handleInfo(ctx *plugin.Context, args []string) bool {
 		sb.WriteString(fmt.Sprintf("Repository: %s\n", targetPlugin.Info.RepoURL))
 	}
 
+	if targetPlugin.Info.Release != "" {
+		sb.WriteString(fmt.Sprintf("Release: %s\n", targetPlugin.Info.Release))
+	}
+
+	if targetPlugin.Info.PreviousVersion != "" {
+		sb.WriteString(fmt.Sprintf("Previous version: %s\n", targetPlugin.Info.PreviousVersion))
+	}
+
+	if targetPlugin.Info.Held {
+		sb.WriteString("Held: yes, upgrades are blocked\n")
+	}
+
+	if targetPlugin.Info.AutoUpdate != pluginmgr.AutoUpdateOff {
+		sb.WriteString(fmt.Sprintf("Auto-update: %s\n", targetPlugin.Info.AutoUpdate))
+	}
+
+	if update := targetPlugin.Info.Update; update != nil {
+		switch {
+		case update.Awaiting != "":
+			sb.WriteString(fmt.Sprintf("Update: 🔐 v%s awaiting capability approval\n", update.Awaiting))
+		case update.Available:
+			sb.WriteString(fmt.Sprintf("Update: 🆕 %s available (checked %s)\n", update.Latest, update.CheckedAt.Format("2006-01-02 15:04")))
+		case update.Error != "":
+			sb.WriteString(fmt.Sprintf("Update: check failed: %s\n", update.Error))
+		default:
+			sb.WriteString(fmt.Sprintf("Update: up to date (checked %s)\n", update.CheckedAt.Format("2006-01-02 15:04")))
+		}
+	}
+
+	switch targetPlugin.Info.Verified {
+	case "":
+	case pluginmgr.VerifiedNone:
+		sb.WriteString("Verified: ⚠️ no, installed on override\n")
+	default:
+		sb.WriteString(fmt.Sprintf("Verified: %s\n", targetPlugin.Info.Verified))
+	}
+
 	if len(targetPlugin.Info.Commands) > 0 {
 		sb.WriteString(fmt.Sprintf("Commands: /%s\n", strings.Join(targetPlugin.Info.Commands, ", /")))
 	}
 
+	if targetPlugin.Info.MessageFilter != nil {
+		sb.WriteString("Messages: filtered\n")
+	} else if targetPlugin.Info.HandleAllMessages {
+		sb.WriteString("Messages: all\n")
+	}
+
+	if len(targetPlugin.Info.Methods) > 0 {
+		sb.WriteString(fmt.Sprintf("Methods: %s\n", strings.Join(targetPlugin.Info.Methods, ", ")))
+	}
+
+	if len(targetPlugin.Info.DependsOn) > 0 {
+		sb.WriteString(fmt.Sprintf("Depends on: %s\n", strings.Join(targetPlugin.Info.DependsOn, ", ")))
+	}
+
+	if n := len(targetPlugin.Info.ConfigSchema); n > 0 {
+		sb.WriteString(fmt.Sprintf("Settings: %d, see /plugin config %s\n", n, targetPlugin.Info.Name))
+	}
+
+	if len(targetPlugin.Info.EventTopics) > 0 {
+		sb.WriteString(fmt.Sprintf("Event topics: %s\n", strings.Join(targetPlugin.Info.EventTopics, ", ")))
+	}
+
+	switch targetPlugin.Info.Approval {
+	case pluginmgr.ApprovalPending:
+		sb.WriteString(fmt.Sprintf("Capabilities: %s (pending approval)\n", targetPlugin.Info.Capabilities))
+	case pluginmgr.ApprovalApproved:
+		sb.WriteString(fmt.Sprintf("Capabilities: %s (approved)\n", targetPlugin.Info.ApprovedCapabilities))
+	default:
+		sb.WriteString(fmt.Sprintf("Capabilities: %s\n", targetPlugin.Info.Capabilities))
+	}
+
+	sdk := targetPlugin.Info.SDKVersion
+	if sdk == "" {
+		sdk = "unknown (predates version handshake)"
+	}
+	sb.WriteString(fmt.Sprintf("SDK: %s\n", sdk))
+	if targetPlugin.Info.CoreVersion != "" {
+		sb.WriteString(fmt.Sprintf("Requires core: %s (this core: %s)\n", targetPlugin.Info.CoreVersion, pluginmgr.CoreVersion))
+	}
+
+	sb.WriteString(fmt.Sprintf("Dispatch: %s, %s, priority %d\n",
+		p.extManager.DispatchMode(), targetPlugin.Info.Role(), p.extManager.Priority(targetPlugin.Info)))
+
 	statusIcon := "🔴"
 	if targetPlugin.Status == "running" {
 		statusIcon = "🟢"
 	} else if targetPlugin.Status == "error" {
 		statusIcon = "🟡"
+	} else if targetPlugin.Status == pluginmgr.StatusCrashloop {
+		statusIcon = "🟠"
+	} else if targetPlugin.Transitional() {
+		statusIcon = "⏳"
 	}
 
 	sb.WriteString(fmt.Sprintf("\nStatus: %s %s\n", statusIcon, targetPlugin.Status))
 
+	if desired, drift := p.extManager.DesiredState(targetPlugin); desired != "" {
+		note := "restored on restart"
+		if drift {
+			note = "⚠️ differs from status"
+		}
+		sb.WriteString(fmt.Sprintf("Desired: %s (%s)\n", desired, note))
+	}
+
 	if targetPlugin.Status == "running" {
-		sb.WriteString(fmt.Sprintf("Port: %d\n", targetPlugin.Port))
+		if targetPlugin.Remote {
+			sb.WriteString("Location: remote\n")
+		}
+		if targetPlugin.Protocol >= 2 {
+			sb.WriteString(fmt.Sprintf("Protocol: v%d (stream)\n", targetPlugin.Protocol))
+		} else if targetPlugin.Socket != "" {
+			sb.WriteString(fmt.Sprintf("Socket: %s\n", targetPlugin.Socket))
+		} else {
+			sb.WriteString(fmt.Sprintf("Port: %d\n", targetPlugin.Port))
+		}
 		if !targetPlugin.StartedAt.IsZero() {
 			sb.WriteString(fmt.Sprintf("Started: %s\n", targetPlugin.StartedAt.Format("2006-01-02 15:04:05")))
 			uptime := time.Since(targetPlugin.StartedAt).Round(time.Second)
@@ -396,11 +1190,69 @@ func (p *PluginCtlPlugin) handleInfo(ctx *plugin.Context, args []string) bool {
 		}
 	}
 
+	if targetPlugin.Restarts > 0 {
+		sb.WriteString(fmt.Sprintf("Restarts: %d\n", targetPlugin.Restarts))
+	}
+
+	if n := targetPlugin.Info.ReplicaCount(); n > 1 {
+		routing := "least busy"
+		if targetPlugin.Info.Sticky != pluginmgr.StickyNone {
+			routing = "sticky by " + targetPlugin.Info.Sticky
+		}
+		sb.WriteString(fmt.Sprintf("Replicas: %d, %s\n", n, routing))
+		for _, r := range p.extManager.Replicas(targetPlugin) {
+			line := fmt.Sprintf("  #%d %s", r.Replica, r.Status)
+			if r.Status == pluginmgr.StatusRunning {
+				line += fmt.Sprintf(", %d in flight", r.Inflight)
+			}
+			if r.Restarts > 0 {
+				line += fmt.Sprintf(", %d restarts", r.Restarts)
+			}
+			if r.Status != pluginmgr.StatusRunning && r.LastError != "" {
+				line += ": " + r.LastError
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+
+	if !targetPlugin.Remote {
+		sb.WriteString(fmt.Sprintf("Limits: %s\n", p.extManager.LimitsFor(targetPlugin.Info.Name)))
+	}
+	if len(targetPlugin.Violations) > 0 {
+		sb.WriteString(fmt.Sprintf("⚠️ Limits hit: %s\n", strings.Join(targetPlugin.Violations, ", ")))
+	}
+
 	if targetPlugin.LastError != "" {
 		sb.WriteString(fmt.Sprintf("\nLast Error: %s\n", targetPlugin.LastError))
 	}
 
+	if targetPlugin.Status == pluginmgr.StatusCrashloop {
+		sb.WriteString(fmt.Sprintf("\nThe plugin kept crashing and is no longer restarted. Use '/plugin start %s' once fixed.\n", targetPlugin.Info.Name))
+	}
+
 	msg := message.NewMessage().Text(sb.String())
 	ctx.Bot.Reply(ctx, msg)
 	return true
 }
+
+// takeFlag removes a flag from args and reports whether it was given
+func takeFlag(args []string, flag string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// unverifiedHint tells an admin how to install a refused download anyway
+func unverifiedHint(err error) string {
+	if errors.Is(err, pluginmgr.ErrUnverified) {
+		return "\n\nIf you trust the source, repeat the command with --unverified."
+	}
+	return ""
+}